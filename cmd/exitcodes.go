@@ -0,0 +1,33 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package cmd
+
+// Exit codes of mittens once the warmup is over, when `-exit-after-warmup` is set. They are part of the interface of
+// mittens so that automation can react to each kind of failure: existing codes must never change meaning.
+const (
+	// exitOK means the warmup succeeded and the readiness probe, if enabled, was written.
+	exitOK = 0
+	// exitFailure means the warmup failed for any reason not covered by the codes below, e.g. the self-test failed.
+	exitFailure = 1
+	// exitConnectionFailure means the target never became ready, or no request could be sent with `-fail-readiness`.
+	exitConnectionFailure = 2
+	// exitThresholdExceeded means too many requests failed or were slow: an endpoint never succeeded with
+	// `-require-all-endpoints-ok` or too many responses exceeded their max latency.
+	exitThresholdExceeded = 3
+	// exitMinSuccessNotMet means the `-min-success` gate was not met.
+	exitMinSuccessNotMet = 4
+	// exitConfigError means the flags, the requests or the pre-flight validation against the target were invalid.
+	exitConfigError = 5
+)
@@ -19,11 +19,17 @@ import (
 	"fmt"
 	"log"
 	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/warmup"
 )
 
 // Grpc stores flags related to gRPC requests.
 type Grpc struct {
-	Requests stringArray
+	Requests       stringArray
+	WarmAll        bool
+	ServiceFilters stringArray
+	FailOnUnknown  bool
+	MetadataFile   string
+	RequestsFile   string
 }
 
 func (g *Grpc) String() string {
@@ -31,12 +37,37 @@ func (g *Grpc) String() string {
 }
 
 func (g *Grpc) initFlags() {
-	flag.Var(&g.Requests, "grpc-requests", `gRPC requests to be sent. Request is in '<service>/<method>[:message]' format. E.g. health/ping:{"key": "value"}`)
+	flag.Var(&g.Requests, "grpc-requests", `gRPC requests to be sent. Request is in '[options]<service>/<method>[:message]' format. E.g. health/ping:{"key": "value"} or [burst=3]health/ping`)
+	flag.StringVar(&g.RequestsFile, "grpc-requests-file", "", "Path to a file with gRPC requests sent in addition to grpc-requests, one request per line in the grpc-requests format or as a JSON object with serviceMethod and message")
+	flag.BoolVar(&g.WarmAll, "grpc-warm-all", false, "If set to true warms up all the gRPC methods discovered via server reflection")
+	flag.BoolVar(&g.FailOnUnknown, "grpc-fail-on-unknown-methods", false, "If set to true no warmup requests are sent and readiness fails if a gRPC method in grpc-requests cannot be resolved via server reflection. Otherwise such methods are skipped with a warning")
+	flag.StringVar(&g.MetadataFile, "grpc-metadata-file", "", "Path to a file with gRPC metadata sent with the warmup requests, one 'key: value' entry per line. Values of -bin keys must be base64 encoded")
+	flag.Var(&g.ServiceFilters, "grpc-warm-all-services", "Glob pattern of the fully-qualified gRPC services to warm up when grpc-warm-all is set, e.g. com.example.search.*")
 }
 
 func (g *Grpc) getWarmupGrpcRequests() ([]grpc.Request, error) {
 	log.Print(g.Requests)
-	return toGrpcRequests(g.Requests)
+	for _, filter := range g.ServiceFilters {
+		if _, err := grpc.MatchesServiceFilters("", []string{filter}); err != nil {
+			return nil, err
+		}
+	}
+	requests, err := toGrpcRequests(g.Requests)
+	if err != nil || g.RequestsFile == "" {
+		return requests, err
+	}
+	fileRequests, err := warmup.LoadGrpcRequestsFromFile(g.RequestsFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(requests, fileRequests...), nil
+}
+
+func (g *Grpc) getGrpcMetadata() ([]string, error) {
+	if g.MetadataFile == "" {
+		return nil, nil
+	}
+	return grpc.LoadMetadataFile(g.MetadataFile)
 }
 
 func toGrpcRequests(requestsFlag []string) ([]grpc.Request, error) {
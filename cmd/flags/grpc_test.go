@@ -34,3 +34,10 @@ func TestGrpc_ToGrpcRequests(t *testing.T) {
 	assert.Equal(t, "svc1/ping", requests[0].ServiceMethod)
 	assert.Equal(t, "svc2/ping", requests[1].ServiceMethod)
 }
+
+func TestGrpc_InvalidServiceFilter(t *testing.T) {
+	g := Grpc{ServiceFilters: []string{"com.example.[search"}}
+
+	_, err := g.getWarmupGrpcRequests()
+	require.Error(t, err)
+}
@@ -18,23 +18,13 @@ import (
 	"flag"
 	"fmt"
 	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/warmup"
 )
 
-var allowedHTTPMethods = map[string]interface{}{
-	"GET":     nil,
-	"HEAD":    nil,
-	"POST":    nil,
-	"PUT":     nil,
-	"PATCH":   nil,
-	"DELETE":  nil,
-	"CONNECT": nil,
-	"OPTIONS": nil,
-	"TRACE":   nil,
-}
-
 // HTTP stores flags related to HTTP requests.
 type HTTP struct {
-	Requests stringArray
+	Requests     stringArray
+	RequestsFile string
 }
 
 func (h *HTTP) String() string {
@@ -42,11 +32,20 @@ func (h *HTTP) String() string {
 }
 
 func (h *HTTP) initFlags() {
-	flag.Var(&h.Requests, "http-requests", `HTTP request to be sent. Request is in '<http-method>:<path>[:body]' format. E.g. post:/ping:{"key":"value"}`)
+	flag.Var(&h.Requests, "http-requests", `HTTP request to be sent. Request is in '[options]<http-method>:<path>[:body]' format. E.g. post:/ping:{"key":"value"} or [burst=3]get:/ping`)
+	flag.StringVar(&h.RequestsFile, "http-requests-file", "", "Path to a file with HTTP requests sent in addition to http-requests, one request per line in the http-requests format or as a JSON object with method, path and body")
 }
 
 func (h *HTTP) getWarmupHTTPRequests() ([]http.Request, error) {
-	return toHTTPRequests(h.Requests)
+	requests, err := toHTTPRequests(h.Requests)
+	if err != nil || h.RequestsFile == "" {
+		return requests, err
+	}
+	fileRequests, err := warmup.LoadHTTPRequestsFromFile(h.RequestsFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(requests, fileRequests...), nil
 }
 
 func toHTTPRequests(requestsFlag []string) ([]http.Request, error) {
@@ -15,13 +15,31 @@
 package flags
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/tokenfile"
+	"mittens/internal/pkg/util"
+	"strings"
+	"time"
 )
 
+// bearerTokenFileTTL is the time after which the token of http-bearer-token-file is read again.
+const bearerTokenFileTTL = time.Second
+
+// defaultSyntheticHeader marks the warmup requests as synthetic unless synthetic-header is set.
+const defaultSyntheticHeader = "X-Mittens-Warmup: true"
+
 // HTTPHeaders stores flags related to HTTP headers.
 type HTTPHeaders struct {
-	Headers stringArray
+	Headers           stringArray
+	CorrelationHeader string
+	CaptureHeaders    stringArray
+	BasicAuth         string
+	BearerToken       string
+	BearerTokenFile   string
+	SyntheticHeader   string
 }
 
 func (h *HTTPHeaders) String() string {
@@ -30,8 +48,81 @@ func (h *HTTPHeaders) String() string {
 
 func (h *HTTPHeaders) initFlags() {
 	flag.Var(&h.Headers, "http-headers", "HTTP header to be sent with warm up requests.")
+	flag.StringVar(&h.CorrelationHeader, "http-correlation-header", "", "Name of a header, e.g. X-Request-Id, in which a new UUID is sent with every request and logged with its result")
+	flag.Var(&h.CaptureHeaders, "http-capture-headers", "Name of an HTTP response header, e.g. X-Cache, whose values are counted per endpoint and logged at the end of the warmup")
+	flag.StringVar(&h.BasicAuth, "http-basic-auth", "", "Credentials, in the username:password form, sent base64-encoded in a basic auth Authorization header with the HTTP and gRPC warm up requests")
+	flag.StringVar(&h.BearerToken, "http-bearer-token", "", "Token sent in a bearer Authorization header with the HTTP and gRPC warm up requests. It can contain placeholders.")
+	flag.StringVar(&h.BearerTokenFile, "http-bearer-token-file", "", "File holding a token sent in a bearer Authorization header with the HTTP and gRPC warm up requests. The file is read again every second so that a token rotated by another process takes effect.")
+	flag.StringVar(&h.SyntheticHeader, "synthetic-header", defaultSyntheticHeader, "Header, in the name: value format, sent with every HTTP and gRPC warm up request that does not set it so that the warmup traffic can be told apart, e.g. in the APM dashboards. Set it to an empty value to disable it")
+}
+
+// getWarmupHTTPHeaders returns the HTTP headers plus the Authorization header of the basic auth or bearer token, if set.
+func (h *HTTPHeaders) getWarmupHTTPHeaders() ([]string, error) {
+	if err := h.validateSyntheticHeader(); err != nil {
+		return nil, err
+	}
+	auth, err := h.getAuthHeader()
+	if err != nil || (auth == "" && h.BearerTokenFile == "") {
+		return h.Headers, err
+	}
+	for name := range util.ToHeaders(h.Headers) {
+		if strings.EqualFold(name, "Authorization") {
+			return nil, errors.New("http-headers already has an Authorization header, it cannot be set with http-basic-auth, http-bearer-token or http-bearer-token-file too")
+		}
+	}
+	if h.BearerTokenFile != "" {
+		// the token is sent by the clients, see getAuthorization, but an unreadable file is rejected upfront
+		_, err := tokenfile.New(h.BearerTokenFile, 0).Token()
+		return h.Headers, err
+	}
+	return append(append([]string{}, h.Headers...), auth), nil
+}
+
+// validateSyntheticHeader returns an error if the synthetic-header is set but has no name.
+func (h *HTTPHeaders) validateSyntheticHeader() error {
+	if strings.TrimSpace(h.SyntheticHeader) == "" {
+		return nil
+	}
+	if name, _, ok := strings.Cut(h.SyntheticHeader, ":"); !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("synthetic-header %s must be in the name: value format", h.SyntheticHeader)
+	}
+	return nil
+}
+
+// getClientHeaders returns the headers the warmup clients add to every request.
+func (h *HTTPHeaders) getClientHeaders() clientHeaders {
+	return clientHeaders{authorization: h.getAuthorization(), synthetic: strings.TrimSpace(h.SyntheticHeader)}
+}
+
+// getAuthorization returns the Authorization header value of the token of http-bearer-token-file, read again every
+// bearerTokenFileTTL, or nil if it is not set.
+func (h *HTTPHeaders) getAuthorization() func() (string, error) {
+	if h.BearerTokenFile == "" {
+		return nil
+	}
+	return tokenfile.New(h.BearerTokenFile, bearerTokenFileTTL).Bearer
 }
 
-func (h *HTTPHeaders) getWarmupHTTPHeaders() []string {
-	return h.Headers
+func (h *HTTPHeaders) getAuthHeader() (string, error) {
+	var set int
+	for _, value := range []string{h.BasicAuth, h.BearerToken, h.BearerTokenFile} {
+		if value != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", errors.New("only one of http-basic-auth, http-bearer-token and http-bearer-token-file can be set")
+	}
+	if h.BasicAuth != "" {
+		username, password, ok := strings.Cut(h.BasicAuth, ":")
+		if !ok {
+			// the value is not logged as it is a credential
+			return "", errors.New("http-basic-auth must be in the username:password form")
+		}
+		return http.BasicAuthHeader(username, password), nil
+	}
+	if h.BearerToken != "" {
+		return http.BearerAuthHeader(h.BearerToken), nil
+	}
+	return "", nil
 }
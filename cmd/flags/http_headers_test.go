@@ -0,0 +1,87 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package flags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPHeaders_BasicAuth(t *testing.T) {
+	h := HTTPHeaders{Headers: []string{"X-Tenant: acme"}, BasicAuth: "user:s3cret"}
+
+	headers, err := h.getWarmupHTTPHeaders()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"X-Tenant: acme", "Authorization: Basic dXNlcjpzM2NyZXQ="}, headers)
+	assert.Equal(t, []string{"X-Tenant: acme"}, []string(h.Headers))
+}
+
+func TestHTTPHeaders_BearerToken(t *testing.T) {
+	h := HTTPHeaders{BearerToken: "token-{$uuid}"}
+
+	headers, err := h.getWarmupHTTPHeaders()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Authorization: Bearer token-{$uuid}"}, headers)
+}
+
+func TestHTTPHeaders_InvalidAuth(t *testing.T) {
+	_, err := (&HTTPHeaders{BasicAuth: "user"}).getWarmupHTTPHeaders()
+	assert.EqualError(t, err, "http-basic-auth must be in the username:password form")
+
+	_, err = (&HTTPHeaders{BasicAuth: "user:s3cret", BearerToken: "token"}).getWarmupHTTPHeaders()
+	assert.Error(t, err)
+
+	_, err = (&HTTPHeaders{Headers: []string{"authorization: Bearer other"}, BearerToken: "token"}).getWarmupHTTPHeaders()
+	assert.Error(t, err)
+}
+
+func TestHTTPHeaders_BearerTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0600))
+	h := HTTPHeaders{Headers: []string{"X-Tenant: acme"}, BearerTokenFile: path}
+
+	headers, err := h.getWarmupHTTPHeaders()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"X-Tenant: acme"}, headers, "the token is sent by the clients")
+	authorization, err := h.getAuthorization()()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer s3cret", authorization)
+
+	assert.Nil(t, (&HTTPHeaders{}).getAuthorization())
+	_, err = (&HTTPHeaders{BearerTokenFile: filepath.Join(t.TempDir(), "missing")}).getWarmupHTTPHeaders()
+	assert.Error(t, err)
+	_, err = (&HTTPHeaders{BearerToken: "token", BearerTokenFile: path}).getWarmupHTTPHeaders()
+	assert.Error(t, err)
+}
+
+func TestHTTPHeaders_SyntheticHeader(t *testing.T) {
+	h := HTTPHeaders{Headers: []string{"X-Tenant: acme"}, SyntheticHeader: defaultSyntheticHeader}
+
+	headers, err := h.getWarmupHTTPHeaders()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"X-Tenant: acme"}, headers)
+	assert.Equal(t, "X-Mittens-Warmup: true", h.getClientHeaders().synthetic)
+
+	assert.Empty(t, (&HTTPHeaders{SyntheticHeader: " "}).getClientHeaders().synthetic)
+
+	_, err = (&HTTPHeaders{SyntheticHeader: "X-Mittens-Warmup"}).getWarmupHTTPHeaders()
+	assert.EqualError(t, err, "synthetic-header X-Mittens-Warmup must be in the name: value format")
+	_, err = (&HTTPHeaders{SyntheticHeader: ": true"}).getWarmupHTTPHeaders()
+	assert.Error(t, err)
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"mittens/internal/pkg/http"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -52,14 +54,14 @@ func TestHttp_ToHttpRequestsInvalidFormat(t *testing.T) {
 func TestHttp_ToHttpRequestsInvalidMethod(t *testing.T) {
 
 	requestFlags := []string{
-		"invalidMethod:/health",
+		"inv@lid:/health",
 	}
 
 	requests, err := toHTTPRequests(requestFlags)
 
 	var expected []http.Request
 	require.Error(t, err)
-	require.Equal(t, "invalid request flag: invalidMethod:/health, method INVALIDMETHOD is not supported", err.Error())
+	require.Equal(t, "invalid request flag: inv@lid:/health, method INV@LID is not a valid HTTP method, expected a token such as GET or PURGE", err.Error())
 	require.Equal(t, expected, requests)
 }
 
@@ -76,3 +78,16 @@ func TestHttp_ToHttpRequestsInvalidBody(t *testing.T) {
 	require.Equal(t, "unable to parse body for request: file:test", err.Error())
 	require.Equal(t, expected, requests)
 }
+
+func TestHttp_RequestsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests")
+	require.NoError(t, os.WriteFile(path, []byte("post:/search:{\"q\":\"shoes\"}\n"), 0644))
+	h := HTTP{Requests: []string{"get:/ping"}, RequestsFile: path}
+
+	requests, err := h.getWarmupHTTPRequests()
+	require.NoError(t, err)
+
+	require.Equal(t, 2, len(requests))
+	assert.Equal(t, "/ping", requests[0].Path)
+	assert.Equal(t, "/search", requests[1].Path)
+}
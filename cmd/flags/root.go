@@ -17,9 +17,17 @@ package flags
 import (
 	"flag"
 	"fmt"
+	"math/rand"
 	"mittens/internal/pkg/grpc"
 	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/marker"
+	"mittens/internal/pkg/resultlog"
+	"mittens/internal/pkg/stopcondition"
 	"mittens/internal/pkg/warmup"
+	"net"
+	"os"
+	"strconv"
+	"time"
 )
 
 // Root stores all the flags.
@@ -29,9 +37,64 @@ type Root struct {
 	MaxWarmupDurationSeconds int
 	Concurrency              int
 	RequestDelayMilliseconds int
+	DelayJitterMilliseconds  int
+	SlowRequestThresholdMs   int
 	ConcurrencyTargetSeconds int
+	RampDownSeconds          int
+	RampUpCurve              string
+	RampUpShape              float64
 	ExitAfterWarmup          bool
 	FailReadiness            bool
+	RequireAllEndpointsOk    bool
+	MinSuccess               int
+	MinSuccessPerEndpoint    bool
+	MaxRequests              int
+	RequestOrder             string
+	Markers                  string
+	RequestLogFormat         string
+	GoldenNormalizeJSON      bool
+	GoldenPrintDiff          bool
+	RewarmIntervalSeconds    int
+	RewarmCloseConnections   bool
+	RewarmGrpcPing           string
+	RewarmGrpcPingSeconds    int
+	TargetRequestsPerSecond  int
+	MinConcurrency           int
+	MaxConcurrency           int
+	JUnitOut                 string
+	LatencyDump              string
+	TimeseriesOut            string
+	Rate                     float64
+	PerWorkerRate            float64
+	StopCondition            string
+	MaxLatencyViolationPct   float64
+	AbortP99Latency          time.Duration
+	AbortP99WindowSeconds    int
+	AbortP99SustainSeconds   int
+	AbortErrorRate           float64
+	AbortErrorWindowSeconds  int
+	AbortErrorMinRequests    int
+	Retries                  int
+	MaxResponseBodyBytes     int
+	RetryBackoff             time.Duration
+	ControlPort              int
+	ControlBindAddress       string
+	MetricsPort              int
+	MetricsBindAddress       string
+	ProtocolMix              string
+	Record                   string
+	Replay                   string
+	SampleRate               float64
+	Seed                     int64
+	StopConditionPollSeconds int
+	SelfTest                 bool
+	DryRun                   bool
+	SummaryLine              bool
+	SummaryJSON              string
+	TotalDuration            time.Duration
+	ConfigURL                string
+	ConfigURLHeader          string
+	ConfigFallbackFile       string
 	FileProbe
 	Target
 	HTTP
@@ -49,11 +112,66 @@ func (r *Root) InitFlags() {
 	flag.IntVar(&r.MaxDurationSeconds, "max-duration-seconds", 60, "Global maximum duration. This includes both the time spent warming up the target service and also the time waiting for the target to become ready")
 	flag.IntVar(&r.MaxReadinessWaitSeconds, "max-readiness-wait-seconds", 30, "Maximum time to wait for the target to become ready")
 	flag.IntVar(&r.MaxWarmupDurationSeconds, "max-warmup-seconds", 30, "Maximum time spent sending warmup requests to the target service. Please note that `max-duration-seconds` may cap this duration.")
-	flag.IntVar(&r.Concurrency, "concurrency", 2, "Number of concurrent requests for warm up")
+	flag.DurationVar(&r.TotalDuration, "total-duration", 0, "If set, e.g. 5m, bounds the whole run end to end: readiness wait, ramp-up, steady warmup, ramp-down and drain of the requests in flight. It overrides max-duration-seconds and max-warmup-seconds and the steady phase gets whatever time is left")
+	flag.IntVar(&r.Concurrency, "concurrency", 2, "Number of concurrent requests for warm up. 0 uses the number of CPUs.")
+	flag.IntVar(&r.TargetRequestsPerSecond, "target-requests-per-second", 0, "If greater than 0 the concurrency is picked automatically to reach this rate based on the latency measured at the start of the warmup. This overrides concurrency.")
+	flag.IntVar(&r.MinConcurrency, "min-concurrency", 1, "Minimum concurrency picked when target-requests-per-second is set")
+	flag.IntVar(&r.MaxConcurrency, "max-concurrency", 50, "Maximum concurrency picked when target-requests-per-second is set")
+	flag.Float64Var(&r.Rate, "rate", 0, "Maximum number of requests per second sent by all the workers together. 0 means no limit")
+	flag.Float64Var(&r.PerWorkerRate, "per-worker-rate", 0, "Maximum number of requests per second sent by each worker. 0 means no limit")
+	flag.StringVar(&r.ProtocolMix, "protocol-mix", "", "If set the same workers send both HTTP and gRPC requests in this proportion, e.g. http=70,grpc=30, instead of running separate HTTP and gRPC workers")
 	flag.IntVar(&r.RequestDelayMilliseconds, "request-delay-milliseconds", 500, "Delay in milliseconds between requests")
+	flag.IntVar(&r.DelayJitterMilliseconds, "request-delay-jitter-milliseconds", 0, "If greater than 0 every delay between requests is randomized by up to this number of milliseconds either way, so that the workers do not send their requests in sync")
+	flag.IntVar(&r.SlowRequestThresholdMs, "slow-request-threshold-milliseconds", 0, "If greater than 0 the requests slower than this number of milliseconds are logged with a distinct marker and the slowest of them are listed at the end of the warmup")
 	flag.IntVar(&r.ConcurrencyTargetSeconds, "concurrency-target-seconds", 0, "Time taken to reach expected concurrency. This is useful to ramp up traffic.")
+	flag.StringVar(&r.RampUpCurve, "concurrency-ramp-up-curve", warmup.LinearRampUp, "How the workers are started over concurrency-target-seconds. One of [linear, exponential]. exponential starts them slowly at first and then faster and faster")
+	flag.Float64Var(&r.RampUpShape, "concurrency-ramp-up-shape", warmup.DefaultRampUpShape, "Shape of the exponential concurrency-ramp-up-curve, greater than 0. The greater, the slower the start of the ramp-up")
+	flag.IntVar(&r.RampDownSeconds, "concurrency-ramp-down-seconds", 0, "Time before the end of the warmup during which the concurrency is gradually reduced to 1. This is useful to avoid stopping abruptly at full load. 0 disables the ramp-down")
 	flag.BoolVar(&r.ExitAfterWarmup, "exit-after-warmup", false, "If warm up process should finish after completion. This is useful to prevent container restarts.")
 	flag.BoolVar(&r.FailReadiness, "fail-readiness", false, "If set to true readiness will fail if no requests were sent.")
+	flag.StringVar(&r.RequestOrder, "request-order", warmup.RandomOrder, "Order in which requests are sent. One of [random, shuffle, sequential]. With shuffle every request is sent once per cycle in a random order. With sequential the requests are sent in the order they are configured, over and over.")
+	flag.StringVar(&r.Markers, "markers", marker.Auto, "Markers used in the logs to flag successes and failures. One of [auto, emoji, color, plain]. auto uses emoji when logging to a terminal and plain text otherwise.")
+	flag.StringVar(&r.RequestLogFormat, "request-log-format", resultlog.Text, "Format of the result of every request. One of [text, json]. json writes every result to stdout as a JSON object with the protocol, method, path or serviceMethod, status, duration_ms, error and attempt instead of logging it as a text line.")
+	flag.BoolVar(&r.GoldenNormalizeJSON, "golden-normalize-json", false, "If set to true JSON responses are compared against their golden files regardless of field order and whitespace.")
+	flag.BoolVar(&r.GoldenPrintDiff, "golden-print-diff", false, "If set to true the first difference between a response and its golden file is logged.")
+	flag.IntVar(&r.RewarmIntervalSeconds, "rewarm-interval-seconds", 0, "If greater than 0 the warmup runs again every given number of seconds after it completes. Ignored if exit-after-warmup is set to true.")
+	flag.BoolVar(&r.RewarmCloseConnections, "rewarm-close-connections", false, "If set to true the connections to the target are closed after every warmup cycle and established again on the next one instead of being reused.")
+	flag.StringVar(&r.RewarmGrpcPing, "rewarm-grpc-ping", "", "If set this gRPC request, in '<service>/<method>[:message]' format, is sent every rewarm-grpc-ping-interval-seconds between warmup cycles to keep the connection and the target state warm. Pick a cheap method")
+	flag.IntVar(&r.RewarmGrpcPingSeconds, "rewarm-grpc-ping-interval-seconds", 10, "Interval in seconds at which rewarm-grpc-ping is sent between warmup cycles")
+	flag.StringVar(&r.JUnitOut, "junit-out", "", "If set the outcome of the warmup is written to this file as JUnit XML, with a test case per endpoint")
+	flag.StringVar(&r.LatencyDump, "latency-dump", "", "If set the latency distribution of all the requests is written to this file, e.g. out.hgrm, in the HDR histogram percentile format that tools like hdr-plot render")
+	flag.StringVar(&r.TimeseriesOut, "timeseries-out", "", "If set the requests sent, successes, failures and p95 latency of every second of the warmup are written to this file, as JSON if it ends with .json and as CSV otherwise")
+	flag.StringVar(&r.StopCondition, "stop-condition", "", "If set the warmup stops as soon as this condition is satisfied. Either file:<path>, satisfied once the file exists, or an http(s) URL, satisfied once it responds 200 with the body 'stop'.")
+	flag.IntVar(&r.StopConditionPollSeconds, "stop-condition-poll-seconds", 1, "Interval in seconds at which the stop-condition is checked")
+	flag.Float64Var(&r.MaxLatencyViolationPct, "max-latency-violation-percent", 100, "Readiness fails if more than this percentage of the successful responses were slower than the max-latency option of their request")
+	flag.DurationVar(&r.AbortP99Latency, "abort-p99-latency", 0, "If greater than 0 the warmup is aborted once the p99 latency of the recent requests stays above this ceiling, e.g. 500ms, as the warmup is then likely harming the target. 0 disables it")
+	flag.IntVar(&r.AbortP99WindowSeconds, "abort-p99-window-seconds", 10, "Number of most recent seconds over which the p99 latency is computed for abort-p99-latency")
+	flag.IntVar(&r.AbortP99SustainSeconds, "abort-p99-sustain-seconds", 5, "Number of seconds in a row the p99 latency must stay above abort-p99-latency before the warmup is aborted")
+	flag.Float64Var(&r.AbortErrorRate, "abort-error-rate", 0, "If greater than 0 the warmup is aborted, and fails, once more than this percentage of the recent requests failed, e.g. 90, as the target is then likely broken. 0 disables it")
+	flag.IntVar(&r.AbortErrorWindowSeconds, "abort-error-rate-window-seconds", 10, "Number of most recent seconds over which the error rate is computed for abort-error-rate")
+	flag.IntVar(&r.AbortErrorMinRequests, "abort-error-rate-min-requests", 20, "Minimum number of requests sent during the window before the error rate is checked against abort-error-rate")
+	flag.IntVar(&r.MaxResponseBodyBytes, "max-response-body-bytes", 1024*1024, "Maximum number of bytes of a response body searched for the substring of the expect-body option of its request. The rest of the body is read but discarded")
+	flag.IntVar(&r.Retries, "retries", 0, "Number of times a warmup request that failed with a transport error or a 5xx status code is sent again before it counts as failed. Useful while the target is still booting")
+	flag.DurationVar(&r.RetryBackoff, "retry-backoff", 100*time.Millisecond, "Delay before the first retry of a failed request, e.g. 200ms. It doubles with every retry")
+	flag.IntVar(&r.ControlPort, "control-port", 0, "If greater than 0 mittens serves a control endpoint on this port, e.g. to change the concurrency while the warmup runs")
+	flag.StringVar(&r.ControlBindAddress, "control-bind-address", "127.0.0.1", "Address the control endpoint listens on. Only local clients can reach it by default")
+	flag.IntVar(&r.MetricsPort, "metrics-port", 0, "If greater than 0 mittens serves Prometheus metrics of the warmup on this port at /metrics: the requests sent and failed and their durations by protocol, and the current concurrency")
+	flag.StringVar(&r.MetricsBindAddress, "metrics-bind-address", "0.0.0.0", "Address the metrics endpoint listens on")
+	flag.StringVar(&r.Record, "record", "", "If set every request sent, along with a summary of its response, is written to this file as newline-delimited JSON that can be replayed with replay")
+	flag.StringVar(&r.Replay, "replay", "", "If set the requests recorded in this file with record are sent in addition to http-requests and grpc-requests")
+	flag.StringVar(&r.ConfigURL, "config-url", "", "If set the YAML or JSON config at this URL is fetched at startup and its http-requests and grpc-requests are sent in addition to the ones of the flags")
+	flag.StringVar(&r.ConfigURLHeader, "config-url-header", "", "Header sent when fetching config-url, e.g. 'Authorization: Bearer <token>'")
+	flag.StringVar(&r.ConfigFallbackFile, "config-fallback-file", "", "Local config used instead of config-url if it cannot be fetched or is invalid")
+	flag.Float64Var(&r.SampleRate, "sample-rate", 1, "Fraction, between 0 and 1, of the HTTP and of the gRPC requests that are randomly selected at startup to be warmed up, e.g. 0.1. This bounds the warmup of large request sets")
+	flag.Int64Var(&r.Seed, "seed", 0, "Seed used to select the sample-rate requests and to seed the worker placeholders, so that the same requests and values are used on every run. 0 uses different ones every time")
+	flag.StringVar(&r.SummaryJSON, "summary-json", "", "If set the summary of the warmup is written to this file as JSON: the requests sent, successes, failures and p50, p90 and p99 latencies in total and for every endpoint, and whether mittens became ready")
+	flag.BoolVar(&r.SummaryLine, "summary-line", false, "If set to true a single line summarising the warmup, starting with MITTENS_SUMMARY and made of stable key=value pairs, is printed to stdout at the end")
+	flag.BoolVar(&r.SelfTest, "self-test", false, "If set to true mittens warms up a built-in mock server with known latencies instead of the target, checks the latency percentiles it measures and exits with 0 if they are accurate and 1 otherwise")
+	flag.BoolVar(&r.DryRun, "dry-run", false, "If set to true no warmup request is sent. Once the target is ready mittens only checks that every HTTP request is valid and that every gRPC method and message can be resolved via server reflection, logs the invalid requests and exits with 0 if there are none and 5 otherwise")
+	flag.IntVar(&r.MaxRequests, "max-requests", 0, "If greater than 0 the warmup stops once this number of requests, HTTP and gRPC together, was sent, even if its duration is not over. 0 means no limit")
+	flag.IntVar(&r.MinSuccess, "min-success", 0, "If greater than 0 the warmup stops as soon as this number of requests succeeded, and readiness fails if the warmup duration elapses first. 0 disables the gate")
+	flag.BoolVar(&r.MinSuccessPerEndpoint, "min-success-per-endpoint", false, "If set to true min-success applies to every endpoint instead of to all the requests together")
+	flag.BoolVar(&r.RequireAllEndpointsOk, "require-all-endpoints-ok", false, "If set to true readiness will fail unless every request returned at least one successful response.")
 
 	r.FileProbe.initFlags()
 	r.Target.initFlags()
@@ -77,34 +195,290 @@ func (r *Root) GetMaxWarmupDurationSeconds() int {
 	return r.MaxWarmupDurationSeconds
 }
 
-// GetConcurrencyTargetSeconds returns the value of the concurrency-target-seconds parameter.
-func (r *Root) GetConcurrencyTargetSeconds() int {
-	return r.ConcurrencyTargetSeconds
+// GetConcurrencyTargetSeconds validates and returns the value of the concurrency-target-seconds parameter.
+func (r *Root) GetConcurrencyTargetSeconds() (int, error) {
+	if r.ConcurrencyTargetSeconds < 0 {
+		return 0, fmt.Errorf("concurrency-target-seconds must not be negative")
+	}
+	return r.ConcurrencyTargetSeconds, nil
+}
+
+// GetRampDownSeconds validates and returns the value of the concurrency-ramp-down-seconds parameter.
+func (r *Root) GetRampDownSeconds() (int, error) {
+	if r.RampDownSeconds < 0 {
+		return 0, fmt.Errorf("concurrency-ramp-down-seconds must not be negative")
+	}
+	return r.RampDownSeconds, nil
+}
+
+// GetRampUp validates and returns the values of the concurrency-ramp-up-curve and concurrency-ramp-up-shape parameters.
+func (r *Root) GetRampUp() (string, float64, error) {
+	if err := warmup.ValidateRampUp(r.RampUpCurve, r.RampUpShape); err != nil {
+		return "", 0, err
+	}
+	return r.RampUpCurve, r.RampUpShape, nil
+}
+
+// GetRewarmGrpcPing validates and returns the gRPC request sent between warmup cycles, nil if not set, along with its interval.
+func (r *Root) GetRewarmGrpcPing() (*grpc.Request, time.Duration, error) {
+	if r.RewarmGrpcPing == "" {
+		return nil, 0, nil
+	}
+	if r.RewarmGrpcPingSeconds < 1 {
+		return nil, 0, fmt.Errorf("rewarm-grpc-ping-interval-seconds must be at least 1")
+	}
+	request, err := grpc.ToGrpcRequest(r.RewarmGrpcPing)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &request, time.Duration(r.RewarmGrpcPingSeconds) * time.Second, nil
+}
+
+// GetConfigURLHeaders returns the headers sent when fetching the config-url.
+func (r *Root) GetConfigURLHeaders() []string {
+	if r.ConfigURLHeader == "" {
+		return nil
+	}
+	return []string{r.ConfigURLHeader}
+}
+
+// GetMinSuccess validates and returns the value of the min-success parameter.
+func (r *Root) GetMinSuccess() (int, error) {
+	if r.MinSuccess < 0 {
+		return 0, fmt.Errorf("min-success must not be negative")
+	}
+	return r.MinSuccess, nil
+}
+
+// GetMaxRequests validates and returns the value of the max-requests parameter.
+func (r *Root) GetMaxRequests() (int, error) {
+	if r.MaxRequests < 0 {
+		return 0, fmt.Errorf("max-requests must not be negative")
+	}
+	return r.MaxRequests, nil
+}
+
+// GetTotalDurationSeconds validates and returns the value of the total-duration parameter in whole seconds, 0 if not set.
+func (r *Root) GetTotalDurationSeconds() (int, error) {
+	if r.TotalDuration < 0 {
+		return 0, fmt.Errorf("total-duration must not be negative")
+	}
+	if r.TotalDuration > 0 && r.TotalDuration < time.Second {
+		return 0, fmt.Errorf("total-duration must be at least 1s")
+	}
+	return int(r.TotalDuration / time.Second), nil
+}
+
+// GetConcurrency validates and returns the value of the concurrency parameter.
+func (r *Root) GetConcurrency() (int, error) {
+	if r.Concurrency < 0 {
+		return 0, fmt.Errorf("concurrency must not be negative, 0 uses the number of CPUs")
+	}
+	return r.Concurrency, nil
+}
+
+// GetTargetRequestsPerSecond validates and returns the value of the target-requests-per-second parameter
+// along with the min-concurrency and max-concurrency bounds.
+func (r *Root) GetTargetRequestsPerSecond() (int, int, int, error) {
+	if r.TargetRequestsPerSecond < 0 {
+		return 0, 0, 0, fmt.Errorf("target-requests-per-second must not be negative")
+	}
+	if r.MinConcurrency < 1 || r.MaxConcurrency < r.MinConcurrency {
+		return 0, 0, 0, fmt.Errorf("min-concurrency must be at least 1 and not greater than max-concurrency")
+	}
+	return r.TargetRequestsPerSecond, r.MinConcurrency, r.MaxConcurrency, nil
+}
+
+// GetGrpcFailOnUnknownMethods returns the value of the grpc-fail-on-unknown-methods parameter.
+func (r *Root) GetGrpcFailOnUnknownMethods() bool {
+	return r.Grpc.FailOnUnknown
+}
+
+// GetRateLimits validates and returns the values of the rate and per-worker-rate parameters.
+func (r *Root) GetRateLimits() (float64, float64, error) {
+	if r.Rate < 0 || r.PerWorkerRate < 0 {
+		return 0, 0, fmt.Errorf("rate and per-worker-rate must not be negative")
+	}
+	return r.Rate, r.PerWorkerRate, nil
+}
+
+// GetMaxLatencyViolationPercent validates and returns the value of the max-latency-violation-percent parameter.
+func (r *Root) GetMaxLatencyViolationPercent() (float64, error) {
+	if r.MaxLatencyViolationPct < 0 || r.MaxLatencyViolationPct > 100 {
+		return 0, fmt.Errorf("max-latency-violation-percent must be between 0 and 100")
+	}
+	return r.MaxLatencyViolationPct, nil
+}
+
+// GetAbortP99 validates and returns the p99 latency ceiling above which the warmup is aborted, 0 if disabled,
+// along with the window and sustain durations in seconds.
+func (r *Root) GetAbortP99() (time.Duration, int, int, error) {
+	if r.AbortP99Latency < 0 {
+		return 0, 0, 0, fmt.Errorf("abort-p99-latency must not be negative")
+	}
+	if r.AbortP99Latency > 0 && (r.AbortP99WindowSeconds < 1 || r.AbortP99SustainSeconds < 1) {
+		return 0, 0, 0, fmt.Errorf("abort-p99-window-seconds and abort-p99-sustain-seconds must be at least 1")
+	}
+	return r.AbortP99Latency, r.AbortP99WindowSeconds, r.AbortP99SustainSeconds, nil
+}
+
+// GetAbortErrorRate validates and returns the percentage of failed requests above which the warmup is aborted, 0 if disabled,
+// along with the window in seconds and the minimum number of requests of the window.
+func (r *Root) GetAbortErrorRate() (float64, int, int, error) {
+	if r.AbortErrorRate < 0 || r.AbortErrorRate >= 100 {
+		return 0, 0, 0, fmt.Errorf("abort-error-rate must be at least 0 and less than 100")
+	}
+	if r.AbortErrorRate > 0 && (r.AbortErrorWindowSeconds < 1 || r.AbortErrorMinRequests < 1) {
+		return 0, 0, 0, fmt.Errorf("abort-error-rate-window-seconds and abort-error-rate-min-requests must be at least 1")
+	}
+	return r.AbortErrorRate, r.AbortErrorWindowSeconds, r.AbortErrorMinRequests, nil
+}
+
+// GetDelayJitterMilliseconds validates and returns the value of the request-delay-jitter-milliseconds parameter.
+func (r *Root) GetDelayJitterMilliseconds() (int, error) {
+	if r.DelayJitterMilliseconds < 0 {
+		return 0, fmt.Errorf("request-delay-jitter-milliseconds must not be negative")
+	}
+	return r.DelayJitterMilliseconds, nil
+}
+
+// GetSlowRequestThresholdMilliseconds validates and returns the value of the slow-request-threshold-milliseconds parameter.
+func (r *Root) GetSlowRequestThresholdMilliseconds() (int, error) {
+	if r.SlowRequestThresholdMs < 0 {
+		return 0, fmt.Errorf("slow-request-threshold-milliseconds must not be negative")
+	}
+	return r.SlowRequestThresholdMs, nil
+}
+
+// GetMaxResponseBodyBytes validates and returns the value of the max-response-body-bytes parameter.
+func (r *Root) GetMaxResponseBodyBytes() (int, error) {
+	if r.MaxResponseBodyBytes < 1 {
+		return 0, fmt.Errorf("max-response-body-bytes must be at least 1")
+	}
+	return r.MaxResponseBodyBytes, nil
+}
+
+// GetRetries validates and returns the number of retries of a failed request and the delay before the first one.
+func (r *Root) GetRetries() (int, time.Duration, error) {
+	if r.Retries < 0 || r.RetryBackoff < 0 {
+		return 0, 0, fmt.Errorf("retries and retry-backoff must not be negative")
+	}
+	return r.Retries, r.RetryBackoff, nil
+}
+
+// GetSampling validates and returns the value of the sample-rate parameter and the random source used to sample the requests.
+func (r *Root) GetSampling() (float64, *rand.Rand, error) {
+	if r.SampleRate <= 0 || r.SampleRate > 1 {
+		return 0, nil, fmt.Errorf("sample-rate must be greater than 0 and at most 1")
+	}
+	seed := r.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return r.SampleRate, rand.New(rand.NewSource(seed)), nil
+}
+
+// GetWorkerSeed returns the seed of the worker placeholders of the first worker: the seed parameter,
+// or the current time if it is not set.
+func (r *Root) GetWorkerSeed() int64 {
+	if r.Seed == 0 {
+		return time.Now().UnixNano()
+	}
+	return r.Seed
+}
+
+// GetProtocolMix validates and returns the value of the protocol-mix parameter, or nil if it is not set.
+func (r *Root) GetProtocolMix() (*warmup.ProtocolMix, error) {
+	if r.ProtocolMix == "" {
+		return nil, nil
+	}
+	mix, err := warmup.ParseProtocolMix(r.ProtocolMix)
+	if err != nil {
+		return nil, err
+	}
+	return &mix, nil
+}
+
+// GetControlAddress validates and returns the address of the control endpoint, or "" if it is disabled.
+func (r *Root) GetControlAddress() (string, error) {
+	if r.ControlPort == 0 {
+		return "", nil
+	}
+	if r.ControlPort < 0 || r.ControlPort > 65535 {
+		return "", fmt.Errorf("control-port must be between 0 and 65535")
+	}
+	return net.JoinHostPort(r.ControlBindAddress, strconv.Itoa(r.ControlPort)), nil
+}
+
+// GetMetricsAddress validates and returns the address the metrics endpoint listens on, or an empty string if it is disabled.
+func (r *Root) GetMetricsAddress() (string, error) {
+	if r.MetricsPort == 0 {
+		return "", nil
+	}
+	if r.MetricsPort < 0 || r.MetricsPort > 65535 {
+		return "", fmt.Errorf("metrics-port must be between 0 and 65535")
+	}
+	return net.JoinHostPort(r.MetricsBindAddress, strconv.Itoa(r.MetricsPort)), nil
+}
+
+// GetStopCondition validates and returns the stop-condition parameter, or nil if it is not set, along with its poll interval.
+func (r *Root) GetStopCondition() (stopcondition.Condition, time.Duration, error) {
+	if r.StopCondition == "" {
+		return nil, 0, nil
+	}
+	if r.StopConditionPollSeconds <= 0 {
+		return nil, 0, fmt.Errorf("stop-condition-poll-seconds must be greater than 0")
+	}
+	condition, err := stopcondition.Parse(r.StopCondition)
+	if err != nil {
+		return nil, 0, err
+	}
+	return condition, time.Duration(r.StopConditionPollSeconds) * time.Second, nil
+}
+
+// GetRequestOrder validates and returns the value of the request-order parameter.
+func (r *Root) GetRequestOrder() (string, error) {
+	if err := warmup.ValidateRequestOrder(r.RequestOrder); err != nil {
+		return "", err
+	}
+	return r.RequestOrder, nil
 }
 
-// GetConcurrency returns the value of the concurrency parameter.
-func (r *Root) GetConcurrency() int {
-	return r.Concurrency
+// GetResultLogger returns the logger of the results of the requests, nil if they are logged as text lines.
+func (r *Root) GetResultLogger() (*resultlog.Logger, error) {
+	return resultlog.New(r.RequestLogFormat, os.Stdout)
+}
+
+// PrimeDNS resolves the target hosts if the dns-prime parameter is set.
+func (r *Root) PrimeDNS() {
+	if r.Target.DNSPrime {
+		r.Target.primeDNS()
+	}
 }
 
 // GetReadinessHTTPClient creates the HTTP client to be used for the readiness requests.
-func (r *Root) GetReadinessHTTPClient() http.Client {
+func (r *Root) GetReadinessHTTPClient() (http.Client, error) {
 	return r.Target.getReadinessHTTPClient()
 }
 
 // GetReadinessGrpcClient creates the gRPC client to be used for the readiness requests.
-func (r *Root) GetReadinessGrpcClient() grpc.Client {
+func (r *Root) GetReadinessGrpcClient() (grpc.Client, error) {
 	return r.Target.getReadinessGrpcClient()
 }
 
 // GetHTTPClient creates the HTTP client to be used for the actual requests.
-func (r *Root) GetHTTPClient() http.Client {
-	return r.Target.getHTTPClient()
+func (r *Root) GetHTTPClient() (http.Client, error) {
+	return r.Target.getHTTPClient(r.HTTPHeaders.getClientHeaders())
 }
 
 // GetGrpcClient creates the gRPC client to be used for the actual requests.
-func (r *Root) GetGrpcClient() grpc.Client {
-	return r.Target.getGrpcClient()
+func (r *Root) GetGrpcClient() (grpc.Client, error) {
+	return r.Target.getGrpcClient(r.HTTPHeaders.getClientHeaders())
+}
+
+// GetAdditionalGrpcClients creates the gRPC clients of the additional gRPC hosts to be used for the actual requests.
+func (r *Root) GetAdditionalGrpcClients() ([]grpc.Client, error) {
+	return r.Target.getAdditionalGrpcClients(r.HTTPHeaders.getClientHeaders())
 }
 
 // GetWarmupTargetOptions validates and returns any options that apply to the target.
@@ -114,14 +488,71 @@ func (r *Root) GetWarmupTargetOptions() (warmup.TargetOptions, error) {
 		err := fmt.Errorf("readiness protocol %s not supported, please use http or grpc", r.ReadinessProtocol)
 		return options, err
 	}
+	if err := r.Target.validateClientCertificate(); err != nil {
+		return options, err
+	}
+	if err := r.Target.validateCAFile(); err != nil {
+		return options, err
+	}
+	if err := r.Target.validateDescriptorSource(); err != nil {
+		return options, err
+	}
+	if err := r.Target.validateConnectProxy(); err != nil {
+		return options, err
+	}
+	if err := r.Target.validateHostOverrides(); err != nil {
+		return options, err
+	}
+	if err := grpc.ValidateFormat(r.Target.GrpcRequestFormat); err != nil {
+		return options, err
+	}
+	if r.Target.HTTPHedgePercentile < 0 || r.Target.HTTPHedgePercentile >= 100 {
+		return options, fmt.Errorf("http-hedge-percentile must be between 0 and 100")
+	}
+	if r.Target.HTTPDialTimeout < 0 {
+		return options, fmt.Errorf("http-dial-timeout must not be negative")
+	}
+	if r.Target.GrpcDialTimeout < 0 {
+		return options, fmt.Errorf("grpc-dial-timeout must not be negative")
+	}
+	if r.Target.HTTPMaxConnsPerHost < 0 {
+		return options, fmt.Errorf("http-max-connections-per-host must not be negative")
+	}
+	if r.Target.HTTPMaxIdleConns < 0 || r.Target.HTTPMaxIdleConnsPerHost < 0 {
+		return options, fmt.Errorf("http-max-idle-connections and http-max-idle-connections-per-host must not be negative")
+	}
+	if r.Target.HTTPReadTimeout < 0 || r.Target.HTTPWriteTimeout < 0 {
+		return options, fmt.Errorf("read-timeout and write-timeout must not be negative")
+	}
+	if r.Target.HTTPExpectContinueTimeout < 0 {
+		return options, fmt.Errorf("http-expect-continue-timeout must not be negative")
+	}
+	if r.Target.ConnectionPoolSize < 0 || r.Target.PoolHealthCheckInterval < 0 {
+		return options, fmt.Errorf("connection-pool-size and connection-pool-health-check-interval must not be negative")
+	}
 	return options, nil
 }
 
-// GetWarmupHTTPHeaders returns the HTTP headers.
-func (r *Root) GetWarmupHTTPHeaders() []string {
+// GetWarmupHTTPHeaders returns the HTTP headers, including the Authorization header of http-basic-auth or http-bearer-token.
+func (r *Root) GetWarmupHTTPHeaders() ([]string, error) {
 	return r.HTTPHeaders.getWarmupHTTPHeaders()
 }
 
+// GetGrpcMetadata returns the gRPC metadata read from the grpc-metadata-file, if set.
+func (r *Root) GetGrpcMetadata() ([]string, error) {
+	return r.Grpc.getGrpcMetadata()
+}
+
+// GetCorrelationHeader returns the name of the header in which a correlation id is sent with every request, if any.
+func (r *Root) GetCorrelationHeader() string {
+	return r.HTTPHeaders.CorrelationHeader
+}
+
+// GetCaptureHeaders returns the names of the HTTP response headers whose values are counted.
+func (r *Root) GetCaptureHeaders() []string {
+	return r.HTTPHeaders.CaptureHeaders
+}
+
 // GetWarmupHTTPRequests HTTP requests.
 func (r *Root) GetWarmupHTTPRequests() ([]http.Request, error) {
 	requests, err := r.HTTP.getWarmupHTTPRequests()
@@ -131,6 +562,16 @@ func (r *Root) GetWarmupHTTPRequests() ([]http.Request, error) {
 	return requests, nil
 }
 
+// GetGrpcWarmAll returns the value of the grpc-warm-all parameter.
+func (r *Root) GetGrpcWarmAll() bool {
+	return r.Grpc.WarmAll
+}
+
+// GetGrpcServiceFilters returns the filters applied to the services discovered when grpc-warm-all is set.
+func (r *Root) GetGrpcServiceFilters() []string {
+	return r.Grpc.ServiceFilters
+}
+
 // GetWarmupGrpcRequests returns gRPC requests.
 func (r *Root) GetWarmupGrpcRequests() ([]grpc.Request, error) {
 	requests, err := r.Grpc.getWarmupGrpcRequests()
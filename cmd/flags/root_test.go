@@ -0,0 +1,49 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package flags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoot_NegativeConcurrency(t *testing.T) {
+	root := Root{Concurrency: -1}
+
+	_, err := root.GetConcurrency()
+	require.Error(t, err)
+	assert.Equal(t, "concurrency must not be negative, 0 uses the number of CPUs", err.Error())
+}
+
+func TestRoot_NegativeConcurrencyTargetSeconds(t *testing.T) {
+	root := Root{ConcurrencyTargetSeconds: -1}
+
+	_, err := root.GetConcurrencyTargetSeconds()
+	require.Error(t, err)
+	assert.Equal(t, "concurrency-target-seconds must not be negative", err.Error())
+}
+
+func TestRoot_ZeroConcurrency(t *testing.T) {
+	root := Root{}
+
+	concurrency, err := root.GetConcurrency()
+	require.NoError(t, err)
+	assert.Equal(t, 0, concurrency)
+	seconds, err := root.GetConcurrencyTargetSeconds()
+	require.NoError(t, err)
+	assert.Equal(t, 0, seconds)
+}
@@ -15,11 +15,25 @@
 package flags
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
+	"log"
+	"mittens/internal/pkg/certs"
+	"mittens/internal/pkg/dns"
 	"mittens/internal/pkg/grpc"
 	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/tunnel"
 	"mittens/internal/pkg/warmup"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fullstorydev/grpcurl"
 )
 
 // Target stores flags related to the target.
@@ -28,11 +42,49 @@ type Target struct {
 	HTTPPort            int
 	GrpcHost            string
 	GrpcPort            int
+	GrpcAdditionalHosts stringArray
 	ReadinessProtocol   string
 	ReadinessHTTPPath   string
 	ReadinessGrpcMethod string
 	ReadinessPort       int
 	Insecure            bool
+
+	HTTPRetryConnectionReuseFailures bool
+	ClientCertFile                   string
+	ClientKeyFile                    string
+	CAFile                           string
+	DNSPrime                         bool
+	DNSCache                         bool
+	IdleConnectionTimeoutSeconds     int
+	ConnectProxy                     string
+	GrpcProxy                        string
+	HTTPHedgePercentile              float64
+	HTTPDialTimeout                  time.Duration
+	GrpcDialTimeout                  time.Duration
+	HTTPMaxConnsPerHost              int
+	HTTPMaxIdleConns                 int
+	HTTPMaxIdleConnsPerHost          int
+	HTTPExpectContinue               bool
+	HTTP2                            bool
+	HTTPFollowRedirects              bool
+	HTTPExpectContinueTimeout        time.Duration
+	HTTPReadTimeout                  time.Duration
+	HTTPWriteTimeout                 time.Duration
+	HostOverrides                    stringArray
+	ConnectionPoolSize               int
+	PoolHealthCheckInterval          time.Duration
+	GrpcRequestFormat                string
+	GrpcProtoFiles                   stringArray
+	GrpcImportPaths                  stringArray
+	GrpcProtosetFiles                stringArray
+
+	clientCertificate  *certs.Reloader
+	rootCAs            *x509.CertPool
+	descriptorSource   grpcurl.DescriptorSource
+	dnsCache           *dns.Cache
+	connectProxyDialer *tunnel.Dialer
+	grpcProxyDialer    *tunnel.Dialer
+	hostOverrides      dns.Overrides
 }
 
 func (t *Target) String() string {
@@ -40,15 +92,44 @@ func (t *Target) String() string {
 }
 
 func (t *Target) initFlags() {
-	flag.StringVar(&t.HTTPHost, "target-http-host", "http://localhost", "HTTP host to warm up")
+	flag.StringVar(&t.HTTPHost, "target-http-host", "http://localhost", "HTTP host to warm up, or the path of a UNIX domain socket in the unix:///path/to/socket form")
 	flag.IntVar(&t.HTTPPort, "target-http-port", 8080, "HTTP port for warm up requests")
 	flag.StringVar(&t.GrpcHost, "target-grpc-host", "localhost", "Grpc host to warm up")
 	flag.IntVar(&t.GrpcPort, "target-grpc-port", 50051, "Grpc port for warm up requests")
+	flag.Var(&t.GrpcAdditionalHosts, "target-grpc-additional-host", "Other gRPC host, in host[:port] format, warmed up along with target-grpc-host. The gRPC requests are spread across all the hosts in turn and a host that cannot be connected to does not prevent warming up the others. The port defaults to target-grpc-port. Can be repeated")
 	flag.StringVar(&t.ReadinessProtocol, "target-readiness-protocol", "http", "Protocol to be used for readiness check. One of [http, grpc]")
 	flag.StringVar(&t.ReadinessHTTPPath, "target-readiness-http-path", "/ready", "The path used for HTTP target readiness probe")
 	flag.StringVar(&t.ReadinessGrpcMethod, "target-readiness-grpc-method", "grpc.health.v1.Health/Check", "The service method used for gRPC target readiness probe")
 	flag.IntVar(&t.ReadinessPort, "target-readiness-port", toIntOrDefaultIfNull(&t.HTTPPort, 8080), "The port used for target readiness probe")
 	flag.BoolVar(&t.Insecure, "target-insecure", false, "Whether to skip TLS validation")
+	flag.StringVar(&t.ClientCertFile, "target-client-cert-file", "", "Path to the client certificate presented to targets that require mutual TLS. The file is reloaded when it changes")
+	flag.StringVar(&t.ClientKeyFile, "target-client-key-file", "", "Path to the key of the client certificate. The file is reloaded when it changes")
+	flag.StringVar(&t.CAFile, "target-ca-file", "", "Path to a PEM bundle of the CA certificates used to verify the target's certificate instead of the system ones")
+	flag.BoolVar(&t.DNSPrime, "dns-prime", false, "If set to true the target hosts are resolved before sending the warmup requests so that these don't pay the DNS resolution cost")
+	flag.BoolVar(&t.DNSCache, "dns-cache", false, "If set to true the target hosts are resolved only once and their addresses are cached for the rest of the run")
+	flag.StringVar(&t.ConnectProxy, "target-connect-proxy", "", "Forward proxy, in [http://][user:password@]host:port format, through which connections to the target are tunneled using HTTP CONNECT")
+	flag.StringVar(&t.GrpcRequestFormat, "grpc-request-format", grpc.FormatJSON, "Format of the gRPC request messages. One of [json, text]")
+	flag.Var(&t.GrpcProtoFiles, "grpc-proto-file", "Path to a .proto file describing the gRPC services of the target, for targets without server reflection. Can be repeated")
+	flag.Var(&t.GrpcImportPaths, "grpc-import-path", "Directory in which the imports of the grpc-proto-file files are searched. Can be repeated. Defaults to the directories of the files")
+	flag.Var(&t.GrpcProtosetFiles, "grpc-protoset-file", "Path to a compiled file descriptor set (protoset), e.g. built with protoc --descriptor_set_out --include_imports, describing the gRPC services of the target, for targets without server reflection. Can be repeated")
+	flag.StringVar(&t.GrpcProxy, "grpc-proxy", "", "Forward proxy, in [http://][user:password@]host:port format, through which the gRPC connections only are tunneled using HTTP CONNECT. It overrides target-connect-proxy for gRPC")
+	flag.Var(&t.HostOverrides, "host-override", "Host whose connections are opened to another IP address, in host=ip format, e.g. api.example.com=10.0.0.5. TLS and the Host header keep using the host name. Can be repeated")
+	flag.IntVar(&t.IdleConnectionTimeoutSeconds, "target-idle-connection-timeout-seconds", 0, "Time after which idle HTTP connections to the target are closed. 0 keeps them open indefinitely")
+	flag.Float64Var(&t.HTTPHedgePercentile, "http-hedge-percentile", 0, "If greater than 0 a second copy of GET, HEAD and OPTIONS requests is sent if the first one has not responded within this percentile of the recent latencies, e.g. 95. The fastest response is used")
+	flag.DurationVar(&t.HTTPReadTimeout, "read-timeout", 0, "Timeout of the HTTP requests with a safe method (GET, HEAD, OPTIONS and TRACE), e.g. 2s. 0 keeps the 10s default")
+	flag.DurationVar(&t.HTTPWriteTimeout, "write-timeout", 0, "Timeout of the HTTP requests with any other method, e.g. POST or PUT, e.g. 30s. 0 keeps the 10s default")
+	flag.DurationVar(&t.HTTPDialTimeout, "http-dial-timeout", 0, "Maximum time spent opening a connection to the HTTP target, e.g. 2s. 0 means the connection is only bounded by the request timeout")
+	flag.DurationVar(&t.GrpcDialTimeout, "grpc-dial-timeout", 0, "Maximum time spent connecting to the gRPC target, including the server reflection requests, e.g. 10s for targets slow to accept connections during their cold start. 0 keeps the 1s default")
+	flag.IntVar(&t.HTTPMaxConnsPerHost, "http-max-connections-per-host", 0, "If greater than 0 at most this number of connections are open to each HTTP host at the same time, whatever the concurrency. Requests wait for a free connection once the limit is reached. 0 means no limit")
+	flag.IntVar(&t.HTTPMaxIdleConns, "http-max-idle-connections", 0, "If greater than 0 at most this number of idle HTTP connections are kept open to all the hosts together. 0 means no limit")
+	flag.IntVar(&t.HTTPMaxIdleConnsPerHost, "http-max-idle-connections-per-host", 0, "If greater than 0 up to this number of idle HTTP connections are kept open to each host for the next requests, instead of 2, so that a high concurrency does not churn connections. 0 keeps connection-pool-size, if set, or the default of 2")
+	flag.BoolVar(&t.HTTP2, "http2", false, "If set to true the HTTP warmup requests negotiate HTTP/2 with TLS targets, falling back to HTTP/1.1 if the target does not support it")
+	flag.BoolVar(&t.HTTPFollowRedirects, "http-follow-redirects", true, "If set to false the HTTP warmup requests do not follow redirects, so that the redirect response itself is recorded, e.g. as a failure with status code 301, instead of warming the redirect target")
+	flag.BoolVar(&t.HTTPExpectContinue, "http-expect-continue", false, "If set to true HTTP warmup requests with a body are sent with 'Expect: 100-continue' so that the server's continue handling is warmed up too")
+	flag.DurationVar(&t.HTTPExpectContinueTimeout, "http-expect-continue-timeout", time.Second, "Time to wait for the server's 100 Continue before sending the body anyway when http-expect-continue is set, e.g. 500ms. 0 sends the body without waiting")
+	flag.IntVar(&t.ConnectionPoolSize, "connection-pool-size", 0, "If greater than 0 the gRPC warmup client opens this number of connections up front and the workers use them in turn, and up to this number of idle HTTP connections are kept open to the target between requests and warmup cycles. 0 keeps a single gRPC connection and the default of 2 idle HTTP connections")
+	flag.DurationVar(&t.PoolHealthCheckInterval, "connection-pool-health-check-interval", 10*time.Second, "Interval at which the failed gRPC connections of the pool set with connection-pool-size are replaced, e.g. 5s. 0 disables the health checks")
+	flag.BoolVar(&t.HTTPRetryConnectionReuseFailures, "http-retry-connection-reuse-failures", false, "If set to true HTTP requests that fail because the server closed a reused keep-alive connection are retried once on another connection")
 }
 
 func toIntOrDefaultIfNull(value *int, defaultValue int) int {
@@ -68,18 +149,331 @@ func (t *Target) getWarmupTargetOptions() warmup.TargetOptions {
 	}
 }
 
-func (t *Target) getReadinessHTTPClient() http.Client {
-	return http.NewClient(fmt.Sprintf("%s:%d", t.HTTPHost, t.ReadinessPort), t.Insecure)
+// validateClientCertificate checks that the client certificate and key are either both set or both unset and that they can be loaded.
+func (t *Target) validateClientCertificate() error {
+	if (t.ClientCertFile == "") != (t.ClientKeyFile == "") {
+		return fmt.Errorf("target-client-cert-file and target-client-key-file must be set together")
+	}
+	if t.ClientCertFile == "" {
+		return nil
+	}
+	reloader, err := certs.NewReloader(t.ClientCertFile, t.ClientKeyFile)
+	if err != nil {
+		return err
+	}
+	t.clientCertificate = reloader
+	return nil
+}
+
+// getClientCertificate returns the callback used by the clients to present the client certificate, or nil if none is configured.
+func (t *Target) getClientCertificate() func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if t.ClientCertFile == "" {
+		return nil
+	}
+	if t.clientCertificate == nil {
+		if err := t.validateClientCertificate(); err != nil {
+			log.Printf("Client certificate will not be used: %v", err)
+			return nil
+		}
+	}
+	return t.clientCertificate.GetClientCertificate
+}
+
+// validateCAFile checks that the CA bundle, if set, can be loaded.
+func (t *Target) validateCAFile() error {
+	if t.CAFile == "" {
+		return nil
+	}
+	pool, err := certs.LoadCertPool(t.CAFile)
+	if err != nil {
+		return err
+	}
+	t.rootCAs = pool
+	return nil
+}
+
+// getRootCAs returns the CA certificates used to verify the target, or nil to use the system ones.
+func (t *Target) getRootCAs() *x509.CertPool {
+	if t.CAFile == "" {
+		return nil
+	}
+	if t.rootCAs == nil {
+		if err := t.validateCAFile(); err != nil {
+			log.Printf("CA file will not be used: %v", err)
+			return nil
+		}
+	}
+	return t.rootCAs
+}
+
+// validateDescriptorSource checks that the proto and protoset files, if set, can be loaded.
+func (t *Target) validateDescriptorSource() error {
+	source, err := grpc.LoadDescriptorSource(t.GrpcImportPaths, t.GrpcProtoFiles, t.GrpcProtosetFiles)
+	if err != nil {
+		return err
+	}
+	t.descriptorSource = source
+	return nil
+}
+
+// getDescriptorSource returns the descriptors of the gRPC services loaded from files, or nil to use server reflection.
+func (t *Target) getDescriptorSource() grpcurl.DescriptorSource {
+	if len(t.GrpcProtoFiles) == 0 && len(t.GrpcProtosetFiles) == 0 {
+		return nil
+	}
+	if t.descriptorSource == nil {
+		if err := t.validateDescriptorSource(); err != nil {
+			log.Printf("gRPC descriptors will be fetched via server reflection: %v", err)
+			return nil
+		}
+	}
+	return t.descriptorSource
+}
+
+// getDNSCache returns the DNS cache shared by all the clients, or nil if the cache is disabled.
+func (t *Target) getDNSCache() *dns.Cache {
+	if !t.DNSCache {
+		return nil
+	}
+	if t.dnsCache == nil {
+		t.dnsCache = dns.NewCache()
+	}
+	return t.dnsCache
+}
+
+// validateConnectProxy checks that the CONNECT proxies, if set, are valid.
+func (t *Target) validateConnectProxy() error {
+	if t.ConnectProxy != "" {
+		dialer, err := t.newProxyDialer(t.ConnectProxy)
+		if err != nil {
+			return err
+		}
+		t.connectProxyDialer = dialer
+	}
+	if t.GrpcProxy != "" {
+		dialer, err := t.newProxyDialer(t.GrpcProxy)
+		if err != nil {
+			return fmt.Errorf("grpc-proxy: %v", err)
+		}
+		t.grpcProxyDialer = dialer
+	}
+	return nil
+}
+
+// newProxyDialer returns a dialer that tunnels the connections through a CONNECT proxy.
+func (t *Target) newProxyDialer(proxy string) (*tunnel.Dialer, error) {
+	var dial func(ctx context.Context, network string, address string) (net.Conn, error)
+	if cache := t.getDNSCache(); cache != nil {
+		dial = cache.DialContext
+	}
+	return tunnel.NewDialer(proxy, dial)
+}
+
+// validateHostOverrides checks that the host overrides, if any, are in host=ip format.
+func (t *Target) validateHostOverrides() error {
+	overrides, err := dns.ParseOverrides(t.HostOverrides)
+	if err != nil {
+		return err
+	}
+	t.hostOverrides = overrides
+	return nil
+}
+
+// withHostOverrides returns a dial function that connects to the IP address of the overridden hosts, or dial itself if there are none.
+func (t *Target) withHostOverrides(dial func(ctx context.Context, network string, address string) (net.Conn, error)) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	if len(t.HostOverrides) == 0 {
+		return dial
+	}
+	if t.hostOverrides == nil {
+		if err := t.validateHostOverrides(); err != nil {
+			log.Printf("Host overrides will not be used: %v", err)
+			return dial
+		}
+	}
+	return t.hostOverrides.Wrap(dial)
+}
+
+// getDialContext returns the function used by the clients to connect to the target, or nil to use the default one.
+func (t *Target) getDialContext() func(ctx context.Context, network string, address string) (net.Conn, error) {
+	return t.withHostOverrides(t.getProxyOrCacheDialContext())
+}
+
+// getProxyOrCacheDialContext returns the function that connects through the CONNECT proxy or using the DNS cache, if enabled.
+func (t *Target) getProxyOrCacheDialContext() func(ctx context.Context, network string, address string) (net.Conn, error) {
+	if t.ConnectProxy != "" {
+		if t.connectProxyDialer == nil {
+			if err := t.validateConnectProxy(); err != nil {
+				log.Printf("CONNECT proxy will not be used: %v", err)
+			}
+		}
+		if t.connectProxyDialer != nil {
+			return t.connectProxyDialer.DialContext
+		}
+	}
+	if cache := t.getDNSCache(); cache != nil {
+		return cache.DialContext
+	}
+	return nil
+}
+
+// getGrpcDialContext returns the function used by the gRPC clients to connect to the target, or nil to use the default one.
+func (t *Target) getGrpcDialContext() func(ctx context.Context, network string, address string) (net.Conn, error) {
+	if t.GrpcProxy != "" {
+		if t.grpcProxyDialer == nil {
+			if err := t.validateConnectProxy(); err != nil {
+				log.Printf("gRPC proxy will not be used: %v", err)
+			}
+		}
+		if t.grpcProxyDialer != nil {
+			return t.withHostOverrides(t.grpcProxyDialer.DialContext)
+		}
+	}
+	return t.getDialContext()
+}
+
+// primeDNS resolves the HTTP and gRPC target hosts. If the DNS cache is enabled the addresses are cached.
+func (t *Target) primeDNS() {
+	var hosts []string
+	if u, err := url.Parse(t.HTTPHost); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+	if t.GrpcHost != "" && (len(hosts) == 0 || hosts[0] != t.GrpcHost) {
+		hosts = append(hosts, t.GrpcHost)
+	}
+	for _, address := range t.getAdditionalGrpcAddresses() {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(t.HostOverrides) > 0 {
+		// overridden hosts are never resolved
+		var resolved []string
+		for _, host := range hosts {
+			if _, ok := t.hostOverrides[strings.ToLower(host)]; !ok {
+				resolved = append(resolved, host)
+			}
+		}
+		hosts = resolved
+	}
+
+	log.Printf("Priming DNS for %v", hosts)
+	if cache := t.getDNSCache(); cache != nil {
+		cache.Prime(context.Background(), hosts)
+	} else {
+		dns.Resolve(context.Background(), hosts)
+	}
+}
+
+func (t *Target) getHTTPClientOptions() http.ClientOptions {
+	return http.ClientOptions{
+		RetryConnectionReuseFailures: t.HTTPRetryConnectionReuseFailures,
+		GetClientCertificate:         t.getClientCertificate(),
+		RootCAs:                      t.getRootCAs(),
+		DialContext:                  t.getDialContext(),
+		IdleConnTimeout:              time.Duration(t.IdleConnectionTimeoutSeconds) * time.Second,
+		DialTimeout:                  t.HTTPDialTimeout,
+		ReadTimeout:                  t.HTTPReadTimeout,
+		WriteTimeout:                 t.HTTPWriteTimeout,
+	}
+}
+
+// getWarmupHTTPClientOptions returns the options of the HTTP client used for the warmup requests.
+// Unlike the readiness client it hedges slow requests, limits the connections per host, keeps the connection pool open,
+// expects 100 Continue and does not follow redirects if configured so.
+func (t *Target) getWarmupHTTPClientOptions() http.ClientOptions {
+	options := t.getHTTPClientOptions()
+	options.HedgePercentile = t.HTTPHedgePercentile
+	options.MaxConnsPerHost = t.HTTPMaxConnsPerHost
+	options.ExpectContinue = t.HTTPExpectContinue
+	options.ForceHTTP2 = t.HTTP2
+	options.DisableRedirects = !t.HTTPFollowRedirects
+	options.ExpectContinueTimeout = t.HTTPExpectContinueTimeout
+	options.MaxIdleConns = t.HTTPMaxIdleConns
+	options.MaxIdleConnsPerHost = t.HTTPMaxIdleConnsPerHost
+	if options.MaxIdleConnsPerHost == 0 {
+		options.MaxIdleConnsPerHost = t.ConnectionPoolSize
+	}
+	return options
+}
+
+func (t *Target) getGrpcClientOptions() grpc.ClientOptions {
+	return grpc.ClientOptions{
+		GetClientCertificate: t.getClientCertificate(),
+		RootCAs:              t.getRootCAs(),
+		DialContext:          t.getGrpcDialContext(),
+		DialTimeout:          t.GrpcDialTimeout,
+		DescriptorSource:     t.getDescriptorSource(),
+	}
 }
 
-func (t *Target) getReadinessGrpcClient() grpc.Client {
-	return grpc.NewClient(fmt.Sprintf("%s:%d", t.GrpcHost, t.ReadinessPort), t.Insecure)
+func (t *Target) getReadinessHTTPClient() (http.Client, error) {
+	return http.NewClient(t.getHTTPAddress(t.ReadinessPort), t.Insecure, t.getHTTPClientOptions())
 }
 
-func (t *Target) getHTTPClient() http.Client {
-	return http.NewClient(fmt.Sprintf("%s:%d", t.HTTPHost, t.HTTPPort), t.Insecure)
+func (t *Target) getReadinessGrpcClient() (grpc.Client, error) {
+	return grpc.NewClient(fmt.Sprintf("%s:%d", t.GrpcHost, t.ReadinessPort), t.Insecure, t.getGrpcClientOptions())
+}
+
+// clientHeaders are the headers the warmup clients add to every request.
+type clientHeaders struct {
+	// authorization, if set, returns the Authorization header, or the authorization metadata, of every request.
+	authorization func() (string, error)
+	// synthetic, if set, is the header in the `name: value` format that marks every request as synthetic.
+	synthetic string
+}
+
+// getHTTPClient returns the HTTP warmup client.
+func (t *Target) getHTTPClient(headers clientHeaders) (http.Client, error) {
+	options := t.getWarmupHTTPClientOptions()
+	options.Authorization = headers.authorization
+	options.SyntheticHeader = headers.synthetic
+	return http.NewClient(t.getHTTPAddress(t.HTTPPort), t.Insecure, options)
+}
+
+// getHTTPAddress returns the HTTP host with the port, or as it is if it is a UNIX domain socket.
+func (t *Target) getHTTPAddress(port int) string {
+	if http.IsUnixSocket(t.HTTPHost) {
+		return t.HTTPHost
+	}
+	return fmt.Sprintf("%s:%d", t.HTTPHost, port)
+}
+
+// getGrpcClient returns the gRPC warmup client.
+func (t *Target) getGrpcClient(headers clientHeaders) (grpc.Client, error) {
+	return t.newWarmupGrpcClient(fmt.Sprintf("%s:%d", t.GrpcHost, t.GrpcPort), headers)
+}
+
+// getAdditionalGrpcClients returns a gRPC warmup client for every additional gRPC host.
+func (t *Target) getAdditionalGrpcClients(headers clientHeaders) ([]grpc.Client, error) {
+	var clients []grpc.Client
+	for _, address := range t.getAdditionalGrpcAddresses() {
+		client, err := t.newWarmupGrpcClient(address, headers)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// getAdditionalGrpcAddresses returns the additional gRPC hosts in host:port format, with target-grpc-port if they have no port.
+func (t *Target) getAdditionalGrpcAddresses() []string {
+	var addresses []string
+	for _, host := range t.GrpcAdditionalHosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, strconv.Itoa(t.GrpcPort))
+		}
+		addresses = append(addresses, host)
+	}
+	return addresses
 }
 
-func (t *Target) getGrpcClient() grpc.Client {
-	return grpc.NewClient(fmt.Sprintf("%s:%d", t.GrpcHost, t.GrpcPort), t.Insecure)
+func (t *Target) newWarmupGrpcClient(address string, headers clientHeaders) (grpc.Client, error) {
+	options := t.getGrpcClientOptions()
+	options.Authorization = headers.authorization
+	options.SyntheticHeader = headers.synthetic
+	options.PoolSize = t.ConnectionPoolSize
+	options.PoolHealthCheckInterval = t.PoolHealthCheckInterval
+	options.Format = t.GrpcRequestFormat
+	return grpc.NewClient(address, t.Insecure, options)
 }
@@ -0,0 +1,104 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package flags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarget_ClientCertificateWithoutKey(t *testing.T) {
+	target := Target{ClientCertFile: "tls.crt"}
+
+	err := target.validateClientCertificate()
+	require.Error(t, err)
+	require.Equal(t, "target-client-cert-file and target-client-key-file must be set together", err.Error())
+}
+
+func TestTarget_WithoutClientCertificate(t *testing.T) {
+	target := Target{}
+
+	require.NoError(t, target.validateClientCertificate())
+	require.Nil(t, target.getClientCertificate())
+}
+
+func TestTarget_InvalidConnectProxy(t *testing.T) {
+	target := Target{ConnectProxy: "socks5://localhost:1080"}
+
+	require.Error(t, target.validateConnectProxy())
+}
+
+func TestTarget_ConnectProxy(t *testing.T) {
+	target := Target{ConnectProxy: "proxy.local:3128"}
+
+	require.NoError(t, target.validateConnectProxy())
+	require.NotNil(t, target.getDialContext())
+}
+
+func TestTarget_InvalidGrpcProxy(t *testing.T) {
+	target := Target{GrpcProxy: "socks5://localhost:1080"}
+
+	require.ErrorContains(t, target.validateConnectProxy(), "grpc-proxy")
+}
+
+func TestTarget_GrpcProxy(t *testing.T) {
+	target := Target{GrpcProxy: "proxy.local:3128"}
+
+	require.NoError(t, target.validateConnectProxy())
+	require.Nil(t, target.getDialContext())
+	require.NotNil(t, target.getGrpcDialContext())
+}
+
+func TestTarget_InvalidHostOverride(t *testing.T) {
+	target := Target{HostOverrides: []string{"api.example.com"}}
+
+	require.Error(t, target.validateHostOverrides())
+	require.Nil(t, target.getDialContext())
+}
+
+func TestTarget_HostOverrides(t *testing.T) {
+	target := Target{HostOverrides: []string{"api.example.com=10.0.0.5"}, GrpcProxy: "proxy.local:3128"}
+
+	require.NoError(t, target.validateHostOverrides())
+	require.NoError(t, target.validateConnectProxy())
+	require.NotNil(t, target.getDialContext())
+	require.NotNil(t, target.getGrpcDialContext())
+}
+
+func TestTarget_AdditionalGrpcAddresses(t *testing.T) {
+	target := Target{GrpcPort: 50051, GrpcAdditionalHosts: []string{"backend-2", "backend-3:6565", "::1"}}
+
+	require.Equal(t, []string{"backend-2:50051", "backend-3:6565", "[::1]:50051"}, target.getAdditionalGrpcAddresses())
+	clients, err := target.getAdditionalGrpcClients(clientHeaders{})
+	require.NoError(t, err)
+	require.Len(t, clients, 3)
+}
+
+func TestTarget_UnixSocketHTTPHost(t *testing.T) {
+	target := Target{HTTPHost: "unix:///var/run/app.sock", HTTPPort: 8080}
+
+	require.Equal(t, "unix:///var/run/app.sock", target.getHTTPAddress(target.HTTPPort))
+	_, err := target.getHTTPClient(clientHeaders{})
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8080", (&Target{HTTPHost: "http://localhost"}).getHTTPAddress(8080))
+}
+
+func TestTarget_InvalidHTTPHost(t *testing.T) {
+	target := Target{HTTPHost: "localhost", HTTPPort: 8080}
+
+	_, err := target.getHTTPClient(clientHeaders{})
+	require.EqualError(t, err, "invalid HTTP host localhost:8080, expected a URL such as http://localhost:8080")
+}
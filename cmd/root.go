@@ -15,18 +15,38 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"math/rand"
 	"mittens/cmd/flags"
+	"mittens/internal/pkg/config"
+	"mittens/internal/pkg/control"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/marker"
+	"mittens/internal/pkg/metrics"
 	"mittens/internal/pkg/probe"
+	"mittens/internal/pkg/recording"
+	"mittens/internal/pkg/report"
 	"mittens/internal/pkg/safe"
+	"mittens/internal/pkg/selftest"
+	"mittens/internal/pkg/stopcondition"
 	"mittens/internal/pkg/warmup"
 	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 var opts *flags.Root
 
+// loggedSlowSamples is the number of slowest requests logged at the end of the warmup.
+const loggedSlowSamples = 10
+
 // CreateConfig creates a flag set and parses the command line arguments.
 func CreateConfig() {
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
@@ -35,97 +55,406 @@ func CreateConfig() {
 	flag.Parse()
 }
 
-// RunCmdRoot runs the main logic
+// RunCmdRoot runs the main logic and returns the exit code of the process, see exitcodes.go.
 //
-//	It blocks forever unless `-exit-after-warmup` is set to true
-func RunCmdRoot() {
-	requestsSent := safe.DoAndReturn(run, 0)
-	postProcess(requestsSent)
+//	It blocks forever unless `-exit-after-warmup` is set to true.
+//	With `-self-test` it only runs the self-test and returns 1 if it fails.
+func RunCmdRoot() int {
+	if opts.SelfTest {
+		if !selftest.Run() {
+			return exitFailure
+		}
+		return exitOK
+	}
+	result := safe.DoAndReturn(run, warmupResult{})
+	exitCode := postProcess(result)
+	if result.dryRun {
+		return exitCode
+	}
+	rewarm(result)
 	block()
+	if result.warmup != nil {
+		result.warmup.Recorder.Close()
+	}
+	return exitCode
+}
+
+// warmupResult holds the outcome of a warmup run.
+type warmupResult struct {
+	// number of warmup requests actually sent
+	requestsSent int64
+	// outcome of the requests per endpoint; nil if the warmup did not run
+	summary *warmup.Summary
+	// warmup that ran, used to run it again in later cycles; nil if the warmup did not run
+	warmup          *warmup.Warmup
+	hasHttpRequests bool
+	hasGrpcRequests bool
+	// condition that stops every warmup cycle early; nil if not set
+	stopCondition             stopcondition.Condition
+	stopConditionPollInterval time.Duration
+	// request sent between warmup cycles to keep the gRPC connection warm; nil if not set
+	grpcPing         *grpc.Request
+	grpcPingInterval time.Duration
+	// true if the options were invalid, in which case the warmup did not run
+	invalidOptions bool
+	// true if the target never became ready, in which case the warmup did not run
+	targetNotReady bool
+	// true if the requests were only validated, in which case the summary holds the invalid requests as pre-flight errors
+	dryRun bool
+	// why no warmup request could be sent at all, if so
+	warmupErr error
 }
 
-// run runs the main logic and returns the number of warmup requests actually sent.
-func run() int {
+// run runs the main logic and returns the number of warmup requests actually sent along with the summary of the warmup.
+func run() warmupResult {
+	var validationError bool
+	if err := marker.SetMode(opts.Markers); err != nil {
+		log.Printf("invalid markers: %v", err)
+		validationError = true
+	}
+
 	if opts.FileProbe.Enabled {
 		probe.WriteFile("alive")
 	}
 
-	var validationError bool
 	httpRequests, err := opts.GetWarmupHTTPRequests()
 	if err != nil {
 		log.Printf("invalid HTTP options: %v", err)
 		validationError = true
 	}
+	httpHeaders, err := opts.GetWarmupHTTPHeaders()
+	if err != nil {
+		log.Printf("invalid HTTP options: %v", err)
+		validationError = true
+	}
 	grpcRequests, err := opts.GetWarmupGrpcRequests()
 	if err != nil {
 		log.Printf("invalid grpc options: %v", err)
 		validationError = true
 	}
+	grpcMetadata, err := opts.GetGrpcMetadata()
+	if err != nil {
+		log.Printf("invalid grpc options: %v", err)
+		validationError = true
+	}
+	if opts.ConfigURL != "" {
+		configHTTPRequests, configGrpcRequests, err := config.Requests(opts.ConfigURL, opts.GetConfigURLHeaders(), opts.ConfigFallbackFile)
+		if err != nil {
+			log.Printf("invalid config options: %v", err)
+			validationError = true
+		}
+		log.Printf("Loaded %d HTTP and %d gRPC request(s) from the config", len(configHTTPRequests), len(configGrpcRequests))
+		httpRequests = append(httpRequests, configHTTPRequests...)
+		grpcRequests = append(grpcRequests, configGrpcRequests...)
+	}
+	if opts.Replay != "" {
+		replayedHTTPRequests, replayedGrpcRequests, err := recording.Requests(opts.Replay)
+		if err != nil {
+			log.Printf("invalid replay options: %v", err)
+			validationError = true
+		}
+		log.Printf("Replaying %d HTTP and %d gRPC request(s) from %s", len(replayedHTTPRequests), len(replayedGrpcRequests), opts.Replay)
+		httpRequests = append(httpRequests, replayedHTTPRequests...)
+		grpcRequests = append(grpcRequests, replayedGrpcRequests...)
+	}
+	if sampleRate, rnd, err := opts.GetSampling(); err != nil {
+		log.Printf("invalid sampling options: %v", err)
+		validationError = true
+	} else if sampleRate < 1 {
+		httpRequests = sampleHTTPRequests(httpRequests, sampleRate, rnd)
+		grpcRequests = sampleGrpcRequests(grpcRequests, sampleRate, rnd)
+	}
+	var recorder *recording.Recorder
+	if opts.Record != "" {
+		if recorder, err = recording.NewRecorder(opts.Record); err != nil {
+			log.Printf("invalid record options: %v", err)
+			validationError = true
+		}
+	}
+	resultLogger, err := opts.GetResultLogger()
+	if err != nil {
+		log.Printf("invalid log options: %v", err)
+		validationError = true
+	}
 	targetOptions, err := opts.GetWarmupTargetOptions()
 	if err != nil {
 		log.Printf("invalid target options: %v", err)
 		validationError = true
 	}
+	target, err := createTarget(targetOptions)
+	if err != nil {
+		log.Printf("invalid target options: %v", err)
+		validationError = true
+	}
+	requestOrder, err := opts.GetRequestOrder()
+	if err != nil {
+		log.Printf("invalid request order: %v", err)
+		validationError = true
+	}
+	rate, perWorkerRate, err := opts.GetRateLimits()
+	if err != nil {
+		log.Printf("invalid rate limits: %v", err)
+		validationError = true
+	}
+	concurrency, err := opts.GetConcurrency()
+	if err != nil {
+		log.Printf("invalid concurrency options: %v", err)
+		validationError = true
+	}
+	concurrencyTargetSeconds, err := opts.GetConcurrencyTargetSeconds()
+	if err != nil {
+		log.Printf("invalid concurrency options: %v", err)
+		validationError = true
+	}
+	targetRequestsPerSecond, minConcurrency, maxConcurrency, err := opts.GetTargetRequestsPerSecond()
+	if err != nil {
+		log.Printf("invalid concurrency options: %v", err)
+		validationError = true
+	}
+	minSuccess, err := opts.GetMinSuccess()
+	if err != nil {
+		log.Printf("invalid readiness options: %v", err)
+		validationError = true
+	}
+	maxRequests, err := opts.GetMaxRequests()
+	if err != nil {
+		log.Printf("invalid duration options: %v", err)
+		validationError = true
+	}
+	abortP99Latency, abortP99WindowSeconds, abortP99SustainSeconds, err := opts.GetAbortP99()
+	if err != nil {
+		log.Printf("invalid latency options: %v", err)
+		validationError = true
+	}
+	abortErrorRate, abortErrorWindowSeconds, abortErrorMinRequests, err := opts.GetAbortErrorRate()
+	if err != nil {
+		log.Printf("invalid error rate options: %v", err)
+		validationError = true
+	}
+	delayJitterMilliseconds, err := opts.GetDelayJitterMilliseconds()
+	if err != nil {
+		log.Printf("invalid request options: %v", err)
+		validationError = true
+	}
+	slowRequestThresholdMilliseconds, err := opts.GetSlowRequestThresholdMilliseconds()
+	if err != nil {
+		log.Printf("invalid request options: %v", err)
+		validationError = true
+	}
+	maxResponseBodyBytes, err := opts.GetMaxResponseBodyBytes()
+	if err != nil {
+		log.Printf("invalid request options: %v", err)
+		validationError = true
+	}
+	retries, retryBackoff, err := opts.GetRetries()
+	if err != nil {
+		log.Printf("invalid retry options: %v", err)
+		validationError = true
+	}
+	if _, err := opts.GetMaxLatencyViolationPercent(); err != nil {
+		log.Printf("invalid latency options: %v", err)
+		validationError = true
+	}
+	stopCondition, stopConditionPollInterval, err := opts.GetStopCondition()
+	if err != nil {
+		log.Printf("invalid stop condition: %v", err)
+		validationError = true
+	}
+	rampDownSeconds, err := opts.GetRampDownSeconds()
+	if err != nil {
+		log.Printf("invalid concurrency options: %v", err)
+		validationError = true
+	}
+	rampUpCurve, rampUpShape, err := opts.GetRampUp()
+	if err != nil {
+		log.Printf("invalid concurrency options: %v", err)
+		validationError = true
+	}
+	totalSeconds, err := opts.GetTotalDurationSeconds()
+	if err != nil {
+		log.Printf("invalid total duration: %v", err)
+		validationError = true
+	}
+	grpcPing, grpcPingInterval, err := opts.GetRewarmGrpcPing()
+	if err != nil {
+		log.Printf("invalid rewarm options: %v", err)
+		validationError = true
+	}
+	protocolMix, err := opts.GetProtocolMix()
+	if err != nil {
+		log.Printf("invalid protocol mix: %v", err)
+		validationError = true
+	}
+	var concurrencyControl *warmup.ConcurrencyControl
+	if controlAddress, err := opts.GetControlAddress(); err != nil {
+		log.Printf("invalid control options: %v", err)
+		validationError = true
+	} else if controlAddress != "" && !validationError {
+		concurrencyControl = warmup.NewConcurrencyControl(concurrency)
+		if err := control.Start(controlAddress, concurrencyControl); err != nil {
+			log.Printf("cannot start the control endpoint: %v", err)
+			validationError = true
+		}
+	}
+	var warmupMetrics *metrics.Metrics
+	if metricsAddress, err := opts.GetMetricsAddress(); err != nil {
+		log.Printf("invalid metrics options: %v", err)
+		validationError = true
+	} else if metricsAddress != "" && !validationError {
+		warmupMetrics = metrics.New()
+		if err := metrics.Start(metricsAddress, warmupMetrics); err != nil {
+			log.Printf("cannot start the metrics endpoint: %v", err)
+			validationError = true
+		}
+	}
 
 	// this is used to decide on whether we should create goroutines for HTTP and/or gRPC requests
 	// since requests are passed to a channel after that point we need to store that info and pass it
 	var hasHttpRequests bool
-	if len(opts.HTTP.Requests) > 0 {
+	if len(httpRequests) > 0 {
 		hasHttpRequests = true
 	}
 	var hasGrpcRequests bool
-	if len(opts.Grpc.Requests) > 0 {
+	if len(grpcRequests) > 0 || opts.GetGrpcWarmAll() {
 		hasGrpcRequests = true
 	}
 
+	if protocolMix != nil && !(hasHttpRequests && hasGrpcRequests) {
+		log.Print("Ignoring protocol-mix as there are not both HTTP and gRPC requests")
+	}
+
 	// The next block contains the "wait for target readiness" + "warmup" logic.
 	c1 := make(chan bool, 1)
 
-	requestsSentCounter := 0
+	var requestsSentCounter int64
+	var summary *warmup.Summary
+	var wp *warmup.Warmup
+	var targetNotReady bool
+	var dryRun bool
+	var warmupErr error
 
 	// current time
 	start := time.Now()
 
 	go safe.Do(func() {
 		if !validationError {
-			target := createTarget(targetOptions)
-
 			maxReadinessWaitDurationInSeconds := Min(opts.MaxDurationSeconds, opts.MaxReadinessWaitSeconds)
+			if totalSeconds > 0 {
+				maxReadinessWaitDurationInSeconds = Min(totalSeconds, opts.MaxReadinessWaitSeconds)
+			}
+
+			w := &warmup.Warmup{
+				Target:                           target,
+				Concurrency:                      concurrency,
+				HttpRequests:                     httpRequests,
+				GrpcRequests:                     grpcRequests,
+				GrpcWarmAll:                      opts.GetGrpcWarmAll(),
+				GrpcServiceFilters:               opts.GetGrpcServiceFilters(),
+				HttpHeaders:                      httpHeaders,
+				GrpcMetadata:                     grpcMetadata,
+				RequestDelayMilliseconds:         opts.RequestDelayMilliseconds,
+				DelayJitterMilliseconds:          delayJitterMilliseconds,
+				RequestOrder:                     requestOrder,
+				RampUpCurve:                      rampUpCurve,
+				RampUpShape:                      rampUpShape,
+				GoldenNormalizeJSON:              opts.GoldenNormalizeJSON,
+				GoldenPrintDiff:                  opts.GoldenPrintDiff,
+				TargetRequestsPerSecond:          targetRequestsPerSecond,
+				MinConcurrency:                   minConcurrency,
+				MaxConcurrency:                   maxConcurrency,
+				FailOnUnknownGrpcMethods:         opts.GetGrpcFailOnUnknownMethods(),
+				RequestsPerSecond:                rate,
+				PerWorkerRequestsPerSecond:       perWorkerRate,
+				CorrelationHeader:                opts.GetCorrelationHeader(),
+				CaptureHeaders:                   opts.GetCaptureHeaders(),
+				ConcurrencyControl:               concurrencyControl,
+				ProtocolMix:                      protocolMix,
+				Recorder:                         recorder,
+				ResultLogger:                     resultLogger,
+				Metrics:                          warmupMetrics,
+				MinSuccess:                       minSuccess,
+				MinSuccessPerEndpoint:            opts.MinSuccessPerEndpoint,
+				MaxRequests:                      maxRequests,
+				WorkerSeed:                       opts.GetWorkerSeed(),
+				AbortP99Latency:                  abortP99Latency,
+				AbortP99WindowSeconds:            abortP99WindowSeconds,
+				AbortP99SustainSeconds:           abortP99SustainSeconds,
+				AbortErrorRate:                   abortErrorRate,
+				AbortErrorWindowSeconds:          abortErrorWindowSeconds,
+				AbortErrorMinRequests:            abortErrorMinRequests,
+				SlowRequestThresholdMilliseconds: slowRequestThresholdMilliseconds,
+				MaxBodyBytes:                     maxResponseBodyBytes,
+				Retries:                          retries,
+				RetryBackoff:                     retryBackoff,
+				ReadinessTimeout:                 time.Duration(maxReadinessWaitDurationInSeconds) * time.Second,
+			}
 
-			if err := target.WaitForReadinessProbe(maxReadinessWaitDurationInSeconds, opts.GetWarmupHTTPHeaders()); err == nil {
+			if err := w.WaitForReadiness(context.Background()); err == nil {
 				elapsed := time.Since(start).Seconds()
 
-				log.Printf("💚 Target took %d second(s) to become ready", int(elapsed))
+				log.Printf("%s Target took %d second(s) to become ready", marker.Success(), int(elapsed))
 
-				globalMaxDurationSecondsLeft := opts.MaxDurationSeconds - int(elapsed)
+				if opts.DryRun {
+					summary = validate(w)
+					dryRun = true
+					c1 <- true
+					return
+				}
 
-				maxDurationInSeconds := Min(globalMaxDurationSecondsLeft, opts.MaxWarmupDurationSeconds)
+				var maxDurationInSeconds int
+				schedule := warmup.Schedule{RampUpSeconds: concurrencyTargetSeconds, RampDownSeconds: rampDownSeconds}
+				if totalSeconds > 0 {
+					schedule = totalDurationSchedule(totalSeconds-int(elapsed), schedule.RampUpSeconds, schedule.RampDownSeconds)
+					maxDurationInSeconds = schedule.WarmupSeconds()
+				} else {
+					globalMaxDurationSecondsLeft := opts.MaxDurationSeconds - int(elapsed)
 
-				if maxDurationInSeconds < opts.MaxWarmupDurationSeconds {
-					log.Printf("⚠️ Warmup requests will only run for %d seconds instead of the configured %d seconds as to meet the global maximum duration of %d seconds", maxDurationInSeconds, opts.MaxWarmupDurationSeconds, opts.MaxDurationSeconds)
-				}
+					maxDurationInSeconds = Min(globalMaxDurationSecondsLeft, opts.MaxWarmupDurationSeconds)
 
-				wp := warmup.Warmup{
-					Target:                   target,
-					Concurrency:              opts.GetConcurrency(),
-					HttpRequests:             httpRequests,
-					GrpcRequests:             grpcRequests,
-					HttpHeaders:              opts.GetWarmupHTTPHeaders(),
-					RequestDelayMilliseconds: opts.RequestDelayMilliseconds,
-					ConcurrencyTargetSeconds: opts.GetConcurrencyTargetSeconds(),
+					if maxDurationInSeconds < opts.MaxWarmupDurationSeconds {
+						log.Printf("%s Warmup requests will only run for %d seconds instead of the configured %d seconds as to meet the global maximum duration of %d seconds", marker.Warning(), maxDurationInSeconds, opts.MaxWarmupDurationSeconds, opts.MaxDurationSeconds)
+					}
 				}
 
-				wp.Run(hasHttpRequests, hasGrpcRequests, maxDurationInSeconds, &requestsSentCounter)
+				opts.PrimeDNS()
+
+				w.ConcurrencyTargetSeconds = schedule.RampUpSeconds
+				w.RampDownSeconds = schedule.RampDownSeconds
+				w.DrainSeconds = schedule.DrainSeconds
+				wp = w
+
+				ctx, cancel := warmupContext(stopCondition, stopConditionPollInterval)
+				summary, warmupErr = wp.Run(ctx, hasHttpRequests, hasGrpcRequests, maxDurationInSeconds, &requestsSentCounter)
+				cancel()
+				if warmupErr != nil {
+					log.Printf("%s Warmup could not run: %v", marker.Failure(), warmupErr)
+				}
 			} else {
 				log.Print("Target still not ready. Giving up!")
+				targetNotReady = true
 			}
 		}
 		c1 <- true
 	})
 
 	<-c1
-	log.Println("🟢 Warmup completed")
-	return requestsSentCounter
+	if !dryRun {
+		log.Printf("%s Warmup completed", marker.Success())
+	}
+	return warmupResult{requestsSent: atomic.LoadInt64(&requestsSentCounter), summary: summary, warmup: wp, hasHttpRequests: hasHttpRequests, hasGrpcRequests: hasGrpcRequests,
+		stopCondition: stopCondition, stopConditionPollInterval: stopConditionPollInterval, grpcPing: grpcPing, grpcPingInterval: grpcPingInterval,
+		invalidOptions: validationError, targetNotReady: targetNotReady, dryRun: dryRun, warmupErr: warmupErr}
+}
+
+// validate checks the requests of the warmup without sending them and returns a summary holding the invalid ones as pre-flight errors.
+func validate(w *warmup.Warmup) *warmup.Summary {
+	summary := warmup.NewSummary()
+	for _, problem := range w.Validate() {
+		log.Printf("%s Invalid request: %v", marker.Failure(), problem)
+		summary.AddPreflightError(problem.Error())
+	}
+	w.CloseConnections()
+	return summary
 }
 
 func Min(x, y int) int {
@@ -135,6 +464,53 @@ func Min(x, y int) int {
 	return x
 }
 
+// sampleHTTPRequests returns a random sample of the HTTP requests of the given rate.
+func sampleHTTPRequests(requests []http.Request, rate float64, rnd *rand.Rand) []http.Request {
+	var sample []http.Request
+	for _, i := range warmup.SampleIndexes(len(requests), rate, rnd) {
+		sample = append(sample, requests[i])
+	}
+	if len(requests) > 0 {
+		log.Printf("Sampled %d of %d HTTP request(s)", len(sample), len(requests))
+	}
+	return sample
+}
+
+// sampleGrpcRequests returns a random sample of the gRPC requests of the given rate.
+func sampleGrpcRequests(requests []grpc.Request, rate float64, rnd *rand.Rand) []grpc.Request {
+	var sample []grpc.Request
+	for _, i := range warmup.SampleIndexes(len(requests), rate, rnd) {
+		sample = append(sample, requests[i])
+	}
+	if len(requests) > 0 {
+		log.Printf("Sampled %d of %d gRPC request(s)", len(sample), len(requests))
+	}
+	return sample
+}
+
+// totalDurationSchedule splits the seconds left out of `-total-duration` once the target is ready into the phases of the warmup.
+// It warns if the ramps had to be shortened to fit.
+func totalDurationSchedule(secondsLeft int, rampUpSeconds int, rampDownSeconds int) warmup.Schedule {
+	schedule, ok := warmup.NewSchedule(secondsLeft, rampUpSeconds, rampDownSeconds)
+	if !ok {
+		log.Printf("%s The ramp-up and ramp-down of %d second(s) alone exceed the %d second(s) left of the total duration. They are shortened to %d and %d second(s)",
+			marker.Warning(), rampUpSeconds+rampDownSeconds, secondsLeft, schedule.RampUpSeconds, schedule.RampDownSeconds)
+	}
+	log.Printf("Warmup schedule for the %d second(s) left of the total duration: ramp-up %ds, steady %ds, ramp-down %ds, drain up to %ds",
+		secondsLeft, schedule.RampUpSeconds, schedule.SteadySeconds, schedule.RampDownSeconds, schedule.DrainSeconds)
+	return schedule
+}
+
+// warmupContext returns the context of a warmup run, which is cancelled once the stop condition, if any, is satisfied.
+// The returned function stops watching the condition and must be called once the run is over.
+func warmupContext(condition stopcondition.Condition, pollInterval time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if condition == nil {
+		return ctx, cancel
+	}
+	return stopcondition.Watch(ctx, condition, pollInterval), cancel
+}
+
 // block blocks forever unless `-exit-after-warmup` is set to true
 func block() {
 	if !opts.ExitAfterWarmup {
@@ -142,32 +518,278 @@ func block() {
 	}
 }
 
+// rewarm runs the warmup again every `-rewarm-interval-seconds` once it first completes.
+// It blocks forever if enabled, unless `-exit-after-warmup` is set to true.
+func rewarm(result warmupResult) {
+	if opts.RewarmIntervalSeconds <= 0 || result.warmup == nil {
+		return
+	}
+	if opts.ExitAfterWarmup {
+		log.Print("Ignoring rewarm-interval-seconds as exit-after-warmup is set")
+		return
+	}
+
+	grpcPing := result.grpcPing
+	if grpcPing != nil && opts.RewarmCloseConnections {
+		log.Print("Ignoring rewarm-grpc-ping as rewarm-close-connections is set")
+		grpcPing = nil
+	}
+
+	for {
+		if opts.RewarmCloseConnections {
+			result.warmup.CloseConnections()
+		}
+		idle := time.Duration(opts.RewarmIntervalSeconds) * time.Second
+		if grpcPing != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), idle)
+			sent, failed := result.warmup.KeepAlive(ctx, *grpcPing, result.grpcPingInterval)
+			cancel()
+			log.Printf("Sent %d gRPC keep-alive ping(s) to %s between warmup cycles, %d failed", sent, grpcPing.ServiceMethod, failed)
+		} else {
+			time.Sleep(idle)
+		}
+
+		log.Print("Starting a new warmup cycle")
+		var requestsSent int64
+		safe.Do(func() {
+			ctx, cancel := warmupContext(result.stopCondition, result.stopConditionPollInterval)
+			defer cancel()
+			if _, err := result.warmup.Run(ctx, result.hasHttpRequests, result.hasGrpcRequests, opts.MaxWarmupDurationSeconds, &requestsSent); err != nil {
+				log.Printf("%s Warmup cycle could not run: %v", marker.Failure(), err)
+			}
+		})
+		log.Printf("Warmup cycle finished. Approximately %d reqs were sent", atomic.LoadInt64(&requestsSent))
+	}
+}
+
 // postProcess includes steps that run once the warmup finishes.
 // For now this either announces that the app is ready or fails the readiness probe.
-// The latter only happens if mittens did not send any requests and the user allows the readiness to fail.
-func postProcess(requestsSentCounter int) {
-	if opts.FailReadiness && requestsSentCounter == 0 {
-		log.Print("🛑 Warmup did not run. Mittens readiness probe will fail 🙁")
+// The latter only happens if the pre-flight validation failed, if no warmup request could be sent at all,
+// if mittens did not send any requests and the user allows the readiness to fail,
+// if the user requires every endpoint to succeed at least once and some endpoint never did,
+// if the minimum number of successful requests was not reached, or if too many responses exceeded the max latency of their request.
+// It finally prints the summary line and writes the JSON summary if enabled and returns the exit code matching the outcome of the warmup.
+func postProcess(result warmupResult) int {
+	if result.dryRun {
+		if errs := result.summary.PreflightErrors(); len(errs) > 0 {
+			log.Printf("%s Dry run found %d invalid request(s)", marker.Failure(), len(errs))
+			return exitConfigError
+		}
+		log.Printf("%s Dry run found no invalid request", marker.Success())
+		return exitOK
+	}
+	for _, e := range result.summary.Endpoints() {
+		if e.Hedged > 0 {
+			log.Printf("%d of the %d requests to %s endpoint %s were hedged", e.Hedged, e.Sent, e.Protocol, e.Endpoint)
+		}
+		if e.Retried > 0 {
+			log.Printf("Requests to %s endpoint %s were retried %d time(s)", e.Protocol, e.Endpoint, e.Retried)
+		}
+		if e.LatencyChecked > 0 {
+			log.Printf("%d of the %d checked responses of %s endpoint %s (%.1f%%) exceeded their max latency", e.LatencyViolations, e.LatencyChecked, e.Protocol, e.Endpoint, 100*float64(e.LatencyViolations)/float64(e.LatencyChecked))
+		}
+		if e.Latencies != nil && e.Latencies.Count() > 0 {
+			log.Printf("Latency of %s endpoint %s: p50 %v, p90 %v, p99 %v, max %v", e.Protocol, e.Endpoint,
+				formatLatency(e.Latencies.Percentile(50)), formatLatency(e.Latencies.Percentile(90)), formatLatency(e.Latencies.Percentile(99)), formatLatency(e.Latencies.Max()))
+		}
+		if e.LatencyStalled() {
+			log.Printf("%s Latency of %s endpoint %s did not improve during the warmup: p50 %v over its first %d responses and %v over its last %d, the warmup requests may not hit the caches used by the real traffic",
+				marker.Warning(), e.Protocol, e.Endpoint, formatLatency(e.FirstLatencies.Percentile(50)), e.FirstLatencies.Count(), formatLatency(e.LastLatencies.Percentile(50)), e.LastLatencies.Count())
+		}
+		for _, name := range opts.GetCaptureHeaders() {
+			if values := e.HeaderValues[name]; len(values) > 0 {
+				log.Printf("%s values returned by %s endpoint %s: %s", name, e.Protocol, e.Endpoint, headerDistribution(values))
+			}
+		}
+	}
+	logSlowSamples(result.summary.SlowSamples())
+	if result.warmup != nil {
+		logHTTPConnections(result.warmup.Target.HTTPConnections())
+		if stats, ok := result.warmup.Target.GrpcPoolStats(); ok {
+			log.Printf("gRPC connection pool: %d of %d connection(s) healthy, %d replaced", stats.Healthy, stats.Size, stats.Replaced)
+		}
+	}
+	if opts.JUnitOut != "" {
+		if err := report.WriteJUnit(opts.JUnitOut, result.summary); err != nil {
+			log.Print(err)
+		}
+	}
+	if opts.LatencyDump != "" {
+		if err := report.WriteLatencyDump(opts.LatencyDump, result.summary.Latencies()); err != nil {
+			log.Print(err)
+		}
+	}
+	if opts.TimeseriesOut != "" {
+		if err := report.WriteTimeseries(opts.TimeseriesOut, result.summary.Timeseries()); err != nil {
+			log.Print(err)
+		}
+	}
+
+	if reason := result.summary.AbortReason(); reason != "" {
+		log.Printf("%s Warmup was aborted: %s", marker.Warning(), reason)
+	}
+
+	ready := false
+	exitCode := exitOK
+	if errs := result.summary.PreflightErrors(); len(errs) > 0 {
+		log.Printf("%s Pre-flight validation failed: %v. Mittens readiness probe will fail", marker.Failure(), errs)
+		exitCode = exitConfigError
+	} else if result.warmupErr != nil {
+		log.Printf("%s Warmup could not run: %v. Mittens readiness probe will fail", marker.Failure(), result.warmupErr)
+		exitCode = exitConnectionFailure
+		if errors.Is(result.warmupErr, warmup.ErrNoRequests) {
+			exitCode = exitConfigError
+		}
+	} else if opts.FailReadiness && result.requestsSent == 0 {
+		log.Printf("%s Warmup did not run. Mittens readiness probe will fail", marker.Failure())
+		exitCode = exitConnectionFailure
+	} else if result.summary.AbortFailed() {
+		log.Printf("%s Warmup was aborted because too many requests failed. Mittens readiness probe will fail", marker.Failure())
+		exitCode = exitThresholdExceeded
+	} else if opts.RequireAllEndpointsOk && !allEndpointsOk(result.summary) {
+		log.Printf("%s Not all endpoints returned a successful response. Mittens readiness probe will fail", marker.Failure())
+		exitCode = exitThresholdExceeded
+	} else if shortfall := minSuccessShortfall(result.summary); len(shortfall) > 0 {
+		log.Printf("%s The minimum of %d successful request(s) was not reached: %s. Mittens readiness probe will fail", marker.Failure(), opts.MinSuccess, strings.Join(shortfall, ", "))
+		exitCode = exitMinSuccessNotMet
+	} else if violations := result.summary.LatencyViolationPercent(); violations > opts.MaxLatencyViolationPct {
+		log.Printf("%s %.1f%% of the responses exceeded their max latency, more than the allowed %.1f%%. Mittens readiness probe will fail", marker.Failure(), violations, opts.MaxLatencyViolationPct)
+		exitCode = exitThresholdExceeded
 	} else {
-		if requestsSentCounter == 0 {
-			log.Print("🛑 Warm up finished but no requests were sent 🙁")
+		if result.requestsSent == 0 {
+			log.Printf("%s Warm up finished but no requests were sent", marker.Failure())
 		} else {
-			log.Printf("Warm up finished 😊 Approximately %d reqs were sent", requestsSentCounter)
+			log.Printf("%s Warm up finished. Approximately %d reqs were sent", marker.Success(), result.requestsSent)
 		}
 
 		if opts.FileProbe.Enabled {
 			probe.WriteFile("ready")
 		}
+		ready = true
+	}
+	if opts.SummaryLine {
+		fmt.Println(report.SummaryLine(result.summary, ready))
+	}
+	if opts.SummaryJSON != "" {
+		if err := report.WriteSummaryJSON(opts.SummaryJSON, result.summary, ready); err != nil {
+			log.Print(err)
+		}
 	}
+
+	// the warmup did not run at all, whatever the readiness
+	if result.invalidOptions {
+		return exitConfigError
+	} else if result.targetNotReady {
+		return exitConnectionFailure
+	}
+	return exitCode
+}
+
+// logHTTPConnections logs the number of connections opened to every HTTP host, sorted by host.
+func logHTTPConnections(connections map[string]http.HostConnections) {
+	hosts := make([]string, 0, len(connections))
+	for host := range connections {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		c := connections[host]
+		log.Printf("Opened %d HTTP connection(s) to %s, at most %d at the same time", c.Opened, host, c.Peak)
+	}
+}
+
+// formatLatency formats a latency in milliseconds, to a precision that suits logs.
+// Unlike time.Duration it never uses µs, which would not be plain ASCII.
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+// headerDistribution describes how often each value of a header was returned, most frequent first, e.g. `HIT 75.0% (3), MISS 25.0% (1)`.
+// Responses without the header are counted as "(none)".
+func headerDistribution(counts map[string]int) string {
+	total := 0
+	values := make([]string, 0, len(counts))
+	for value, count := range counts {
+		total += count
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		name := value
+		if name == "" {
+			name = "(none)"
+		}
+		parts = append(parts, fmt.Sprintf("%s %.1f%% (%d)", name, 100*float64(counts[value])/float64(total), counts[value]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// logSlowSamples logs the slowest requests above the slow request threshold, at most loggedSlowSamples of them.
+func logSlowSamples(samples []warmup.SlowSample) {
+	if len(samples) == 0 {
+		return
+	}
+	if len(samples) > loggedSlowSamples {
+		samples = samples[:loggedSlowSamples]
+	}
+	log.Printf("Slowest requests above %d ms:", opts.SlowRequestThresholdMs)
+	for _, sample := range samples {
+		log.Printf("%s %s %s\t%d ms", marker.Slow(), sample.Protocol, sample.Endpoint, sample.Duration/time.Millisecond)
+	}
+}
+
+// minSuccessShortfall returns how far the successful requests are from `-min-success`, or nothing if it was reached or is not set.
+func minSuccessShortfall(summary *warmup.Summary) []string {
+	if opts.MinSuccess <= 0 {
+		return nil
+	}
+	return summary.SuccessShortfall(opts.MinSuccess, opts.MinSuccessPerEndpoint)
+}
+
+// allEndpointsOk returns true if every endpoint returned at least one successful response.
+// It logs the endpoints that never did.
+func allEndpointsOk(summary *warmup.Summary) bool {
+	if summary == nil {
+		log.Print("Warmup did not run so no endpoint returned a successful response")
+		return false
+	}
+	endpoints := summary.EndpointsWithoutSuccess()
+	for _, e := range endpoints {
+		log.Printf("%s %s endpoint %s never returned a successful response (%d sent, %d failed)", marker.Failure(), e.Protocol, e.Endpoint, e.Sent, e.Failures)
+	}
+	return len(endpoints) == 0
 }
 
 // createTarget creates the target versus which mittens will run.
-func createTarget(targetOptions warmup.TargetOptions) warmup.Target {
-	return warmup.NewTarget(
-		opts.GetReadinessHTTPClient(),
-		opts.GetReadinessGrpcClient(),
-		opts.GetHTTPClient(),
-		opts.GetGrpcClient(),
-		targetOptions,
-	)
+// It returns an error if a host is invalid, in which case no request could be sent.
+func createTarget(targetOptions warmup.TargetOptions) (warmup.Target, error) {
+	readinessHTTPClient, err := opts.GetReadinessHTTPClient()
+	if err != nil {
+		return warmup.Target{}, err
+	}
+	readinessGrpcClient, err := opts.GetReadinessGrpcClient()
+	if err != nil {
+		return warmup.Target{}, err
+	}
+	httpClient, err := opts.GetHTTPClient()
+	if err != nil {
+		return warmup.Target{}, err
+	}
+	grpcClient, err := opts.GetGrpcClient()
+	if err != nil {
+		return warmup.Target{}, err
+	}
+	additionalGrpcClients, err := opts.GetAdditionalGrpcClients()
+	if err != nil {
+		return warmup.Target{}, err
+	}
+	return warmup.NewTarget(readinessHTTPClient, readinessGrpcClient, httpClient, grpcClient, targetOptions).
+		WithAdditionalGrpcClients(additionalGrpcClients...), nil
 }
@@ -1,13 +1,19 @@
 package fixture
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/test/grpc_testing"
 )
@@ -17,15 +23,90 @@ type PathResponseHandler struct {
 	PathHandlerFunc func(rw http.ResponseWriter, r *http.Request)
 }
 
+// testServiceServer implements EmptyCall, StreamingOutputCall and StreamingInputCall. All the other methods return the Unimplemented status.
+type testServiceServer struct {
+	grpc_testing.UnimplementedTestServiceServer
+}
+
+func (testServiceServer) EmptyCall(context.Context, *grpc_testing.Empty) (*grpc_testing.Empty, error) {
+	return &grpc_testing.Empty{}, nil
+}
+
+// StreamingOutputCall streams a response with a payload of the requested size for every response parameter, waiting the requested interval before each.
+func (testServiceServer) StreamingOutputCall(request *grpc_testing.StreamingOutputCallRequest, stream grpc_testing.TestService_StreamingOutputCallServer) error {
+	for _, parameters := range request.ResponseParameters {
+		time.Sleep(time.Duration(parameters.IntervalUs) * time.Microsecond)
+		if err := stream.Send(&grpc_testing.StreamingOutputCallResponse{Payload: &grpc_testing.Payload{Body: make([]byte, parameters.Size)}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamingInputCall returns the size of all the payloads received once the client closes the stream.
+func (testServiceServer) StreamingInputCall(stream grpc_testing.TestService_StreamingInputCallServer) error {
+	var size int32
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&grpc_testing.StreamingInputCallResponse{AggregatedPayloadSize: size})
+		}
+		if err != nil {
+			return err
+		}
+		size += int32(len(request.Payload.GetBody()))
+	}
+}
+
 // StartGrpcTargetTestServer starts a gRPC server on the provided port
 // It uses the test.proto from grpc-testing: https://github.com/grpc/grpc-go/blob/40a879c23a0dc77234d17e0699d074d5fd151bd0/test/grpc_testing/test.proto
+// Only EmptyCall, StreamingOutputCall and StreamingInputCall are implemented. The standard health-check service reports the server as serving.
 func StartGrpcTargetTestServer(port int) *grpc.Server {
-	server := grpc.NewServer()
-	grpc_testing.RegisterTestServiceServer(server, &grpc_testing.UnimplementedTestServiceServer{})
-	reflection.Register(server)
+	return startGrpcTargetTestServer(port, true)
+}
 
+// StartGrpcTargetTestServerWithoutReflection starts the same gRPC server as StartGrpcTargetTestServer without the server reflection service.
+func StartGrpcTargetTestServerWithoutReflection(port int) *grpc.Server {
+	return startGrpcTargetTestServer(port, false)
+}
+
+// StartGrpcTargetTestServerOnFreePort starts the same gRPC server as StartGrpcTargetTestServer on a free port of 127.0.0.1
+// and stops it at the end of the test. It returns the address of the server in host:port format.
+func StartGrpcTargetTestServerOnFreePort(t testing.TB) string {
+	return startGrpcTargetTestServerOnFreePort(t, true)
+}
+
+// StartGrpcTargetTestServerWithoutReflectionOnFreePort works like StartGrpcTargetTestServerOnFreePort but without the
+// server reflection service.
+func StartGrpcTargetTestServerWithoutReflectionOnFreePort(t testing.TB) string {
+	return startGrpcTargetTestServerOnFreePort(t, false)
+}
+
+func startGrpcTargetTestServerOnFreePort(t testing.TB, withReflection bool) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen on a free port: %v", err)
+	}
+	server := serveGrpcTargetTestServer(l, withReflection)
+	t.Cleanup(server.Stop)
+	return l.Addr().String()
+}
+
+func startGrpcTargetTestServer(port int, withReflection bool) *grpc.Server {
 	uri := ":" + fmt.Sprint(port)
 	l, _ := net.Listen("tcp", uri)
+	return serveGrpcTargetTestServer(l, withReflection)
+}
+
+// serveGrpcTargetTestServer serves the test gRPC services on the listener.
+func serveGrpcTargetTestServer(l net.Listener, withReflection bool) *grpc.Server {
+	server := grpc.NewServer()
+	grpc_testing.RegisterTestServiceServer(server, testServiceServer{})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	if withReflection {
+		reflection.Register(server)
+	}
 
 	go func() {
 		err := server.Serve(l)
@@ -66,3 +147,25 @@ func StartHttpTargetTestServer(pathHandlers []PathResponseHandler) (*http.Server
 	}()
 	return server, port
 }
+
+// StartLatencyTestServer starts a HTTP server on a random port whose responses take the provided latencies in turn,
+// whatever the path, so that the distribution of the latencies is known.
+func StartLatencyTestServer(latencies []time.Duration) (*http.Server, int) {
+	var served uint64
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddUint64(&served, 1) - 1
+		time.Sleep(latencies[n%uint64(len(latencies))])
+		w.WriteHeader(http.StatusOK)
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed. Err: %v", err)
+		}
+	}()
+	return server, listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,46 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package certs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCertPool(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	writeCertificate(t, caFile, filepath.Join(dir, "ca.key"), "ca")
+
+	pool, err := LoadCertPool(caFile)
+	require.NoError(t, err)
+	assert.NotNil(t, pool)
+}
+
+func TestLoadCertPool_InvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadCertPool(filepath.Join(dir, "missing.crt"))
+	assert.Error(t, err)
+
+	emptyFile := filepath.Join(dir, "empty.crt")
+	require.NoError(t, os.WriteFile(emptyFile, []byte("not a certificate"), 0644))
+	_, err = LoadCertPool(emptyFile)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no certificates found in CA file")
+}
@@ -0,0 +1,86 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package certs
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader loads a client certificate and its key from files and reloads them whenever the files change.
+// This allows certificates mounted from e.g. Kubernetes secrets to be rotated without restarting mittens.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+// NewReloader returns a reloader for the given certificate and key files.
+// It returns an error if the files cannot be loaded.
+func NewReloader(certFile string, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reloadIfChanged(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetClientCertificate returns the current client certificate. It can be used as the tls.Config callback of the same name.
+// If the files changed since they were last loaded they are reloaded. If reloading fails the previous certificate is kept.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if err := r.reloadIfChanged(); err != nil {
+		log.Printf("Keeping the previous client certificate: %v", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.certificate, nil
+}
+
+func (r *Reloader) reloadIfChanged() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("client certificate: %v", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("client key: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.certificate != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load client certificate: %v", err)
+	}
+	if r.certificate != nil {
+		log.Printf("Reloaded client certificate from %s", r.certFile)
+	}
+	r.certificate = &certificate
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	return nil
+}
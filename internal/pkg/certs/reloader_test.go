@@ -0,0 +1,99 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader_ReloadsRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeCertificate(t, certFile, keyFile, "first")
+
+	reloader, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "first", commonName(t, reloader))
+
+	writeCertificate(t, certFile, keyFile, "second")
+	// make sure the modification time changes even if the files were rewritten within the same second
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+
+	assert.Equal(t, "second", commonName(t, reloader))
+}
+
+func TestReloader_KeepsPreviousCertificateIfReloadFails(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeCertificate(t, certFile, keyFile, "first")
+
+	reloader, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0644))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+
+	assert.Equal(t, "first", commonName(t, reloader))
+}
+
+func TestReloader_InvalidFiles(t *testing.T) {
+	_, err := NewReloader("non-existent.crt", "non-existent.key")
+	require.Error(t, err)
+}
+
+func commonName(t *testing.T, reloader *Reloader) string {
+	certificate, err := reloader.GetClientCertificate(&tls.CertificateRequestInfo{})
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
+
+func writeCertificate(t *testing.T, certFile string, keyFile string, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
@@ -0,0 +1,136 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Warmup request definitions served centrally and fetched at startup.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/util"
+	nethttp "net/http"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxBytes bounds the size of a config so that a misconfigured URL cannot exhaust the memory.
+const maxBytes = 10 << 20
+
+// fetchTimeout bounds the time spent fetching a config.
+const fetchTimeout = 10 * time.Second
+
+// Config holds warmup request definitions in the same format as the http-requests and grpc-requests flags, e.g.
+//
+//	http-requests:
+//	  - get:/ping
+//	  - "[burst=3]post:/search:{\"q\":\"shoes\"}"
+//	grpc-requests:
+//	  - health.Health/Check
+//
+// JSON documents are also accepted since YAML is a superset of JSON.
+type Config struct {
+	HTTPRequests []string `yaml:"http-requests"`
+	GrpcRequests []string `yaml:"grpc-requests"`
+}
+
+// Parse parses and validates a config. Unknown keys and invalid requests are rejected.
+func Parse(content []byte) ([]http.Request, []grpc.Request, error) {
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && err != io.EOF {
+		return nil, nil, fmt.Errorf("invalid config: %v", err)
+	}
+
+	var httpRequests []http.Request
+	for _, requestFlag := range config.HTTPRequests {
+		request, err := http.ToHTTPRequest(requestFlag)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid config: %v", err)
+		}
+		httpRequests = append(httpRequests, request)
+	}
+	var grpcRequests []grpc.Request
+	for _, requestFlag := range config.GrpcRequests {
+		request, err := grpc.ToGrpcRequest(requestFlag)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid config: %v", err)
+		}
+		grpcRequests = append(grpcRequests, request)
+	}
+	return httpRequests, grpcRequests, nil
+}
+
+// Fetch downloads a config. Headers, e.g. `Authorization: Bearer <token>`, are sent with the request.
+func Fetch(url string, headers []string) ([]byte, error) {
+	request, err := nethttp.NewRequest(nethttp.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch config from %s: %v", url, err)
+	}
+	for name, value := range util.ToHeaders(headers) {
+		request.Header.Set(name, value)
+	}
+	client := &nethttp.Client{Timeout: fetchTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch config from %s: %v", url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != nethttp.StatusOK {
+		return nil, fmt.Errorf("cannot fetch config from %s: status code %d", url, response.StatusCode)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch config from %s: %v", url, err)
+	}
+	if len(content) > maxBytes {
+		return nil, fmt.Errorf("cannot fetch config from %s: larger than %d bytes", url, maxBytes)
+	}
+	return content, nil
+}
+
+// Requests fetches the config served at url and returns its requests. If the config cannot be fetched or is invalid
+// and fallbackFile is set, the config is read from that file instead.
+func Requests(url string, headers []string, fallbackFile string) ([]http.Request, []grpc.Request, error) {
+	content, err := Fetch(url, headers)
+	if err == nil {
+		var httpRequests []http.Request
+		var grpcRequests []grpc.Request
+		if httpRequests, grpcRequests, err = Parse(content); err == nil {
+			return httpRequests, grpcRequests, nil
+		}
+		err = fmt.Errorf("config fetched from %s: %v", url, err)
+	}
+	if fallbackFile == "" {
+		return nil, nil, err
+	}
+
+	log.Printf("%v. Using the fallback config %s", err, fallbackFile)
+	content, err = os.ReadFile(fallbackFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot read fallback config: %v", err)
+	}
+	httpRequests, grpcRequests, err := Parse(content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fallback config %s: %v", fallbackFile, err)
+	}
+	return httpRequests, grpcRequests, nil
+}
@@ -0,0 +1,99 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validConfig = `
+http-requests:
+  - get:/ping
+  - "[burst=3]post:/search:{\"q\":\"shoes\"}"
+grpc-requests:
+  - health.Health/Check
+`
+
+func TestParse(t *testing.T) {
+	httpRequests, grpcRequests, err := Parse([]byte(validConfig))
+	require.NoError(t, err)
+
+	require.Len(t, httpRequests, 2)
+	assert.Equal(t, "/search", httpRequests[1].Path)
+	assert.Equal(t, 3, httpRequests[1].Burst)
+	assert.Equal(t, `{"q":"shoes"}`, *httpRequests[1].Body)
+	require.Len(t, grpcRequests, 1)
+	assert.Equal(t, "health.Health/Check", grpcRequests[0].ServiceMethod)
+}
+
+func TestParse_JSON(t *testing.T) {
+	httpRequests, _, err := Parse([]byte(`{"http-requests": ["get:/ping"]}`))
+	require.NoError(t, err)
+	assert.Len(t, httpRequests, 1)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, _, err := Parse([]byte("http-request:\n  - get:/ping\n"))
+	assert.ErrorContains(t, err, "http-request not found")
+
+	_, _, err = Parse([]byte("http-requests:\n  - \"fe tch:/ping\"\n"))
+	assert.ErrorContains(t, err, "method FE TCH is not a valid HTTP method")
+
+	_, _, err = Parse([]byte("http-requests: get:/ping\n"))
+	assert.Error(t, err)
+}
+
+func TestRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rw.Write([]byte(validConfig))
+	}))
+	defer server.Close()
+
+	httpRequests, grpcRequests, err := Requests(server.URL, []string{"Authorization: Bearer secret"}, "")
+	require.NoError(t, err)
+	assert.Len(t, httpRequests, 2)
+	assert.Len(t, grpcRequests, 1)
+
+	_, _, err = Requests(server.URL, nil, "")
+	assert.ErrorContains(t, err, "status code 401")
+}
+
+func TestRequests_Fallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("not: a config"))
+	}))
+	defer server.Close()
+	fallback := filepath.Join(t.TempDir(), "warmup.yaml")
+	require.NoError(t, os.WriteFile(fallback, []byte("http-requests: [get:/fallback]"), 0644))
+
+	httpRequests, _, err := Requests(server.URL, nil, fallback)
+	require.NoError(t, err)
+	require.Len(t, httpRequests, 1)
+	assert.Equal(t, "/fallback", httpRequests[0].Path)
+
+	_, _, err = Requests(server.URL, nil, filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "cannot read fallback config")
+}
@@ -0,0 +1,84 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// HTTP endpoint to control the warmup while it runs.
+
+package control
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mittens/internal/pkg/safe"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ConcurrencyPath is the path of the endpoint that reads and changes the concurrency.
+const ConcurrencyPath = "/concurrency"
+
+// Concurrency is the concurrency of the warmup that can be read and changed.
+type Concurrency interface {
+	Concurrency() int
+	SetConcurrency(n int) error
+}
+
+// Handler returns the handler of the control endpoints.
+// GET /concurrency returns the current concurrency and PUT or POST /concurrency with a number as body changes it.
+func Handler(concurrency Concurrency) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ConcurrencyPath, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			body, err := io.ReadAll(io.LimitReader(r.Body, 64))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(string(body)))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid concurrency %q", body), http.StatusBadRequest)
+				return
+			}
+			if err := concurrency.SetConcurrency(n); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		fmt.Fprintf(w, "%d\n", concurrency.Concurrency())
+	})
+	return mux
+}
+
+// Start serves the control endpoints on address in the background.
+func Start(address string, concurrency Concurrency) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	log.Printf("Control endpoint listening on %s", listener.Addr())
+	go safe.Do(func() {
+		if err := http.Serve(listener, Handler(concurrency)); err != nil {
+			log.Printf("Control endpoint stopped: %v", err)
+		}
+	})
+	return nil
+}
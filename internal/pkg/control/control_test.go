@@ -0,0 +1,73 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package control
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConcurrency struct {
+	n int
+}
+
+func (c *fakeConcurrency) Concurrency() int {
+	return c.n
+}
+
+func (c *fakeConcurrency) SetConcurrency(n int) error {
+	if n < 1 {
+		return fmt.Errorf("concurrency must be greater than 0")
+	}
+	c.n = n
+	return nil
+}
+
+func TestConcurrencyEndpoint(t *testing.T) {
+	concurrency := &fakeConcurrency{n: 2}
+	handler := Handler(concurrency)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ConcurrencyPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, ConcurrencyPath, strings.NewReader("5\n")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "5\n", rec.Body.String())
+	assert.Equal(t, 5, concurrency.n)
+}
+
+func TestConcurrencyEndpoint_Invalid(t *testing.T) {
+	concurrency := &fakeConcurrency{n: 2}
+	handler := Handler(concurrency)
+
+	for _, body := range []string{"", "many", "0"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ConcurrencyPath, strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+	assert.Equal(t, 2, concurrency.n)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, ConcurrencyPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
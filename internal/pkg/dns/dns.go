@@ -0,0 +1,114 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Resolve resolves every host and logs how long each resolution took.
+// It returns the addresses of the hosts that could be resolved.
+func Resolve(ctx context.Context, hosts []string) map[string][]string {
+	addresses := make(map[string][]string)
+	for _, host := range hosts {
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		start := time.Now()
+		hostAddresses, err := net.DefaultResolver.LookupHost(ctx, host)
+		elapsed := time.Since(start)
+		if err != nil {
+			log.Printf("DNS resolution of %s failed after %d ms: %v", host, elapsed/time.Millisecond, err)
+			continue
+		}
+		log.Printf("DNS resolution of %s took %d ms: %v", host, elapsed/time.Millisecond, hostAddresses)
+		addresses[host] = hostAddresses
+	}
+	return addresses
+}
+
+// Cache is a simple in-process DNS cache. Hosts are resolved once and their addresses are kept for the rest of the run.
+type Cache struct {
+	dialer    *net.Dialer
+	mu        sync.Mutex
+	addresses map[string][]string
+}
+
+// NewCache returns an empty DNS cache.
+func NewCache() *Cache {
+	return &Cache{dialer: &net.Dialer{}, addresses: make(map[string][]string)}
+}
+
+// Prime resolves the hosts and stores their addresses in the cache.
+func (c *Cache) Prime(ctx context.Context, hosts []string) {
+	resolved := Resolve(ctx, hosts)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for host, addresses := range resolved {
+		c.addresses[host] = addresses
+	}
+}
+
+// DialContext connects to the address using the cached addresses of its host.
+// Hosts that are not cached yet are resolved and cached.
+func (c *Cache) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.dialer.DialContext(ctx, network, address)
+	}
+
+	addresses, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var dialErr error
+	for _, ip := range addresses {
+		conn, err := c.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+	}
+	return nil, dialErr
+}
+
+func (c *Cache) lookup(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	addresses, ok := c.addresses[host]
+	c.mu.Unlock()
+	if ok {
+		return addresses, nil
+	}
+
+	addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	c.mu.Lock()
+	c.addresses[host] = addresses
+	c.mu.Unlock()
+	return addresses, nil
+}
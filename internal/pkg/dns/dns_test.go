@@ -0,0 +1,55 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolve(t *testing.T) {
+	addresses := Resolve(context.Background(), []string{"localhost", "127.0.0.1"})
+
+	assert.NotEmpty(t, addresses["localhost"])
+	// IP addresses do not need to be resolved
+	assert.NotContains(t, addresses, "127.0.0.1")
+}
+
+func TestCache_DialContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	cache := NewCache()
+	cache.Prime(context.Background(), []string{"localhost"})
+	// point the cached host to the listener regardless of what localhost resolves to
+	cache.addresses["localhost"] = []string{"127.0.0.1"}
+
+	conn, err := cache.DialContext(context.Background(), "tcp", net.JoinHostPort("localhost", port))
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestCache_DialContextUncachedHost(t *testing.T) {
+	cache := NewCache()
+
+	_, err := cache.DialContext(context.Background(), "tcp", "invalid-address")
+	require.Error(t, err)
+}
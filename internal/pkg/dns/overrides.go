@@ -0,0 +1,59 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Overrides maps host names to the IP address their connections are opened to instead, like entries of /etc/hosts.
+// Only the address that is dialled changes, so TLS and the Host header still use the original host name.
+type Overrides map[string]string
+
+// ParseOverrides parses overrides in host=ip format, e.g. api.example.com=10.0.0.5.
+func ParseOverrides(overrides []string) (Overrides, error) {
+	parsed := make(Overrides)
+	for _, override := range overrides {
+		host, ip, ok := strings.Cut(override, "=")
+		host, ip = strings.TrimSpace(host), strings.TrimSpace(ip)
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid host override %q, expected host=ip", override)
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid host override %q: %q is not an IP address", override, ip)
+		}
+		parsed[strings.ToLower(host)] = ip
+	}
+	return parsed, nil
+}
+
+// Wrap returns a dial function that connects to the IP address of the overridden hosts and to any other address as is.
+// A nil dial stands for the default dialer.
+func (o Overrides) Wrap(dial func(ctx context.Context, network string, address string) (net.Conn, error)) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(address); err == nil {
+			if ip, ok := o[strings.ToLower(host)]; ok {
+				address = net.JoinHostPort(ip, port)
+			}
+		}
+		return dial(ctx, network, address)
+	}
+}
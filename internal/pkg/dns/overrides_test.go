@@ -0,0 +1,64 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package dns
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOverrides(t *testing.T) {
+	overrides, err := ParseOverrides([]string{"api.example.com=10.0.0.5", "Other.example.com = ::1"})
+	require.NoError(t, err)
+	assert.Equal(t, Overrides{"api.example.com": "10.0.0.5", "other.example.com": "::1"}, overrides)
+
+	_, err = ParseOverrides([]string{"api.example.com"})
+	assert.ErrorContains(t, err, "expected host=ip")
+	_, err = ParseOverrides([]string{"api.example.com=backend.local"})
+	assert.ErrorContains(t, err, "is not an IP address")
+}
+
+func TestOverrides_Wrap(t *testing.T) {
+	var dialled []string
+	dial := Overrides{"api.example.com": "10.0.0.5"}.Wrap(func(ctx context.Context, network string, address string) (net.Conn, error) {
+		dialled = append(dialled, address)
+		return nil, nil
+	})
+
+	dial(context.Background(), "tcp", "API.example.com:443")
+	dial(context.Background(), "tcp", "other.example.com:443")
+	assert.Equal(t, []string{"10.0.0.5:443", "other.example.com:443"}, dialled)
+}
+
+func TestOverrides_KeepHostHeader(t *testing.T) {
+	var host string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client := &http.Client{Transport: &http.Transport{DialContext: Overrides{"api.example.test": "127.0.0.1"}.Wrap(nil)}}
+	resp, err := client.Get("http://api.example.test:" + port + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "api.example.test:"+port, host)
+}
@@ -0,0 +1,108 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Golden files holding the expected responses of warmup requests.
+
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxBodyBytes is the maximum size of a response body that is compared against a golden file.
+// Larger responses are only read up to this size and reported as mismatches.
+const MaxBodyBytes = 10 * 1024 * 1024
+
+// File is a golden file loaded in memory.
+type File struct {
+	Path    string
+	content []byte
+}
+
+// Load reads a golden file.
+func Load(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read golden file: %v", err)
+	}
+	if len(content) > MaxBodyBytes {
+		return nil, fmt.Errorf("golden file %s is larger than %d bytes", path, MaxBodyBytes)
+	}
+	return &File{Path: path, content: content}, nil
+}
+
+// Compare returns an empty string if the body matches the golden file or a description of the differences otherwise.
+// If normalizeJSON is true and both are valid JSON they are compared regardless of field order and whitespace.
+func (f *File) Compare(body []byte, truncated bool, normalizeJSON bool) string {
+	if truncated {
+		return fmt.Sprintf("response body is larger than %d bytes", MaxBodyBytes)
+	}
+
+	expected, actual := f.content, body
+	if normalizeJSON {
+		if e, a, ok := normalize(expected, actual); ok {
+			expected, actual = e, a
+		}
+	}
+	if bytes.Equal(expected, actual) {
+		return ""
+	}
+	if !utf8.Valid(expected) || !utf8.Valid(actual) {
+		return fmt.Sprintf("binary content differs: expected %d bytes, got %d bytes", len(expected), len(actual))
+	}
+	return diff(string(expected), string(actual))
+}
+
+// normalize re-encodes both documents so that field order and whitespace do not matter.
+// It returns false if any of them is not valid JSON.
+func normalize(expected, actual []byte) ([]byte, []byte, bool) {
+	var e, a interface{}
+	if json.Unmarshal(expected, &e) != nil || json.Unmarshal(actual, &a) != nil {
+		return nil, nil, false
+	}
+	// maps are encoded with sorted keys
+	normalizedExpected, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return nil, nil, false
+	}
+	normalizedActual, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, nil, false
+	}
+	return normalizedExpected, normalizedActual, true
+}
+
+// diff describes the first line that differs between the expected and actual text.
+func diff(expected, actual string) string {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var e, a string
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			a = actualLines[i]
+		}
+		if i >= len(expectedLines) || i >= len(actualLines) || e != a {
+			return fmt.Sprintf("line %d differs:\n- %s\n+ %s", i+1, e, a)
+		}
+	}
+	return "content differs"
+}
@@ -0,0 +1,66 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package golden
+
+import (
+	"mittens/internal/pkg/internal"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadGolden(t *testing.T, content string) *File {
+	path := internal.CreateTempFile(content)
+	t.Cleanup(func() { os.Remove(path) })
+
+	f, err := Load(path)
+	require.NoError(t, err)
+	return f
+}
+
+func TestCompareMatch(t *testing.T) {
+	f := loadGolden(t, "pong")
+	assert.Empty(t, f.Compare([]byte("pong"), false, false))
+}
+
+func TestCompareMismatch(t *testing.T) {
+	f := loadGolden(t, "a\nb\nc")
+	assert.Equal(t, "line 2 differs:\n- b\n+ x", f.Compare([]byte("a\nx\nc"), false, false))
+	assert.Equal(t, "line 4 differs:\n- \n+ d", f.Compare([]byte("a\nb\nc\nd"), false, false))
+}
+
+func TestCompareNormalizedJSON(t *testing.T) {
+	f := loadGolden(t, `{"a": 1, "b": [1, 2]}`)
+	assert.Empty(t, f.Compare([]byte(`{"b":[1,2],"a":1}`), false, true))
+	assert.NotEmpty(t, f.Compare([]byte(`{"b":[1,2],"a":1}`), false, false))
+	assert.NotEmpty(t, f.Compare([]byte(`{"b":[2,1],"a":1}`), false, true))
+}
+
+func TestCompareBinary(t *testing.T) {
+	f := loadGolden(t, "\xff\xfe\x00")
+	assert.Equal(t, "binary content differs: expected 3 bytes, got 2 bytes", f.Compare([]byte("\xff\xfe"), false, false))
+}
+
+func TestCompareTruncated(t *testing.T) {
+	f := loadGolden(t, "pong")
+	assert.NotEmpty(t, f.Compare([]byte("pong"), true, false))
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load("/does/not/exist")
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"context"
+)
+
+// authorizationCredentials sends the authorization metadata it returns with every call, so that it is refreshed
+// for every call instead of being set once when connecting.
+type authorizationCredentials func() (string, error)
+
+// GetRequestMetadata returns the authorization metadata of a call.
+func (a authorizationCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	authorization, err := a()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": authorization}, nil
+}
+
+// RequireTransportSecurity returns false as the targets of a warmup are often reached without TLS.
+func (a authorizationCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -16,23 +16,35 @@ package grpc
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"mittens/internal/pkg/placeholders"
 	"mittens/internal/pkg/response"
+	"net"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/fullstorydev/grpcurl"
 	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
 	"github.com/jhump/protoreflect/grpcreflect"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 	reflectpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 )
 
+// defaultDialTimeout bounds the time taken to connect to the target unless overridden by the dial timeout option.
+const defaultDialTimeout = time.Second
+
 // Client represents a gRPC client.
 type Client struct {
 	host             string
@@ -40,67 +52,198 @@ type Client struct {
 	connClose        func() error
 	conn             *grpc.ClientConn
 	descriptorSource grpcurl.DescriptorSource
+	options          ClientOptions
+	// pool is only set if the client connects with more than one connection
+	pool *connPool
+}
+
+// ClientOptions holds optional settings of the gRPC client.
+type ClientOptions struct {
+	// GetClientCertificate, if set, returns the certificate presented to targets that require mutual TLS.
+	GetClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	// RootCAs, if set, are the CA certificates used to verify the target instead of the system ones.
+	RootCAs *x509.CertPool
+	// DialTimeout, if greater than 0, bounds the time taken to connect to the target, and to open every connection
+	// of the pool, instead of defaultDialTimeout.
+	DialTimeout time.Duration
+	// DialContext, if set, is used to open the connection to the target.
+	DialContext func(ctx context.Context, network string, address string) (net.Conn, error)
+	// DialOptions are appended to the dial options built from the settings above when connecting.
+	// As they are applied last they can also override those settings, e.g. the transport credentials.
+	DialOptions []grpc.DialOption
+	// PoolSize, if greater than 1, is the number of connections opened by Connect. Requests use them in turn.
+	PoolSize int
+	// PoolHealthCheckInterval, if greater than 0, is the interval at which the failed connections of the pool are replaced.
+	PoolHealthCheckInterval time.Duration
+	// Format is the format of the request messages, FormatJSON or FormatText. It defaults to FormatJSON.
+	Format string
+	// DescriptorSource, if set, describes the services of the target instead of server reflection, see LoadDescriptorSource.
+	DescriptorSource grpcurl.DescriptorSource
+	// Authorization, if set, returns the value of the authorization metadata of every call, including the server reflection
+	// ones, e.g. a bearer token read from a file that is rotated. A call fails if it returns an error.
+	Authorization func() (string, error)
+	// SyntheticHeader, if set, is metadata in the `key: value` format sent with every call that does not set it,
+	// including the server reflection ones, so that the target and the observability tooling can tell the warmup
+	// traffic apart.
+	SyntheticHeader string
 }
 
 // eventHandler is a custom event handler with the option to enable/disable logging of responses.
 type eventHandler struct {
 	grpcurl.InvocationEventHandler
 	logResponses bool
+	formatter    grpcurl.Formatter
+	// captured, if set, receives the formatted response messages.
+	captured *bytes.Buffer
+	// received counts the response messages, the handler being copied by value
+	received *int
 }
 
 // NewClient returns a gRPC client.
-func NewClient(host string, insecure bool) Client {
-	return Client{host: host, insecure: insecure, connClose: func() error { return nil }}
+// It returns an error if the host is obviously invalid, as the client could never connect to it.
+func NewClient(host string, insecure bool, options ClientOptions) (Client, error) {
+	if strings.TrimSpace(host) == "" {
+		return Client{}, errors.New("gRPC host is empty")
+	}
+	return Client{host: host, insecure: insecure, connClose: func() error { return nil }, options: options}, nil
+}
+
+// Host returns the address, in host:port format, the client connects to.
+func (c *Client) Host() string {
+	return c.host
 }
 
 // Connect attempts to establish a connection with a gRPC server.
 func (c *Client) Connect(headers []string) error {
-	timeoutSeconds := 1 // TODO: make this configurable?
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), c.dialTimeout())
 
-	headersMetadata := grpcurl.MetadataFromHeaders(headers)
+	headersMetadata := grpcurl.MetadataFromHeaders(c.withSyntheticHeader(headers))
 	contextWithMetadata := metadata.NewOutgoingContext(ctx, headersMetadata)
 
 	dialOptions := []grpc.DialOption{grpc.WithBlock()}
 	if c.insecure {
 		dialOptions = append(dialOptions, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else if c.options.GetClientCertificate != nil || c.options.RootCAs != nil {
+		tlsConfig := &tls.Config{GetClientCertificate: c.options.GetClientCertificate, RootCAs: c.options.RootCAs}
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	if c.options.DialContext != nil {
+		dialContext := c.options.DialContext
+		dialOptions = append(dialOptions, grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return dialContext(ctx, "tcp", address)
+		}))
 	}
+	if c.options.Authorization != nil {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(authorizationCredentials(c.options.Authorization)))
+	}
+	dialOptions = append(dialOptions, c.options.DialOptions...)
 
 	conn, err := grpc.DialContext(ctx, c.host, dialOptions...)
 	if err != nil {
 		return fmt.Errorf("gRPC dial: %v", err)
 	}
 
-	reflectionClient := grpcreflect.NewClient(contextWithMetadata, reflectpb.NewServerReflectionClient(conn))
-	descriptorSource := grpcurl.DescriptorSourceFromServer(contextWithMetadata, reflectionClient)
+	descriptorSource := c.options.DescriptorSource
+	if descriptorSource == nil {
+		reflectionClient := grpcreflect.NewClient(contextWithMetadata, reflectpb.NewServerReflectionClient(conn))
+		descriptorSource = grpcurl.DescriptorSourceFromServer(contextWithMetadata, reflectionClient)
+	}
+
+	connClose := func() error { cancel(); return conn.Close() }
+	if c.options.PoolSize > 1 {
+		pool, err := newConnPool(conn, c.options.PoolSize, func() (*grpc.ClientConn, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), c.dialTimeout())
+			defer cancel()
+			return grpc.DialContext(ctx, c.host, dialOptions...)
+		})
+		if err != nil {
+			cancel()
+			return fmt.Errorf("gRPC connection pool: %v", err)
+		}
+		if c.options.PoolHealthCheckInterval > 0 {
+			pool.checkEvery(c.options.PoolHealthCheckInterval)
+		}
+		log.Printf("gRPC connection pool of %d connections opened", c.options.PoolSize)
+		c.pool = pool
+		connClose = func() error { cancel(); return pool.close() }
+	}
 
 	log.Print("gRPC client connected")
 	c.conn = conn
-	c.connClose = func() error { cancel(); return conn.Close() }
+	c.connClose = connClose
 	c.descriptorSource = descriptorSource
 	return nil
 }
 
+// withSyntheticHeader returns the headers with the synthetic header of the options first, unless they already set it.
+func (c *Client) withSyntheticHeader(headers []string) []string {
+	if c.options.SyntheticHeader == "" {
+		return headers
+	}
+	return MergeMetadata([]string{c.options.SyntheticHeader}, headers)
+}
+
+// dialTimeout returns the time allowed to connect to the target.
+func (c *Client) dialTimeout() time.Duration {
+	if c.options.DialTimeout > 0 {
+		return c.options.DialTimeout
+	}
+	return defaultDialTimeout
+}
+
+// connection returns the connection a request is sent on.
+func (c *Client) connection() *grpc.ClientConn {
+	if c.pool != nil {
+		return c.pool.get()
+	}
+	return c.conn
+}
+
+// PoolStats returns the health of the connection pool. It is false if the client is not connected with a pool.
+func (c *Client) PoolStats() (PoolStats, bool) {
+	if c.pool == nil {
+		return PoolStats{}, false
+	}
+	return c.pool.stats(), true
+}
+
 // SendRequest sends a request to the gRPC server and wraps useful information into a Response object.
 // Note that the message cannot be null. Even if there is no message to be sent this needs to be set to an empty string.
 func (c *Client) SendRequest(serviceMethod string, message string, headers []string, logResponses bool) response.Response {
+	return c.sendRequest(serviceMethod, message, headers, logResponses, 0)
+}
+
+// SendRequestCapturingBody works like SendRequest but also captures up to maxBodyBytes of the response messages formatted as JSON.
+func (c *Client) SendRequestCapturingBody(serviceMethod string, message string, headers []string, maxBodyBytes int) response.Response {
+	return c.sendRequest(serviceMethod, message, headers, false, maxBodyBytes)
+}
+
+func (c *Client) sendRequest(serviceMethod string, message string, headers []string, logResponses bool, maxBodyBytes int) response.Response {
 	const respType = "grpc"
-	in := bytes.NewBufferString(message)
+	in := bytes.NewBufferString(placeholders.InterpolatePlaceholders(message))
 
-	// TODO - create generic parser and formatter for any request, can we use text parser/formatter?
-	requestParser, formatter, err := grpcurl.RequestParserAndFormatter("json", c.descriptorSource, in, grpcurl.FormatOptions{})
+	format := c.format()
+	requestParser, formatter, err := grpcurl.RequestParserAndFormatter(grpcurl.Format(format), c.descriptorSource, in, grpcurl.FormatOptions{})
 	if err != nil {
-		log.Printf("Cannot construct request parser and formatter for json")
+		log.Printf("Cannot construct request parser and formatter for %s", format)
 		// FIXME FATAL
 		return response.Response{Duration: time.Duration(0), Err: err, Type: respType}
 	}
+	if format != FormatJSON {
+		// Responses are always logged and captured as JSON.
+		formatter = grpcurl.NewJSONFormatter(false, grpcurl.AnyResolverFromDescriptorSource(c.descriptorSource))
+	}
 
 	delegate := &grpcurl.DefaultEventHandler{
 		Out:       os.Stdout,
 		Formatter: formatter,
 	}
 
-	loggingEventHandler := eventHandler{InvocationEventHandler: delegate, logResponses: logResponses}
+	var received int
+	loggingEventHandler := eventHandler{InvocationEventHandler: delegate, logResponses: logResponses, formatter: formatter, received: &received}
+	if maxBodyBytes > 0 {
+		loggingEventHandler.captured = &bytes.Buffer{}
+	}
 	startTime := time.Now()
 
 	// Interpolate
@@ -108,25 +251,167 @@ func (c *Client) SendRequest(serviceMethod string, message string, headers []str
 	for i, header := range headers {
 		interpolatedHeaders[i] = placeholders.InterpolatePlaceholders(header)
 	}
+	interpolatedHeaders = c.withSyntheticHeader(interpolatedHeaders)
 
-	err = grpcurl.InvokeRPC(context.Background(), c.descriptorSource, c.conn, serviceMethod, interpolatedHeaders, loggingEventHandler, requestParser.Next)
+	err = grpcurl.InvokeRPC(context.Background(), c.descriptorSource, c.connection(), serviceMethod, interpolatedHeaders, loggingEventHandler, requestParser.Next)
 	endTime := time.Now()
 	if err != nil {
 		log.Printf("grpc response error: %s", err)
-		return response.Response{Duration: endTime.Sub(startTime), Err: nil, Type: respType}
+		return response.Response{Duration: endTime.Sub(startTime), Err: err, Type: respType}
+	}
+	result := response.Response{Duration: endTime.Sub(startTime), Type: respType, GrpcMessages: received}
+	if delegate.Status != nil {
+		result.GrpcStatus = delegate.Status.Code()
+	}
+	if loggingEventHandler.captured != nil {
+		result.Body = loggingEventHandler.captured.Bytes()
+		if len(result.Body) > maxBodyBytes {
+			result.Body = result.Body[:maxBodyBytes]
+			result.BodyTruncated = true
+		}
+	}
+	return result
+}
+
+// DiscoverMethods uses the descriptor source to list the methods of all the services that match the filters.
+// Methods are returned in the `<service>/<method>` format. The reflection services are always skipped.
+func (c *Client) DiscoverMethods(serviceFilters []string) ([]string, error) {
+	services, err := grpcurl.ListServices(c.descriptorSource)
+	if err != nil {
+		return nil, fmt.Errorf("list services: %v", err)
+	}
+
+	var matchedServices []string
+	var methods []string
+	for _, service := range services {
+		if strings.HasPrefix(service, reflectionServicePrefix) {
+			continue
+		}
+		matched, err := MatchesServiceFilters(service, serviceFilters)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			continue
+		}
+		serviceMethods, err := grpcurl.ListMethods(c.descriptorSource, service)
+		if err != nil {
+			return nil, fmt.Errorf("list methods for %s: %v", service, err)
+		}
+		for _, method := range serviceMethods {
+			// fully-qualified method names are in the <service>.<method> format
+			methods = append(methods, service+"/"+strings.TrimPrefix(method, service+"."))
+		}
+		matchedServices = append(matchedServices, service)
+	}
+
+	log.Printf("gRPC services matching %v: %v", serviceFilters, matchedServices)
+	return methods, nil
+}
+
+// DefaultMessage returns a minimally-valid request message, in the format of the client, for a method in the `<service>/<method>` format.
+func (c *Client) DefaultMessage(serviceMethod string) (string, error) {
+	method, err := c.findMethod(serviceMethod)
+	if err != nil {
+		return "", err
 	}
-	return response.Response{Duration: endTime.Sub(startTime), Err: nil, Type: respType}
+	return DefaultMessageInFormat(method.GetInputType(), c.format())
+}
+
+func (c *Client) format() string {
+	if c.options.Format == "" {
+		return FormatJSON
+	}
+	return c.options.Format
+}
+
+// UnknownMethods returns the methods, in the `<service>/<method>` format, that cannot be resolved via the descriptor source.
+func (c *Client) UnknownMethods(serviceMethods []string) []string {
+	var unknown []string
+	for _, serviceMethod := range serviceMethods {
+		if _, err := c.findMethod(serviceMethod); err != nil {
+			log.Printf("gRPC method %s cannot be resolved: %v", serviceMethod, err)
+			unknown = append(unknown, serviceMethod)
+		}
+	}
+	return unknown
+}
+
+// ValidateRequest checks, without sending it, that a method in the `<service>/<method>` format can be resolved via the
+// descriptor source and that the messages, once their placeholders are interpolated, are valid inputs of the method.
+func (c *Client) ValidateRequest(serviceMethod string, message string) error {
+	method, err := c.findMethod(serviceMethod)
+	if err != nil {
+		return err
+	}
+	in := strings.NewReader(placeholders.InterpolatePlaceholders(message))
+	requestParser, _, err := grpcurl.RequestParserAndFormatter(grpcurl.Format(c.format()), c.descriptorSource, in, grpcurl.FormatOptions{})
+	if err != nil {
+		return err
+	}
+	// an empty message is sent as the default instance of the input type, and client-streaming methods take several messages
+	for {
+		err := requestParser.Next(dynamic.NewMessage(method.GetInputType()))
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid message for %s: %v", serviceMethod, err)
+		}
+	}
+}
+
+// findMethod looks up the descriptor of a method in the `<service>/<method>` format.
+func (c *Client) findMethod(serviceMethod string) (*desc.MethodDescriptor, error) {
+	parts := strings.SplitN(serviceMethod, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid method: %s, expected format <service>/<method>", serviceMethod)
+	}
+	symbol, err := c.descriptorSource.FindSymbol(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("find service %s: %v", parts[0], err)
+	}
+	service, ok := symbol.(*desc.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", parts[0])
+	}
+	method := service.FindMethodByName(parts[1])
+	if method == nil {
+		return nil, fmt.Errorf("service %s does not have method %s", parts[0], parts[1])
+	}
+	return method, nil
 }
 
 // OnReceiveResponse overrides the default method and allows enabling/disabling logging of responses.
 func (h eventHandler) OnReceiveResponse(msg proto.Message) {
+	if h.received != nil {
+		*h.received++
+	}
+	if h.captured != nil {
+		if text, err := h.formatter(msg); err == nil {
+			if h.captured.Len() > 0 {
+				h.captured.WriteString("\n")
+			}
+			h.captured.WriteString(text)
+		}
+	}
 	if h.logResponses {
 		h.InvocationEventHandler.OnReceiveResponse(msg)
 	}
 }
 
+// Connected returns true if the client established a connection that has not been closed.
+func (c *Client) Connected() bool {
+	return c.conn != nil
+}
+
 // Close calling close on a client that has not established connection does not return an error.
-func (c Client) Close() error {
+// The client can connect again after being closed.
+func (c *Client) Close() error {
 	log.Print("Closing gRPC client connection")
-	return c.connClose()
+	err := c.connClose()
+	c.conn = nil
+	c.pool = nil
+	c.connClose = func() error { return nil }
+	return err
 }
@@ -0,0 +1,191 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"mittens/fixture"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// newClient returns a client of the host, which must be valid.
+func newClient(t *testing.T, host string, insecure bool, options ClientOptions) Client {
+	t.Helper()
+	client, err := NewClient(host, insecure, options)
+	require.NoError(t, err)
+	return client
+}
+
+func TestNewClientRejectsAnEmptyHost(t *testing.T) {
+	_, err := NewClient(" ", true, ClientOptions{})
+	assert.EqualError(t, err, "gRPC host is empty")
+}
+
+func connectToTestServer(t *testing.T) Client {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+
+	client := newClient(t, address, true, ClientOptions{})
+	require.NoError(t, client.Connect(nil))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestConnectDialTimeout(t *testing.T) {
+	// the listener accepts connections but never completes the HTTP/2 handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	client := newClient(t, listener.Addr().String(), true, ClientOptions{})
+	assert.Equal(t, defaultDialTimeout, client.dialTimeout())
+
+	client = newClient(t, listener.Addr().String(), true, ClientOptions{DialTimeout: 200 * time.Millisecond})
+	start := time.Now()
+	assert.Error(t, client.Connect(nil))
+	assert.Less(t, time.Since(start), defaultDialTimeout)
+}
+
+func TestSendRequestReturnsInvocationErrors(t *testing.T) {
+	client := connectToTestServer(t)
+
+	resp := client.SendRequest("grpc.testing.TestService/Typo", "", nil, false)
+	require.Error(t, resp.Err)
+	assert.Contains(t, resp.Err.Error(), "Typo")
+
+	resp = client.SendRequest("grpc.testing.TestService/UnaryCall", `{"unknown": 1}`, nil, false)
+	assert.Error(t, resp.Err)
+}
+
+func TestSendRequestRefreshesTheAuthorization(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+	var calls int
+	token := "valid"
+	client := newClient(t, address, true, ClientOptions{Authorization: func() (string, error) {
+		calls++
+		if token == "" {
+			return "", errors.New("no token")
+		}
+		return "Bearer " + token, nil
+	}})
+	require.NoError(t, client.Connect(nil))
+	defer client.Close()
+
+	resp := client.SendRequest("grpc.testing.TestService/EmptyCall", "", nil, false)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.OK, resp.GrpcStatus)
+	sent := calls
+	assert.Greater(t, sent, 0)
+
+	token = ""
+	resp = client.SendRequest("grpc.testing.TestService/EmptyCall", "", nil, false)
+	require.NoError(t, resp.Err)
+	assert.NotEqual(t, codes.OK, resp.GrpcStatus)
+	assert.Greater(t, calls, sent)
+}
+
+func TestConnectAppliesTheDialOptionsLast(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+	// the client is not insecure and has no TLS settings, so it can only connect with the credentials of the dial options
+	client := newClient(t, address, false, ClientOptions{DialOptions: []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}})
+	require.NoError(t, client.Connect(nil))
+	defer client.Close()
+
+	resp := client.SendRequest("grpc.testing.TestService/EmptyCall", "", nil, false)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.OK, resp.GrpcStatus)
+}
+
+func TestSendRequestWithTheSyntheticHeader(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+	var sent metadata.MD
+	capture := grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+	client := newClient(t, address, true, ClientOptions{SyntheticHeader: "X-Mittens-Warmup: true", DialOptions: []grpc.DialOption{capture}})
+	require.NoError(t, client.Connect(nil))
+	defer client.Close()
+
+	resp := client.SendRequest("grpc.testing.TestService/EmptyCall", "", []string{"x-tenant: acme"}, false)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, []string{"true"}, sent.Get("x-mittens-warmup"))
+	assert.Equal(t, []string{"acme"}, sent.Get("x-tenant"))
+
+	client.SendRequest("grpc.testing.TestService/EmptyCall", "", []string{"X-Mittens-Warmup: replay"}, false)
+	assert.Equal(t, []string{"replay"}, sent.Get("x-mittens-warmup"))
+}
+
+func TestSendRequestReturnsTheGrpcStatus(t *testing.T) {
+	client := connectToTestServer(t)
+
+	resp := client.SendRequest("grpc.testing.TestService/EmptyCall", "", nil, false)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.OK, resp.GrpcStatus)
+
+	resp = client.SendRequest("grpc.testing.TestService/UnaryCall", "", nil, false)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.Unimplemented, resp.GrpcStatus)
+}
+
+func TestSendRequestInterpolatesPlaceholders(t *testing.T) {
+	client := connectToTestServer(t)
+
+	// the error names the field, which shows that the placeholder was resolved before the message was parsed
+	resp := client.SendRequest("grpc.testing.TestService/UnaryCall", `{"{$random|unknown}": 1}`, nil, false)
+	require.Error(t, resp.Err)
+	assert.Contains(t, resp.Err.Error(), "no known field named unknown")
+}
+
+func TestValidateRequest(t *testing.T) {
+	client := connectToTestServer(t)
+
+	assert.NoError(t, client.ValidateRequest("grpc.testing.TestService/EmptyCall", ""))
+	assert.NoError(t, client.ValidateRequest("grpc.testing.TestService/UnaryCall", `{"responseSize": {$range|min=1,max=10}}`))
+	assert.ErrorContains(t, client.ValidateRequest("grpc.testing.TestService/Typo", ""), "does not have method Typo")
+	assert.ErrorContains(t, client.ValidateRequest("grpc.testing.Typo/EmptyCall", ""), "grpc.testing.Typo")
+	assert.ErrorContains(t, client.ValidateRequest("grpc.testing.TestService/UnaryCall", `{"unknown": 1}`), "invalid message")
+	assert.NoError(t, client.ValidateRequest("grpc.testing.TestService/StreamingInputCall", `{"payload": {}} {"payload": {}}`))
+	assert.ErrorContains(t, client.ValidateRequest("grpc.testing.TestService/StreamingInputCall", `{"payload": {}} {"unknown": 1}`), "invalid message")
+}
+
+func TestSendRequestStreams(t *testing.T) {
+	client := connectToTestServer(t)
+
+	// the response stream is drained and timed until its end
+	resp := client.SendRequestCapturingBody("grpc.testing.TestService/StreamingOutputCall",
+		`{"responseParameters": [{"size": 1}, {"size": 2, "intervalUs": 100000}, {"size": 3}]}`, nil, 1024)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.OK, resp.GrpcStatus)
+	assert.Equal(t, 3, resp.GrpcMessages)
+	assert.GreaterOrEqual(t, resp.Duration, 100*time.Millisecond)
+
+	// every message is sent on the request stream
+	resp = client.SendRequestCapturingBody("grpc.testing.TestService/StreamingInputCall",
+		`{"payload": {"body": "YWJj"}} {"payload": {"body": "ZGU="}}`, nil, 1024)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.OK, resp.GrpcStatus)
+	assert.Contains(t, string(resp.Body), `"aggregatedPayloadSize": 5`)
+}
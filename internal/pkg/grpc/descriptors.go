@@ -0,0 +1,95 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	_ "google.golang.org/grpc/health/grpc_health_v1" // registers the health-check service descriptor
+)
+
+// healthProtoFile is the name of the descriptor of the standard gRPC health-check service.
+const healthProtoFile = "grpc/health/v1/health.proto"
+
+// LoadDescriptorSource returns the descriptors of the services and messages defined in the .proto files, found in
+// importPaths, and in the compiled file descriptor sets (protosets). The standard health-check service is always
+// included so that readiness checks keep working against targets without server reflection.
+// It returns nil if no files are given, in which case the descriptors are fetched from the target via server reflection.
+func LoadDescriptorSource(importPaths []string, protoFiles []string, protosetFiles []string) (grpcurl.DescriptorSource, error) {
+	if len(protoFiles) == 0 && len(protosetFiles) == 0 {
+		return nil, nil
+	}
+	files := make(map[string]*desc.FileDescriptor)
+	if len(protoFiles) > 0 {
+		names, err := protoparse.ResolveFilenames(importPaths, protoFiles...)
+		if err != nil {
+			return nil, err
+		}
+		parser := protoparse.Parser{ImportPaths: importPaths, InferImportPaths: len(importPaths) == 0}
+		parsed, err := parser.ParseFiles(names...)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse proto files: %v", err)
+		}
+		for _, fd := range parsed {
+			files[fd.GetName()] = fd
+		}
+	}
+	if len(protosetFiles) > 0 {
+		// the protosets are merged first as they commonly share dependencies, e.g. the well-known types
+		merged := &dpb.FileDescriptorSet{}
+		seen := make(map[string]bool)
+		for _, protosetFile := range protosetFiles {
+			b, err := os.ReadFile(protosetFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read protoset file %s: %v", protosetFile, err)
+			}
+			var set dpb.FileDescriptorSet
+			if err := proto.Unmarshal(b, &set); err != nil {
+				return nil, fmt.Errorf("cannot parse protoset file %s: %v", protosetFile, err)
+			}
+			for _, fd := range set.File {
+				if !seen[fd.GetName()] {
+					seen[fd.GetName()] = true
+					merged.File = append(merged.File, fd)
+				}
+			}
+		}
+		fds, err := desc.CreateFileDescriptorsFromSet(merged)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protoset files: %v", err)
+		}
+		for name, fd := range fds {
+			files[name] = fd
+		}
+	}
+	if _, ok := files[healthProtoFile]; !ok {
+		health, err := desc.LoadFileDescriptor(healthProtoFile)
+		if err != nil {
+			return nil, err
+		}
+		files[healthProtoFile] = health
+	}
+	fds := make([]*desc.FileDescriptor, 0, len(files))
+	for _, fd := range files {
+		fds = append(fds, fd)
+	}
+	return grpcurl.DescriptorSourceFromFileDescriptors(fds...)
+}
@@ -0,0 +1,109 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mittens/fixture"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/test/grpc_testing"
+)
+
+const testServiceProto = `syntax = "proto3";
+package grpc.testing;
+message Empty {}
+service TestService {
+  rpc EmptyCall(Empty) returns (Empty);
+}
+`
+
+func startTestServerWithoutReflection(t *testing.T) string {
+	return fixture.StartGrpcTargetTestServerWithoutReflectionOnFreePort(t)
+}
+
+func connectWithDescriptors(t *testing.T, host string, importPaths []string, protoFiles []string, protosetFiles []string) Client {
+	source, err := LoadDescriptorSource(importPaths, protoFiles, protosetFiles)
+	require.NoError(t, err)
+	client := newClient(t, host, true, ClientOptions{DescriptorSource: source})
+	require.NoError(t, client.Connect(nil))
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestLoadDescriptorSource_WithoutFiles(t *testing.T) {
+	source, err := LoadDescriptorSource(nil, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, source)
+}
+
+func TestLoadDescriptorSource_InvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	invalidProto := filepath.Join(dir, "invalid.proto")
+	require.NoError(t, os.WriteFile(invalidProto, []byte("service {"), 0644))
+	_, err := LoadDescriptorSource([]string{dir}, []string{"invalid.proto"}, nil)
+	assert.ErrorContains(t, err, "cannot parse proto files")
+
+	_, err = LoadDescriptorSource(nil, nil, []string{filepath.Join(dir, "missing.protoset")})
+	assert.ErrorContains(t, err, "cannot read protoset file")
+}
+
+func TestSendRequestWithProtoFiles(t *testing.T) {
+	host := startTestServerWithoutReflection(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test.proto"), []byte(testServiceProto), 0644))
+
+	// without reflection the method is unknown
+	client := newClient(t, host, true, ClientOptions{})
+	require.NoError(t, client.Connect(nil))
+	defer client.Close()
+	assert.Error(t, client.SendRequest("grpc.testing.TestService/EmptyCall", "", nil, false).Err)
+
+	client = connectWithDescriptors(t, host, []string{dir}, []string{"test.proto"}, nil)
+	resp := client.SendRequest("grpc.testing.TestService/EmptyCall", "", nil, false)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.OK, resp.GrpcStatus)
+	// the health-check service is always known
+	resp = client.SendRequest("grpc.health.v1.Health/Check", "", nil, false)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.OK, resp.GrpcStatus)
+	methods, err := client.DiscoverMethods(nil)
+	require.NoError(t, err)
+	assert.Contains(t, methods, "grpc.testing.TestService/EmptyCall")
+}
+
+func TestSendRequestWithProtosetFiles(t *testing.T) {
+	host := startTestServerWithoutReflection(t)
+	fd, err := desc.LoadFileDescriptor("test/grpc_testing/test.proto")
+	require.NoError(t, err)
+	set := &dpb.FileDescriptorSet{File: []*dpb.FileDescriptorProto{fd.AsFileDescriptorProto()}}
+	b, err := proto.Marshal(set)
+	require.NoError(t, err)
+	protoset := filepath.Join(t.TempDir(), "test.protoset")
+	require.NoError(t, os.WriteFile(protoset, b, 0644))
+
+	client := connectWithDescriptors(t, host, nil, nil, []string{protoset, protoset})
+	resp := client.SendRequest("grpc.testing.TestService/UnaryCall", `{"responseSize": 1}`, nil, false)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, codes.Unimplemented, resp.GrpcStatus)
+}
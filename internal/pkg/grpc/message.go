@@ -0,0 +1,91 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+// maxDefaultMessageDepth bounds the nesting of required message fields, which could otherwise recurse forever.
+const maxDefaultMessageDepth = 32
+
+// Formats in which request messages can be written.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// ValidateFormat returns an error if the request message format is not supported.
+func ValidateFormat(format string) error {
+	if format != FormatJSON && format != FormatText {
+		return fmt.Errorf("gRPC request format %s not supported, please use %s or %s", format, FormatJSON, FormatText)
+	}
+	return nil
+}
+
+// DefaultMessage returns the JSON of a minimally-valid message of the given type.
+// All fields are left unset apart from proto2 required fields, which are set to their default values.
+func DefaultMessage(md *desc.MessageDescriptor) (string, error) {
+	return DefaultMessageInFormat(md, FormatJSON)
+}
+
+// DefaultMessageInFormat is like DefaultMessage but writes the message in the given format.
+func DefaultMessageInFormat(md *desc.MessageDescriptor, format string) (string, error) {
+	msg, err := defaultMessage(md, 0)
+	if err != nil {
+		return "", err
+	}
+	var out []byte
+	if format == FormatText {
+		out, err = msg.MarshalText()
+	} else {
+		out, err = msg.MarshalJSONPB(&jsonpb.Marshaler{})
+	}
+	if err != nil {
+		return "", fmt.Errorf("marshal default message for %s: %v", md.GetFullyQualifiedName(), err)
+	}
+	return string(out), nil
+}
+
+func defaultMessage(md *desc.MessageDescriptor, depth int) (*dynamic.Message, error) {
+	if depth > maxDefaultMessageDepth {
+		return nil, fmt.Errorf("required fields of %s are nested too deeply", md.GetFullyQualifiedName())
+	}
+
+	msg := dynamic.NewMessage(md)
+	for _, fd := range md.GetFields() {
+		if !fd.IsRequired() {
+			continue
+		}
+		var value interface{}
+		if fd.GetMessageType() != nil {
+			nested, err := defaultMessage(fd.GetMessageType(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			value = nested
+		} else {
+			value = fd.GetDefaultValue()
+		}
+		if err := msg.TrySetField(fd, value); err != nil {
+			return nil, fmt.Errorf("set required field %s: %v", fd.GetFullyQualifiedName(), err)
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,97 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProto = `
+syntax = "proto2";
+package test;
+
+enum Kind {
+  KIND_A = 1;
+  KIND_B = 2;
+}
+
+message Inner {
+  required int32 id = 1;
+  optional string note = 2;
+}
+
+message Request {
+  required string name = 1;
+  required Kind kind = 2;
+  required Inner inner = 3;
+  required int64 limit = 4 [default = 10];
+  optional string ignored = 5;
+  repeated string tags = 6;
+}
+
+message Empty {
+  optional string ignored = 1;
+}
+
+message Loop {
+  required Loop loop = 1;
+}
+`
+
+func findMessage(t *testing.T, name string) *desc.MessageDescriptor {
+	parser := protoparse.Parser{Accessor: protoparse.FileContentsFromMap(map[string]string{"test.proto": testProto})}
+	files, err := parser.ParseFiles("test.proto")
+	require.NoError(t, err)
+	md := files[0].FindMessage(name)
+	require.NotNil(t, md)
+	return md
+}
+
+func TestDefaultMessageSetsRequiredFields(t *testing.T) {
+	message, err := DefaultMessage(findMessage(t, "test.Request"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "", "kind": "KIND_A", "inner": {"id": 0}, "limit": "10"}`, message)
+}
+
+func TestDefaultMessageWithoutRequiredFields(t *testing.T) {
+	message, err := DefaultMessage(findMessage(t, "test.Empty"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, message)
+}
+
+func TestDefaultMessageRecursiveRequiredFields(t *testing.T) {
+	_, err := DefaultMessage(findMessage(t, "test.Loop"))
+	require.Error(t, err)
+}
+
+func TestDefaultMessageInTextFormat(t *testing.T) {
+	message, err := DefaultMessageInFormat(findMessage(t, "test.Request"), FormatText)
+	require.NoError(t, err)
+	assert.Contains(t, message, `name:""`)
+	assert.Contains(t, message, `kind:KIND_A`)
+	assert.Contains(t, message, `inner:<id:0>`)
+	assert.Contains(t, message, `limit:10`)
+}
+
+func TestValidateFormat(t *testing.T) {
+	assert.NoError(t, ValidateFormat(FormatJSON))
+	assert.NoError(t, ValidateFormat(FormatText))
+	assert.Error(t, ValidateFormat("yaml"))
+}
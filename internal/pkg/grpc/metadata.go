@@ -0,0 +1,102 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// metadataKeyRegex matches the valid gRPC metadata keys once lowercased.
+var metadataKeyRegex = regexp.MustCompile(`^[0-9a-z_.-]+$`)
+
+// LoadMetadataFile reads gRPC metadata from a file with a `key: value` entry per line.
+// Blank lines and lines starting with # are ignored. Keys are lowercased and may be repeated.
+// Values of binary keys, i.e. with the -bin suffix, must be base64 encoded.
+// It returns the metadata in the same `key: value` format as the headers.
+func LoadMetadataFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open gRPC metadata file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var metadata []string
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.SplitN(text, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid gRPC metadata file %s, line %d: expected format <key>: <value>", path, line)
+		}
+		key := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+		if !metadataKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid gRPC metadata file %s, line %d: invalid key %q", path, line, key)
+		}
+		if strings.HasSuffix(key, "-bin") && !isBase64(value) {
+			return nil, fmt.Errorf("invalid gRPC metadata file %s, line %d: the value of binary key %s must be base64 encoded", path, line, key)
+		}
+		metadata = append(metadata, fmt.Sprintf("%s: %s", key, value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read gRPC metadata file %s: %v", path, err)
+	}
+	return metadata, nil
+}
+
+// MergeMetadata returns the global metadata followed by the metadata of a request, both in the `key: value` format.
+// The global entries whose key is also set by the request are dropped so that the request overrides them.
+func MergeMetadata(global []string, request []string) []string {
+	if len(request) == 0 {
+		return global
+	}
+	overridden := make(map[string]bool, len(request))
+	for _, entry := range request {
+		overridden[metadataKey(entry)] = true
+	}
+	merged := make([]string, 0, len(global)+len(request))
+	for _, entry := range global {
+		if !overridden[metadataKey(entry)] {
+			merged = append(merged, entry)
+		}
+	}
+	return append(merged, request...)
+}
+
+// metadataKey returns the lowercased key of a `key: value` entry.
+func metadataKey(entry string) string {
+	key, _, _ := strings.Cut(entry, ":")
+	return strings.ToLower(strings.TrimSpace(key))
+}
+
+// isBase64 returns true if the value can be decoded with any of the base64 flavours accepted by grpcurl.
+func isBase64(value string) bool {
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if _, err := encoding.DecodeString(value); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeMetadataFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "metadata")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadMetadataFile(t *testing.T) {
+	path := writeMetadataFile(t, `
+# routing
+X-Tenant: acme
+x-request-id: {$uuid}
+x-roles: reader
+x-roles: writer
+trace-bin: AAEC
+`)
+
+	metadata, err := LoadMetadataFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x-tenant: acme", "x-request-id: {$uuid}", "x-roles: reader", "x-roles: writer", "trace-bin: AAEC"}, metadata)
+
+	md := grpcurl.MetadataFromHeaders(metadata)
+	assert.Equal(t, []string{"reader", "writer"}, md["x-roles"])
+	assert.Equal(t, []string{"\x00\x01\x02"}, md["trace-bin"])
+}
+
+func TestLoadMetadataFile_Invalid(t *testing.T) {
+	for _, content := range []string{"x-tenant", "x tenant: acme", "trace-bin: not base64!"} {
+		_, err := LoadMetadataFile(writeMetadataFile(t, "x-ok: 1\n"+content))
+		require.Error(t, err, content)
+		assert.Contains(t, err.Error(), "line 2")
+	}
+
+	_, err := LoadMetadataFile(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestMergeMetadata(t *testing.T) {
+	global := []string{"Authorization: Bearer global", "x-tenant: acme", "x-roles: reader"}
+
+	merged := MergeMetadata(global, []string{"authorization: Bearer orders", "x-roles: writer", "x-roles: admin"})
+
+	assert.Equal(t, []string{"x-tenant: acme", "authorization: Bearer orders", "x-roles: writer", "x-roles: admin"}, merged)
+	assert.Equal(t, []string{"Bearer orders"}, grpcurl.MetadataFromHeaders(merged)["authorization"])
+	assert.Equal(t, global, MergeMetadata(global, nil))
+}
+
+func TestToGrpcRequestWithMetadataFile(t *testing.T) {
+	path := writeMetadataFile(t, "authorization: Bearer orders\n")
+
+	request, err := ToGrpcRequest("[metadata-file=" + path + "]orders.Orders/List")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"authorization: Bearer orders"}, request.Headers)
+
+	_, err = ToGrpcRequest("[metadata-file=" + filepath.Join(t.TempDir(), "missing") + "]orders.Orders/List")
+	assert.Error(t, err)
+}
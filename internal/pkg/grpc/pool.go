@@ -0,0 +1,163 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// PoolStats describes the health of the connection pool of a client.
+type PoolStats struct {
+	// Size is the number of connections in the pool.
+	Size int
+	// Healthy is the number of connections that were not failing at the time of the call.
+	Healthy int
+	// Replaced is the number of dead connections replaced by the health checker so far.
+	Replaced int
+}
+
+// connPool is a fixed-size set of connections shared by all the workers, which pick them in turn.
+// A health checker replaces the connections that failed. It is safe for concurrent use.
+type connPool struct {
+	dial func() (*grpc.ClientConn, error)
+	// primary is used by the reflection client so it is only closed along with the pool, even once replaced.
+	primary  *grpc.ClientConn
+	mu       sync.RWMutex
+	conns    []*grpc.ClientConn
+	replaced int
+	next     uint64
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+}
+
+// newConnPool returns a pool of size connections, including primary, opening the missing ones with dial.
+func newConnPool(primary *grpc.ClientConn, size int, dial func() (*grpc.ClientConn, error)) (*connPool, error) {
+	p := &connPool{dial: dial, primary: primary, conns: []*grpc.ClientConn{primary}, stop: make(chan struct{})}
+	for len(p.conns) < size {
+		conn, err := dial()
+		if err != nil {
+			p.close()
+			return nil, err
+		}
+		p.conns = append(p.conns, conn)
+	}
+	return p, nil
+}
+
+// get returns the next connection of the pool.
+func (p *connPool) get() *grpc.ClientConn {
+	n := atomic.AddUint64(&p.next, 1)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.conns[n%uint64(len(p.conns))]
+}
+
+// checkEvery checks the health of the connections at every interval until the pool is closed.
+func (p *connPool) checkEvery(interval time.Duration) {
+	p.stopped.Add(1)
+	go func() {
+		defer p.stopped.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.check()
+			}
+		}
+	}()
+}
+
+// check replaces the connections that are failing or were shut down with new ones.
+// Connections that cannot be replaced are kept: gRPC keeps trying to reconnect them.
+func (p *connPool) check() {
+	for i := 0; i < p.size(); i++ {
+		p.mu.RLock()
+		conn := p.conns[i]
+		p.mu.RUnlock()
+		if healthy(conn) {
+			continue
+		}
+		replacement, err := p.dial()
+		if err != nil {
+			log.Printf("Cannot replace failed gRPC connection: %v", err)
+			continue
+		}
+		p.mu.Lock()
+		p.conns[i] = replacement
+		p.replaced++
+		p.mu.Unlock()
+		if conn != p.primary {
+			conn.Close()
+		}
+		log.Print("Replaced failed gRPC connection")
+	}
+}
+
+func (p *connPool) size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.conns)
+}
+
+func (p *connPool) stats() PoolStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	stats := PoolStats{Size: len(p.conns), Replaced: p.replaced}
+	for _, conn := range p.conns {
+		if healthy(conn) {
+			stats.Healthy++
+		}
+	}
+	return stats
+}
+
+// close stops the health checker and closes all the connections.
+func (p *connPool) close() error {
+	close(p.stop)
+	p.stopped.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	closedPrimary := false
+	for _, conn := range p.conns {
+		if conn == p.primary {
+			closedPrimary = true
+		}
+		if closeErr := conn.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	if !closedPrimary {
+		if closeErr := p.primary.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// healthy returns false if the connection is failing or was shut down.
+func healthy(conn *grpc.ClientConn) bool {
+	state := conn.GetState()
+	return state != connectivity.TransientFailure && state != connectivity.Shutdown
+}
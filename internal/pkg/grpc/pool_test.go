@@ -0,0 +1,65 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// dialLazily returns connections that are only opened when used, to a port nothing listens on.
+func dialLazily() (*grpc.ClientConn, error) {
+	return grpc.Dial("localhost:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+func TestConnPoolUsesConnectionsInTurn(t *testing.T) {
+	primary, err := dialLazily()
+	require.NoError(t, err)
+	pool, err := newConnPool(primary, 3, dialLazily)
+	require.NoError(t, err)
+	defer pool.close()
+
+	seen := map[*grpc.ClientConn]int{}
+	for i := 0; i < 6; i++ {
+		seen[pool.get()]++
+	}
+
+	assert.Len(t, seen, 3)
+	for _, count := range seen {
+		assert.Equal(t, 2, count)
+	}
+	assert.Equal(t, 2, seen[primary])
+}
+
+func TestConnPoolReplacesShutDownConnections(t *testing.T) {
+	primary, err := dialLazily()
+	require.NoError(t, err)
+	pool, err := newConnPool(primary, 2, dialLazily)
+	require.NoError(t, err)
+	defer pool.close()
+
+	dead := pool.conns[1]
+	require.NoError(t, dead.Close())
+	assert.Equal(t, PoolStats{Size: 2, Healthy: 1}, pool.stats())
+
+	pool.check()
+
+	assert.NotSame(t, dead, pool.conns[1])
+	assert.Equal(t, PoolStats{Size: 2, Healthy: 2, Replaced: 1}, pool.stats())
+}
@@ -16,35 +16,99 @@ package grpc
 
 import (
 	"fmt"
+	"mittens/internal/pkg/golden"
 	"mittens/internal/pkg/placeholders"
+	"mittens/internal/pkg/requestoptions"
+	"path"
 	"strings"
+	"time"
 )
 
+// reflectionServicePrefix is the package of the server reflection services. These are never warmed up.
+const reflectionServicePrefix = "grpc.reflection."
+
 // Request represents a gRPC request.
 type Request struct {
 	ServiceMethod string
-	Message       string
+	// Message holds the messages sent on the request stream. Client-streaming methods are sent every message one after the
+	// other, e.g. `{"id": 1} {"id": 2}` in JSON or messages separated by the 0x1E record separator in text format.
+	Message string
+	// Burst is the number of times the request is sent back-to-back every time it is selected.
+	Burst int
+	// Golden, if set, holds the expected response body.
+	Golden *golden.File
+	// MaxLatency, if greater than 0, is the latency above which a successful response violates the latency SLO of the request.
+	MaxLatency time.Duration
+	// Weight is how often the request is selected compared to the others. Weights lower than 1 count as 1.
+	Weight int
+	// Headers is the metadata sent with this request only, in the `key: value` format. It overrides the global metadata with the same keys.
+	Headers []string
 }
 
 // ToGrpcRequest parses a gRPC request which is in a string format and stores it in a struct.
 func ToGrpcRequest(requestFlag string) (Request, error) {
+	options, rest, err := requestoptions.Parse(requestFlag, requestoptions.Burst, requestoptions.Golden, requestoptions.MaxLatency, requestoptions.Weight, requestoptions.MetadataFile)
+	if err != nil {
+		return Request{}, err
+	}
+	burst, err := options.PositiveInt(requestoptions.Burst, 1)
+	if err != nil {
+		return Request{}, err
+	}
+	goldenFile, err := options.GoldenFile()
+	if err != nil {
+		return Request{}, err
+	}
+	maxLatency, err := options.PositiveDuration(requestoptions.MaxLatency)
+	if err != nil {
+		return Request{}, err
+	}
+	weight, err := options.PositiveInt(requestoptions.Weight, 1)
+	if err != nil {
+		return Request{}, err
+	}
+	var headers []string
+	if path, ok := options[requestoptions.MetadataFile]; ok {
+		if headers, err = LoadMetadataFile(path); err != nil {
+			return Request{}, err
+		}
+	}
 
 	// service/method[:message]
-	parts := strings.SplitN(requestFlag, ":", 2)
+	parts := strings.SplitN(rest, ":", 2)
 	if len(strings.Split(parts[0], "/")) != 2 {
 		return Request{}, fmt.Errorf("invalid request flag: %s, expected format <service>/<method>[:body]", requestFlag)
 	}
 
-	request := Request{ServiceMethod: parts[0]}
+	request := Request{ServiceMethod: parts[0], Burst: burst, Golden: goldenFile, MaxLatency: maxLatency, Weight: weight, Headers: headers}
 	if len(parts) == 2 {
 		// the body of the request can either be inlined, or come from a file
 		rawBody, err := placeholders.GetBodyFromFileOrInlined(parts[1])
 		if err != nil {
 			return Request{}, fmt.Errorf("unable to parse body for request: %s", parts[1])
 		}
-		request.Message = placeholders.InterpolatePlaceholders(*rawBody)
+		// placeholders are interpolated every time the request is sent
+		request.Message = *rawBody
 	} else {
 		request.Message = ""
 	}
 	return request, nil
 }
+
+// MatchesServiceFilters returns true if a fully-qualified service name matches any of the filters.
+// Filters use shell glob syntax, e.g. `com.example.search.*`. If there are no filters every service matches.
+func MatchesServiceFilters(service string, filters []string) (bool, error) {
+	if len(filters) == 0 {
+		return true, nil
+	}
+	for _, filter := range filters {
+		matched, err := path.Match(filter, service)
+		if err != nil {
+			return false, fmt.Errorf("invalid service filter: %s, %v", filter, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -17,7 +17,6 @@ package grpc
 import (
 	"mittens/internal/pkg/internal"
 	"os"
-	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -57,6 +56,24 @@ func TestGrpc_FlagWithoutBodyToGrpcRequest(t *testing.T) {
 	assert.Equal(t, "", request.Message)
 }
 
+func TestGrpc_FlagWithBurstToGrpcRequest(t *testing.T) {
+	requestFlag := `[burst=2]health/ping:{"db": "true"}`
+	request, err := ToGrpcRequest(requestFlag)
+	require.NoError(t, err)
+
+	assert.Equal(t, "health/ping", request.ServiceMethod)
+	assert.Equal(t, `{"db": "true"}`, request.Message)
+	assert.Equal(t, 2, request.Burst)
+}
+
+func TestGrpc_FlagWithWeightToGrpcRequest(t *testing.T) {
+	request, err := ToGrpcRequest(`[weight=3]health/ping`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "health/ping", request.ServiceMethod)
+	assert.Equal(t, 3, request.Weight)
+}
+
 func TestGrpc_InvalidFlagToGrpcRequest(t *testing.T) {
 
 	requestFlag := `health:ping`
@@ -64,13 +81,35 @@ func TestGrpc_InvalidFlagToGrpcRequest(t *testing.T) {
 	require.Error(t, err)
 }
 
-func TestGrpc_Interpolation(t *testing.T) {
-	requestFlag := `health/ping:{"lorem": "{$random|foo}", "ipsum":"{$random|foo}"}"}`
+func TestGrpc_PlaceholdersAreKeptUntilSent(t *testing.T) {
+	requestFlag := `health/ping:{"lorem": "{$random|foo}", "ipsum":"{$random|foo}"}`
 	request, err := ToGrpcRequest(requestFlag)
 	require.NoError(t, err)
 
-	var pathRegex = regexp.MustCompile(`{"lorem": "(foo|bar)", "ipsum":"(foo|bar)"}`)
-	matchRequest := pathRegex.MatchString(request.Message)
+	assert.Equal(t, `{"lorem": "{$random|foo}", "ipsum":"{$random|foo}"}`, request.Message)
+}
+
+func TestGrpc_MatchesServiceFilters(t *testing.T) {
+	matched, err := MatchesServiceFilters("com.example.search.Search", []string{"com.example.search.*"})
+	require.NoError(t, err)
+	assert.True(t, matched)
+
+	matched, err = MatchesServiceFilters("com.example.booking.Booking", []string{"com.example.search.*"})
+	require.NoError(t, err)
+	assert.False(t, matched)
+
+	matched, err = MatchesServiceFilters("com.example.booking.Booking", []string{"com.example.search.*", "*.Booking"})
+	require.NoError(t, err)
+	assert.True(t, matched)
+}
+
+func TestGrpc_MatchesServiceFiltersWithoutFilters(t *testing.T) {
+	matched, err := MatchesServiceFilters("com.example.search.Search", nil)
+	require.NoError(t, err)
+	assert.True(t, matched)
+}
 
-	assert.True(t, matchRequest)
+func TestGrpc_MatchesServiceFiltersInvalidFilter(t *testing.T) {
+	_, err := MatchesServiceFilters("com.example.search.Search", []string{"com.example.[search"})
+	require.Error(t, err)
 }
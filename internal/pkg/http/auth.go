@@ -0,0 +1,30 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"encoding/base64"
+)
+
+// BasicAuthHeader returns the Authorization header of basic auth with the given credentials, base64-encoded.
+func BasicAuthHeader(username string, password string) string {
+	return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// BearerAuthHeader returns the Authorization header of a bearer token. The token is not encoded so that its placeholders
+// are still interpolated when the header is sent.
+func BearerAuthHeader(token string) string {
+	return "Authorization: Bearer " + token
+}
@@ -0,0 +1,50 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// bodyFiles caches the content of the body files by path so that every file is only read once, however many
+// requests send it.
+var bodyFiles = struct {
+	sync.Mutex
+	bodies map[string]string
+}{bodies: make(map[string]string)}
+
+// WithBodyFromFile returns the request with its body read from BodyFile, if set and the request has no inline body.
+// The file is read the first time and cached for all the next requests. Placeholders in its content are interpolated
+// when the request is sent, like those of inline bodies.
+func (r Request) WithBodyFromFile() (Request, error) {
+	if r.BodyFile == "" || r.Body != nil {
+		return r, nil
+	}
+	bodyFiles.Lock()
+	defer bodyFiles.Unlock()
+	body, ok := bodyFiles.bodies[r.BodyFile]
+	if !ok {
+		content, err := os.ReadFile(r.BodyFile)
+		if err != nil {
+			return r, fmt.Errorf("cannot read body file: %v", err)
+		}
+		body = string(content)
+		bodyFiles.bodies[r.BodyFile] = body
+	}
+	r.Body = &body
+	return r, nil
+}
@@ -0,0 +1,48 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithBodyFromFile(t *testing.T) {
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	require.NoError(t, os.WriteFile(bodyFile, []byte(`{"id": 1}`), 0644))
+
+	request, err := Request{Method: "POST", Path: "/", BodyFile: bodyFile}.WithBodyFromFile()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id": 1}`, *request.Body)
+
+	// the file is only read once
+	require.NoError(t, os.WriteFile(bodyFile, []byte(`{"id": 2}`), 0644))
+	request, err = Request{Method: "POST", Path: "/", BodyFile: bodyFile}.WithBodyFromFile()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id": 1}`, *request.Body)
+
+	// inline bodies take precedence
+	inline := "inline"
+	request, err = Request{Method: "POST", Path: "/", Body: &inline, BodyFile: bodyFile}.WithBodyFromFile()
+	require.NoError(t, err)
+	assert.Equal(t, "inline", *request.Body)
+
+	_, err = Request{Method: "POST", Path: "/", BodyFile: filepath.Join(t.TempDir(), "missing.json")}.WithBodyFromFile()
+	assert.ErrorContains(t, err, "cannot read body file")
+}
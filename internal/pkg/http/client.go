@@ -16,7 +16,10 @@ package http
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -24,68 +27,396 @@ import (
 	"mittens/internal/pkg/placeholders"
 	"mittens/internal/pkg/response"
 	"mittens/internal/pkg/util"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
 
+// defaultTimeout bounds every request unless overridden by the read or write timeouts.
+const defaultTimeout = 10 * time.Second
+
 // Client is a wrapper for the HTTP Client which includes a host.
 type Client struct {
 	httpClient *http.Client
+	transport  *http.Transport
 	host       string
+	options    ClientOptions
+	hedger     *hedger
+	conns      *connectionTracker
+	// requestTimeout, if greater than 0, overrides the timeouts of the options for every request
+	requestTimeout time.Duration
+	// gzip, if true, compresses the body of every request, see WithGzip
+	gzip bool
+}
+
+// ClientOptions holds optional settings of the HTTP client.
+type ClientOptions struct {
+	// RetryConnectionReuseFailures retries a request once on another connection
+	// if the server closed the reused keep-alive connection while the request was being sent.
+	RetryConnectionReuseFailures bool
+	// GetClientCertificate, if set, returns the certificate presented to targets that require mutual TLS.
+	GetClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+	// RootCAs, if set, are the CA certificates used to verify the target instead of the system ones.
+	RootCAs *x509.CertPool
+	// DialContext, if set, is used to open the connections to the target.
+	DialContext func(ctx context.Context, network string, address string) (net.Conn, error)
+	// DialTimeout, if greater than 0, bounds the time spent opening a connection, including any DNS lookup or proxy tunnel.
+	// Requests are always bounded by the overall request timeout.
+	DialTimeout time.Duration
+	// IdleConnTimeout is the time after which idle connections are closed. Zero means no limit.
+	IdleConnTimeout time.Duration
+	// MaxConnsPerHost, if greater than 0, limits the number of connections open to each host at the same time.
+	// Requests wait for a connection to become available once the limit is reached.
+	MaxConnsPerHost int
+	// MaxIdleConns, if greater than 0, is the number of idle connections kept open to all the hosts together. 0 means no limit.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost, if greater than 0, is the number of idle connections kept open to each host between requests
+	// instead of the default of 2, so that they can be reused by the next requests.
+	MaxIdleConnsPerHost int
+	// ExpectContinue sets `Expect: 100-continue` on requests with a body so that the body is only sent
+	// once the server has accepted the request headers.
+	ExpectContinue bool
+	// ExpectContinueTimeout is the time to wait for the server's 100 Continue before sending the body anyway.
+	ExpectContinueTimeout time.Duration
+	// ReadTimeout and WriteTimeout, if greater than 0, replace the default request timeout for the safe methods
+	// (GET, HEAD, OPTIONS and TRACE) and for the other methods respectively.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// HedgePercentile, if greater than 0, enables hedging of GET, HEAD and OPTIONS requests: if a request has not responded
+	// within this percentile of the recent latencies a second copy is sent and the fastest response is used.
+	HedgePercentile float64
+	// ForceHTTP2 negotiates HTTP/2 with TLS targets, which the customized transport would not attempt otherwise.
+	// Targets that do not support it are still reached over HTTP/1.1.
+	ForceHTTP2 bool
+	// DisableRedirects returns redirect responses as they are instead of following them, so that exactly one request
+	// is sent and its 3xx status code is recorded.
+	DisableRedirects bool
+	// Authorization, if set, returns the value of the Authorization header of every request, e.g. a bearer token
+	// read from a file that is rotated. A request fails without being sent if it returns an error.
+	Authorization func() (string, error)
+	// SyntheticHeader, if set, is a header in the `name: value` format sent with every request that does not set it,
+	// so that the target and the observability tooling can tell the warmup traffic apart.
+	SyntheticHeader string
+	// ConfigureTransport, if set, is called with the transport once it has been configured from the options above
+	// and before the client is used, to tune any setting that is not exposed as an option.
+	ConfigureTransport func(transport *http.Transport)
 }
 
-// NewClient creates a new HTTP client for a given host.
+// NewClient creates a new HTTP client for a given host, an http:// or https:// URL, or the path of a UNIX domain socket
+// in the unix:///path/to/socket form, in which case the DialContext of the options is not used.
 // If insecure is true, the client will not verify the server's certificate chain and host name.
-func NewClient(host string, insecure bool) Client {
+// It returns an error if the host is obviously invalid, as every request sent to it would fail.
+func NewClient(host string, insecure bool, options ClientOptions) (Client, error) {
+	if err := validateHost(host); err != nil {
+		return Client{}, err
+	}
 	client := &http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: defaultTimeout,
+	}
+	if options.ReadTimeout > 0 || options.WriteTimeout > 0 {
+		// requests are bounded one by one depending on their method instead
+		client.Timeout = 0
+	}
+	if options.DisableRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
 	}
 
-	client.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure},
+	dialContext := options.DialContext
+	if IsUnixSocket(host) {
+		dialContext = unixSocketDialContext(strings.TrimPrefix(host, unixSocketPrefix))
+		host = unixSocketURL
+	}
+	conns := newConnectionTracker()
+	transport := &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: insecure, GetClientCertificate: options.GetClientCertificate, RootCAs: options.RootCAs},
+		DialContext:       withDialTimeout(conns.wrap(dialContext), options.DialTimeout),
+		IdleConnTimeout:   options.IdleConnTimeout,
+		MaxConnsPerHost:   options.MaxConnsPerHost,
+		MaxIdleConns:      options.MaxIdleConns,
+		ForceAttemptHTTP2: options.ForceHTTP2,
+	}
+	if options.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
 	}
-	return Client{httpClient: client, host: strings.TrimRight(host, "/")}
+	if options.ExpectContinue {
+		transport.ExpectContinueTimeout = options.ExpectContinueTimeout
+	}
+	if options.ConfigureTransport != nil {
+		options.ConfigureTransport(transport)
+	}
+	client.Transport = transport
+	return Client{httpClient: client, transport: transport, host: strings.TrimRight(host, "/"), options: options, hedger: newHedger(options.HedgePercentile), conns: conns}, nil
+}
+
+// validateHost returns an error if the host is not an absolute URL with a host name, e.g. because its scheme is missing,
+// nor the path of a UNIX domain socket.
+func validateHost(host string) error {
+	if host == "" {
+		return errors.New("HTTP host is empty")
+	}
+	if IsUnixSocket(host) {
+		if strings.TrimPrefix(host, unixSocketPrefix) == "" {
+			return fmt.Errorf("invalid HTTP host %s, expected the path of a UNIX socket such as unix:///var/run/app.sock", host)
+		}
+		return nil
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return fmt.Errorf("invalid HTTP host %s: %v", host, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid HTTP host %s, expected a URL such as http://localhost:8080", host)
+	}
+	return nil
 }
 
 // SendRequest sends a request to the HTTP server and wraps useful information into a Response object.
 func (c Client) SendRequest(method, path string, headers []string, requestBody *string) response.Response {
-	const respType = "http"
-	var body io.Reader
-	if requestBody != nil {
-		body = bytes.NewBufferString(*requestBody)
+	return c.sendRequest(method, path, headers, requestBody, 0)
+}
+
+// SendRequestCapturingBody works like SendRequest but also captures up to maxBodyBytes of the response body.
+func (c Client) SendRequestCapturingBody(method, path string, headers []string, requestBody *string, maxBodyBytes int) response.Response {
+	return c.sendRequest(method, path, headers, requestBody, maxBodyBytes)
+}
+
+// ValidateRequest checks, without sending it, that the method is supported and that the path, once its placeholders
+// are interpolated, makes a valid URL.
+func (c Client) ValidateRequest(method, path string) error {
+	method, err := NormalizeMethod(method)
+	if err != nil {
+		return err
 	}
+	if _, err := http.NewRequest(method, c.url(placeholders.InterpolatePlaceholders(path)), nil); err != nil {
+		return fmt.Errorf("invalid path %s: %v", path, err)
+	}
+	return nil
+}
 
-	url := fmt.Sprintf("%s/%s", c.host, strings.TrimLeft(path, "/"))
-	req, err := http.NewRequest(method, url, body)
+// url returns the URL of a path on the host of the client.
+func (c Client) url(path string) string {
+	return fmt.Sprintf("%s/%s", c.host, strings.TrimLeft(path, "/"))
+}
 
+func (c Client) sendRequest(method, path string, headers []string, requestBody *string, maxBodyBytes int) response.Response {
+	const respType = "http"
+	method, err := NormalizeMethod(method)
 	if err != nil {
-		log.Printf("Failed to create request: %s %s: %v", method, url, err)
-		return response.Response{Duration: time.Duration(0), Err: err, Type: respType}
+		return response.Response{Err: err, Type: respType}
+	}
+	// interpolate the path, the body and the headers (just the values, not the keys) every time the request is sent
+	// but only once per send so that hedged and retried copies of a request are identical
+	path = placeholders.InterpolatePlaceholders(path)
+	if requestBody != nil {
+		body := placeholders.InterpolatePlaceholders(*requestBody)
+		requestBody = &body
 	}
+	url := c.url(path)
 
 	headersMap := util.ToHeaders(headers)
 	for k, v := range headersMap {
-		if strings.EqualFold(k, "Host") {
-			req.Host = v
+		headersMap[k] = placeholders.InterpolatePlaceholders(v)
+	}
+	c.addSyntheticHeader(headersMap)
+	if c.options.Authorization != nil {
+		authorization, err := c.options.Authorization()
+		if err != nil {
+			return response.Response{Err: fmt.Errorf("authorization: %w", err), Type: respType}
+		}
+		headersMap["Authorization"] = authorization
+	}
+	if requestBody != nil && c.gzip {
+		compressed, err := gzipRequest(headersMap, *requestBody)
+		if err != nil {
+			return response.Response{Err: err, Type: respType}
+		}
+		requestBody = &compressed
+	}
+	ctx := context.Background()
+	if timeout := c.timeout(method); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		// the body is read before returning so the context can only be cancelled then
+		defer cancel()
+	}
+	newRequest := func() (*http.Request, error) {
+		var body io.Reader
+		if requestBody != nil {
+			body = bytes.NewBufferString(*requestBody)
 		}
+		req, err := http.NewRequestWithContext(ctx, method, url, body)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range headersMap {
+			if strings.EqualFold(k, "Host") {
+				req.Host = v
+			}
+			req.Header.Add(k, v)
+		}
+		if c.options.ExpectContinue && requestBody != nil {
+			req.Header.Set("Expect", "100-continue")
+		}
+		return req, nil
+	}
 
-		// interpolate headers (just the values, not the keys)
-		interpolatedHeaderValue := placeholders.InterpolatePlaceholders(v)
+	req, err := newRequest()
+	if err != nil {
+		log.Printf("Failed to create request: %s %s: %v", method, url, err)
+		return response.Response{Duration: time.Duration(0), Err: err, Type: respType}
+	}
 
-		req.Header.Add(k, interpolatedHeaderValue)
+	if c.hedger != nil && canHedge(method) {
+		startTime := time.Now()
+		resp, hedged, err := c.hedger.do(c.httpClient, newRequest)
+		result := c.toResponse(resp, err, time.Since(startTime), maxBodyBytes)
+		result.Hedged = hedged
+		return result
 	}
 
 	startTime := time.Now()
 	resp, err := c.httpClient.Do(req)
+	if err != nil && c.options.RetryConnectionReuseFailures && isConnectionReuseFailure(err) {
+		// the server closed the keep-alive connection just as we reused it; this is not a real failure
+		// so try once more, the transport already dropped the closed connection and the other ones are kept warm
+		log.Printf("Connection reused for %s %s was closed by the server, retrying on another connection", method, url)
+		if req, err = newRequest(); err == nil {
+			startTime = time.Now()
+			resp, err = c.httpClient.Do(req)
+		}
+	}
 	endTime := time.Now()
+	return c.toResponse(resp, err, endTime.Sub(startTime), maxBodyBytes)
+}
+
+// addSyntheticHeader adds the synthetic header of the options to the headers unless they already set it.
+func (c Client) addSyntheticHeader(headers map[string]string) {
+	if c.options.SyntheticHeader == "" {
+		return
+	}
+	for name, value := range util.ToHeaders([]string{c.options.SyntheticHeader}) {
+		for k := range headers {
+			if strings.EqualFold(k, name) {
+				return
+			}
+		}
+		headers[name] = value
+	}
+}
+
+// WithTimeout returns a copy of the client whose requests are cancelled after timeout, whatever their method.
+// The copy shares the connections of the client.
+func (c Client) WithTimeout(timeout time.Duration) Client {
+	httpClient := *c.httpClient
+	// requests are bounded by their own context instead
+	httpClient.Timeout = 0
+	c.httpClient = &httpClient
+	c.requestTimeout = timeout
+	return c
+}
+
+// timeout returns the time after which a request with the given method is cancelled if a timeout was set with WithTimeout
+// or the read or write timeouts are set, 0 otherwise. The methods without a timeout of their own keep the default one.
+func (c Client) timeout(method string) time.Duration {
+	if c.requestTimeout > 0 {
+		return c.requestTimeout
+	}
+	if c.options.ReadTimeout <= 0 && c.options.WriteTimeout <= 0 {
+		return 0
+	}
+	timeout := c.options.WriteTimeout
+	if isSafeMethod(method) {
+		timeout = c.options.ReadTimeout
+	}
+	if timeout <= 0 {
+		return defaultTimeout
+	}
+	return timeout
+}
+
+// isSafeMethod returns true for the methods that only read data on the server.
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions || method == http.MethodTrace
+}
+
+// toResponse reads the body of an HTTP response, capturing up to maxBodyBytes of it, and wraps it into a Response object.
+func (c Client) toResponse(resp *http.Response, err error, duration time.Duration, maxBodyBytes int) response.Response {
+	const respType = "http"
 	if err != nil {
-		return response.Response{Duration: endTime.Sub(startTime), Err: err, Type: respType}
+		return response.Response{Duration: duration, Err: err, Type: respType, TimedOut: isTimeout(err)}
 	}
 	defer resp.Body.Close()
 
-	if _, err = io.Copy(ioutil.Discard, resp.Body); err != nil {
-		return response.Response{Duration: endTime.Sub(startTime), Err: err, Type: respType, StatusCode: resp.StatusCode}
+	var captured []byte
+	if maxBodyBytes > 0 {
+		// read one byte more than the limit to know if the body was truncated
+		if captured, err = ioutil.ReadAll(io.LimitReader(resp.Body, int64(maxBodyBytes)+1)); err != nil {
+			return response.Response{Duration: duration, Err: err, Type: respType, StatusCode: resp.StatusCode, TimedOut: isTimeout(err)}
+		}
+	}
+	discarded, err := io.Copy(ioutil.Discard, resp.Body)
+	if err != nil {
+		return response.Response{Duration: duration, Err: err, Type: respType, StatusCode: resp.StatusCode, TimedOut: isTimeout(err)}
+	}
+	result := response.Response{Duration: duration, Err: nil, Type: respType, StatusCode: resp.StatusCode, Headers: resp.Header,
+		BodySize: int64(len(captured)) + discarded, Protocol: resp.Proto}
+	if maxBodyBytes > 0 {
+		result.BodyTruncated = len(captured) > maxBodyBytes
+		if result.BodyTruncated {
+			captured = captured[:maxBodyBytes]
+		}
+		result.Body = captured
+	}
+	return result
+}
+
+// withDialTimeout returns a dial function that gives up after timeout, or dial itself if timeout is not greater than 0.
+// A nil dial stands for the default dialer.
+func withDialTimeout(dial func(ctx context.Context, network string, address string) (net.Conn, error), timeout time.Duration) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	if timeout <= 0 {
+		return dial
+	}
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		conn, err := dial(ctx, network, address)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("dial %s: timed out after %v: %w", address, timeout, err)
+		}
+		return conn, err
+	}
+}
+
+// CloseIdleConnections closes the connections that are not in use. New requests open new connections.
+func (c Client) CloseIdleConnections() {
+	c.transport.CloseIdleConnections()
+}
+
+// Connections returns the connections opened so far by host, in host:port format.
+func (c Client) Connections() map[string]HostConnections {
+	if c.conns == nil {
+		return nil
 	}
-	return response.Response{Duration: endTime.Sub(startTime), Err: nil, Type: respType, StatusCode: resp.StatusCode}
+	return c.conns.snapshot()
+}
+
+// isTimeout returns true if the error was caused by a request not completing within its timeout, as opposed to e.g. a refused connection.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isConnectionReuseFailure returns true if the error was caused by the server closing an idle connection that we tried to reuse.
+func isConnectionReuseFailure(err error) bool {
+	return errors.Is(err, io.EOF) || strings.Contains(err.Error(), "server closed idle connection")
 }
@@ -15,13 +15,25 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/x509"
 	"fmt"
+	"io"
 	"mittens/fixture"
+	"mittens/internal/pkg/response"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var mockServer *http.Server
@@ -37,15 +49,94 @@ func TestMain(m *testing.M) {
 	teardown()
 }
 
+// newClient returns a client of the host, which must be valid.
+func newClient(t *testing.T, host string, insecure bool, options ClientOptions) Client {
+	t.Helper()
+	c, err := NewClient(host, insecure, options)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNewClientRejectsInvalidHosts(t *testing.T) {
+	for _, host := range []string{"", "localhost:8080", "/path", "http://", "http://local host", "unix://"} {
+		_, err := NewClient(host, false, ClientOptions{})
+		assert.Error(t, err, host)
+	}
+}
+
 func TestRequestSuccess(t *testing.T) {
-	c := NewClient(serverUrl, false)
+	c := newClient(t, serverUrl, false, ClientOptions{})
 	reqBody := ""
 	resp := c.SendRequest("GET", WorkingPath, []string{}, &reqBody)
 	assert.Nil(t, resp.Err)
 }
 
+func TestRequestNormalizesTheMethod(t *testing.T) {
+	c := newClient(t, serverUrl, false, ClientOptions{})
+	resp := c.SendRequest(" get ", WorkingPath, []string{}, nil)
+	assert.Nil(t, resp.Err)
+
+	resp = c.SendRequest("FE TCH", WorkingPath, []string{}, nil)
+	assert.ErrorContains(t, resp.Err, "method FE TCH is not a valid HTTP method")
+}
+
+func TestValidateRequest(t *testing.T) {
+	c := newClient(t, serverUrl, false, ClientOptions{})
+
+	assert.NoError(t, c.ValidateRequest("GET", "/search?q={$random|shoes,socks}"))
+	assert.NoError(t, c.ValidateRequest("refresh", WorkingPath))
+	assert.ErrorContains(t, c.ValidateRequest("FE TCH", WorkingPath), "method FE TCH is not a valid HTTP method")
+	assert.ErrorContains(t, c.ValidateRequest("GET", "/search%zz"), "invalid path /search%zz")
+}
+
+func TestAuthorization(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+	token := "first"
+	c := newClient(t, server.URL, false, ClientOptions{Authorization: func() (string, error) {
+		if token == "" {
+			return "", assert.AnError
+		}
+		return "Bearer " + token, nil
+	}})
+
+	resp := c.SendRequest("GET", "/", []string{"Authorization: Bearer static"}, nil)
+	require.NoError(t, resp.Err)
+	assert.Equal(t, "Bearer first", authorization)
+
+	token = "second"
+	c.SendRequest("GET", "/", nil, nil)
+	assert.Equal(t, "Bearer second", authorization)
+
+	token = ""
+	resp = c.SendRequest("GET", "/", nil, nil)
+	assert.ErrorIs(t, resp.Err, assert.AnError)
+}
+
+func TestSyntheticHeader(t *testing.T) {
+	var synthetic []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		synthetic = r.Header.Values("X-Mittens-Warmup")
+	}))
+	defer server.Close()
+
+	c := newClient(t, server.URL, false, ClientOptions{SyntheticHeader: "X-Mittens-Warmup: true"})
+	require.NoError(t, c.SendRequest("GET", "/", nil, nil).Err)
+	assert.Equal(t, []string{"true"}, synthetic)
+
+	c.SendRequest("GET", "/", []string{"x-mittens-warmup: replay"}, nil)
+	assert.Equal(t, []string{"replay"}, synthetic)
+
+	c = newClient(t, server.URL, false, ClientOptions{})
+	c.SendRequest("GET", "/", nil, nil)
+	assert.Empty(t, synthetic)
+}
+
 func TestHttpError(t *testing.T) {
-	c := NewClient(serverUrl, false)
+	c := newClient(t, serverUrl, false, ClientOptions{})
 	reqBody := ""
 	resp := c.SendRequest("GET", "/", []string{}, &reqBody)
 	assert.Nil(t, resp.Err)
@@ -53,12 +144,464 @@ func TestHttpError(t *testing.T) {
 }
 
 func TestConnectionError(t *testing.T) {
-	c := NewClient("http://localhost:9999", false)
+	c := newClient(t, "http://localhost:9999", false, ClientOptions{})
 	reqBody := ""
 	resp := c.SendRequest("GET", "/potato", []string{}, &reqBody)
 	assert.NotNil(t, resp.Err)
 }
 
+func TestRequestCapturingBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	c := newClient(t, server.URL, false, ClientOptions{})
+	resp := c.SendRequestCapturingBody("GET", "/", []string{}, nil, 100)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "hello world", string(resp.Body))
+	assert.False(t, resp.BodyTruncated)
+
+	resp = c.SendRequestCapturingBody("GET", "/", []string{}, nil, 5)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "hello", string(resp.Body))
+	assert.True(t, resp.BodyTruncated)
+	assert.Equal(t, int64(11), resp.BodySize)
+
+	resp = c.SendRequest("GET", "/", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.Nil(t, resp.Body)
+	assert.Equal(t, int64(11), resp.BodySize)
+}
+
+func TestResponseHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("X-Cache", "HIT")
+	}))
+	defer server.Close()
+
+	c := newClient(t, server.URL, false, ClientOptions{})
+	resp := c.SendRequest("GET", "/", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "HIT", resp.Header("x-cache"))
+	assert.Equal(t, "", resp.Header("X-Served-By"))
+}
+
+func TestDialTimeout(t *testing.T) {
+	// a listener that accepts connections but never answers the proxy CONNECT sent by the dialer below
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	dial := func(ctx context.Context, network string, address string) (net.Conn, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, network, listener.Addr().String())
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\n\r\n", address)
+		<-ctx.Done()
+		conn.Close()
+		return nil, ctx.Err()
+	}
+
+	c := newClient(t, "http://target", false, ClientOptions{DialContext: dial, DialTimeout: 100 * time.Millisecond})
+	start := time.Now()
+	resp := c.SendRequest("GET", "/", []string{}, nil)
+	require.Error(t, resp.Err)
+	assert.Contains(t, resp.Err.Error(), "timed out after 100ms")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestConnectionReuseFailureIsRetried(t *testing.T) {
+	url := startIdleCloseServer(t, nil)
+	c := newClient(t, url, false, ClientOptions{RetryConnectionReuseFailures: true})
+	reqBody := "{}"
+
+	resp := c.SendRequest("POST", "/", []string{}, &reqBody)
+	assert.Nil(t, resp.Err)
+	// the second request reuses the connection which the server closes without responding
+	resp = c.SendRequest("POST", "/", []string{}, &reqBody)
+	assert.Nil(t, resp.Err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestConnectionReuseFailureKeepsTheOtherIdleConnections(t *testing.T) {
+	release := make(chan struct{})
+	url := startIdleCloseServer(t, release)
+	var dials int64
+	c := newClient(t, url, false, ClientOptions{RetryConnectionReuseFailures: true, ConfigureTransport: func(transport *http.Transport) {
+		dialer := &net.Dialer{}
+		transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			atomic.AddInt64(&dials, 1)
+			return dialer.DialContext(ctx, network, address)
+		}
+	}})
+	reqBody := "{}"
+
+	// the first connection is busy while a second one is opened, then both are idle
+	done := make(chan response.Response)
+	go func() {
+		done <- c.SendRequest("POST", "/", []string{}, &reqBody)
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&dials) == 1 }, time.Second, time.Millisecond)
+	require.Nil(t, c.SendRequest("POST", "/", []string{}, &reqBody).Err)
+	close(release)
+	require.Nil(t, (<-done).Err)
+	require.Equal(t, int64(2), atomic.LoadInt64(&dials))
+
+	// the most recently used first connection is reused and closed by the server, the retry takes the second one
+	resp := c.SendRequest("POST", "/", []string{}, &reqBody)
+	assert.Nil(t, resp.Err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&dials))
+}
+
+func TestConnectionReuseFailureWithoutRetry(t *testing.T) {
+	url := startIdleCloseServer(t, nil)
+	c := newClient(t, url, false, ClientOptions{})
+	reqBody := "{}"
+
+	resp := c.SendRequest("POST", "/", []string{}, &reqBody)
+	assert.Nil(t, resp.Err)
+	resp = c.SendRequest("POST", "/", []string{}, &reqBody)
+	assert.NotNil(t, resp.Err)
+}
+
+// startIdleCloseServer starts a server that simulates an idle-close race: on the first connection it answers one request
+// and then closes the connection as soon as the next request arrives. Any other connection is served normally.
+// If release is not nil the first request of the first connection is only answered once release is closed.
+func startIdleCloseServer(t *testing.T, release <-chan struct{}) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for connections := 0; ; connections++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn, first bool) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for served := 0; ; served++ {
+					req, err := http.ReadRequest(reader)
+					if err != nil {
+						return
+					}
+					io.Copy(io.Discard, req.Body)
+					if first && served == 0 && release != nil {
+						<-release
+					}
+					if first && served == 1 {
+						return
+					}
+					conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+				}
+			}(conn, connections == 0)
+		}
+	}()
+	return "http://" + listener.Addr().String()
+}
+
+func TestConfigureTransportHook(t *testing.T) {
+	dials := 0
+	c := newClient(t, serverUrl, false, ClientOptions{ConfigureTransport: func(transport *http.Transport) {
+		dialer := &net.Dialer{}
+		transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials++
+			return dialer.DialContext(ctx, network, address)
+		}
+	}})
+
+	resp := c.SendRequest("GET", WorkingPath, []string{}, nil)
+	assert.Nil(t, resp.Err)
+	assert.Equal(t, 1, dials)
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	dials := 0
+	c := newClient(t, serverUrl, false, ClientOptions{IdleConnTimeout: time.Minute, ConfigureTransport: func(transport *http.Transport) {
+		dialer := &net.Dialer{}
+		transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials++
+			return dialer.DialContext(ctx, network, address)
+		}
+	}})
+	assert.Equal(t, time.Minute, c.transport.IdleConnTimeout)
+
+	c.SendRequest("GET", WorkingPath, []string{}, nil)
+	c.SendRequest("GET", WorkingPath, []string{}, nil)
+	assert.Equal(t, 1, dials)
+
+	c.CloseIdleConnections()
+	c.SendRequest("GET", WorkingPath, []string{}, nil)
+	assert.Equal(t, 2, dials)
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	server, port := fixture.StartLatencyTestServer([]time.Duration{100 * time.Millisecond})
+	defer server.Close()
+	c := newClient(t, fmt.Sprintf("http://127.0.0.1:%d", port), false, ClientOptions{MaxConnsPerHost: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := c.SendRequest("GET", "/", []string{}, nil)
+			assert.Nil(t, resp.Err)
+		}()
+	}
+	wg.Wait()
+
+	connections := c.Connections()
+	require.Equal(t, 1, len(connections))
+	host := connections[fmt.Sprintf("127.0.0.1:%d", port)]
+	assert.Equal(t, 2, host.Opened)
+	assert.Equal(t, 2, host.Peak)
+
+	c.CloseIdleConnections()
+	assert.Equal(t, 0, c.Connections()[fmt.Sprintf("127.0.0.1:%d", port)].Open)
+}
+
+func TestMaxIdleConnsPerHost(t *testing.T) {
+	server, port := fixture.StartLatencyTestServer([]time.Duration{50 * time.Millisecond})
+	defer server.Close()
+	host := fmt.Sprintf("127.0.0.1:%d", port)
+	sendConcurrently := func(c Client, n int) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Nil(t, c.SendRequest("GET", "/", []string{}, nil).Err)
+			}()
+		}
+		wg.Wait()
+	}
+
+	c := newClient(t, "http://"+host, false, ClientOptions{MaxIdleConnsPerHost: 4})
+	sendConcurrently(c, 4)
+	sendConcurrently(c, 4)
+	assert.Equal(t, 4, c.Connections()[host].Opened, "the connections of the first requests are all reused")
+	c.CloseIdleConnections()
+
+	// by default only 2 idle connections are kept
+	c = newClient(t, "http://"+host, false, ClientOptions{})
+	sendConcurrently(c, 4)
+	sendConcurrently(c, 4)
+	assert.Equal(t, 6, c.Connections()[host].Opened)
+	c.CloseIdleConnections()
+}
+
+func TestReadAndWriteTimeouts(t *testing.T) {
+	server, port := fixture.StartLatencyTestServer([]time.Duration{200 * time.Millisecond})
+	defer server.Close()
+	c := newClient(t, fmt.Sprintf("http://127.0.0.1:%d", port), false, ClientOptions{ReadTimeout: 50 * time.Millisecond, WriteTimeout: time.Second})
+
+	resp := c.SendRequest("GET", "/", []string{}, nil)
+	assert.ErrorIs(t, resp.Err, context.DeadlineExceeded)
+
+	body := "{}"
+	resp = c.SendRequest("POST", "/", []string{}, &body)
+	assert.Nil(t, resp.Err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	assert.Equal(t, 50*time.Millisecond, c.timeout("TRACE"))
+	assert.Equal(t, time.Second, c.timeout("DELETE"))
+	assert.Equal(t, defaultTimeout, newClient(t, serverUrl, false, ClientOptions{ReadTimeout: time.Second}).timeout("PUT"))
+	assert.Equal(t, time.Duration(0), newClient(t, serverUrl, false, ClientOptions{}).timeout("GET"))
+}
+
+func TestWithTimeout(t *testing.T) {
+	server, port := fixture.StartLatencyTestServer([]time.Duration{200 * time.Millisecond})
+	defer server.Close()
+	c := newClient(t, fmt.Sprintf("http://127.0.0.1:%d", port), false, ClientOptions{ReadTimeout: time.Second})
+
+	resp := c.WithTimeout(50*time.Millisecond).SendRequest("GET", "/", []string{}, nil)
+	assert.ErrorIs(t, resp.Err, context.DeadlineExceeded)
+	assert.True(t, resp.TimedOut)
+
+	// the client itself keeps its timeouts
+	resp = c.SendRequest("GET", "/", []string{}, nil)
+	assert.Nil(t, resp.Err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, time.Minute, newClient(t, serverUrl, false, ClientOptions{}).WithTimeout(time.Minute).timeout("POST"))
+}
+
+func TestConnectionErrorIsNotATimeout(t *testing.T) {
+	c := newClient(t, "http://localhost:9999", false, ClientOptions{})
+	resp := c.WithTimeout(time.Second).SendRequest("GET", "/", []string{}, nil)
+	assert.NotNil(t, resp.Err)
+	assert.False(t, resp.TimedOut)
+}
+
+func TestExpectContinue(t *testing.T) {
+	var expect, body string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		expect = r.Header.Get("Expect")
+		// reading the body makes the server reply with 100 Continue
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer server.Close()
+
+	var received recordingConn
+	dialer := &net.Dialer{}
+	c := newClient(t, server.URL, false, ClientOptions{ExpectContinue: true, ExpectContinueTimeout: 5 * time.Second, DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		received.Conn = conn
+		return &received, err
+	}})
+
+	requestBody := `{"upload":true}`
+	resp := c.SendRequest("POST", "/upload", []string{}, &requestBody)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "100-continue", expect)
+	assert.Equal(t, requestBody, body)
+	assert.Contains(t, received.String(), "HTTP/1.1 100 Continue")
+
+	// requests without a body do not expect anything
+	resp = c.SendRequest("GET", "/", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "", expect)
+}
+
+func TestForceHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	resp := newClient(t, server.URL, true, ClientOptions{}).SendRequest("GET", "/", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "HTTP/1.1", resp.Protocol)
+
+	resp = newClient(t, server.URL, true, ClientOptions{ForceHTTP2: true}).SendRequest("GET", "/", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "HTTP/2.0", resp.Protocol)
+}
+
+func TestDisableRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(rw, r, "/new", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	resp := newClient(t, server.URL, false, ClientOptions{}).SendRequest("GET", "/old", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp = newClient(t, server.URL, false, ClientOptions{DisableRedirects: true}).SendRequest("GET", "/old", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, 301, resp.StatusCode)
+}
+
+func TestRootCAs(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp := newClient(t, server.URL, false, ClientOptions{}).SendRequest("GET", "/", []string{}, nil)
+	require.NotNil(t, resp.Err)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	resp = newClient(t, server.URL, false, ClientOptions{RootCAs: rootCAs}).SendRequest("GET", "/", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
+
+func TestGzipRequestBody(t *testing.T) {
+	var encoding, body string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		if gz, err := gzip.NewReader(bytes.NewReader(b)); err == nil {
+			b, _ = io.ReadAll(gz)
+			body = string(b)
+		}
+	}))
+	defer server.Close()
+
+	c := newClient(t, server.URL, false, ClientOptions{})
+	requestBody := `{"id": "{$range|min=7,max=7}"}`
+	resp := c.WithGzip().SendRequest("POST", "/items", []string{"content-encoding: identity"}, &requestBody)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "gzip", encoding)
+	assert.Equal(t, `{"id": "7"}`, body)
+
+	// bodies are sent as they are by the original client, whatever their headers
+	resp = c.SendRequest("POST", "/items", []string{}, &requestBody)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "", encoding)
+	assert.Equal(t, `{"id": "7"}`, body)
+
+	rawBody := "not compressed"
+	resp = c.SendRequest("POST", "/items", []string{"Content-Encoding: gzip"}, &rawBody)
+	require.Nil(t, resp.Err)
+	assert.Equal(t, "gzip", encoding)
+	assert.Equal(t, "not compressed", body)
+}
+
+func TestPlaceholdersAreInterpolatedOnEverySend(t *testing.T) {
+	var paths, bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(b))
+	}))
+	defer server.Close()
+
+	c := newClient(t, server.URL, false, ClientOptions{})
+	body := `{"id": "{$uuid}"}`
+	for i := 0; i < 2; i++ {
+		resp := c.SendRequest("POST", "/items/{$uuid}", []string{}, &body)
+		require.Nil(t, resp.Err)
+	}
+
+	require.Len(t, paths, 2)
+	assert.Regexp(t, `^/items/[0-9a-f-]{36}$`, paths[0])
+	assert.Regexp(t, `^{"id": "[0-9a-f-]{36}"}$`, bodies[0])
+	assert.NotEqual(t, paths[0], paths[1])
+	assert.NotEqual(t, bodies[0], bodies[1])
+	assert.Equal(t, `{"id": "{$uuid}"}`, body)
+}
+
+// recordingConn records everything read from the connection it wraps.
+type recordingConn struct {
+	net.Conn
+	mu   sync.Mutex
+	read []byte
+}
+
+func (c *recordingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.mu.Lock()
+	c.read = append(c.read, b[:n]...)
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *recordingConn) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return string(c.read)
+}
+
 func setup() {
 	pathResponseHandlerFunc := func(rw http.ResponseWriter, r *http.Request) {
 		if want, have := "/path", r.URL.Path; want != have {
@@ -0,0 +1,102 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// HostConnections describes the connections opened to a host.
+type HostConnections struct {
+	// Opened is the number of connections opened to the host.
+	Opened int
+	// Open is the number of connections currently open to the host.
+	Open int
+	// Peak is the largest number of connections open to the host at the same time.
+	Peak int
+}
+
+// connectionTracker counts the connections opened to every host. It is safe for concurrent use.
+type connectionTracker struct {
+	mu    sync.Mutex
+	hosts map[string]*HostConnections
+}
+
+func newConnectionTracker() *connectionTracker {
+	return &connectionTracker{hosts: make(map[string]*HostConnections)}
+}
+
+// wrap returns a dial function that counts the connections opened with dial by the address they were opened to.
+func (t *connectionTracker) wrap(dial func(ctx context.Context, network string, address string) (net.Conn, error)) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	if dial == nil {
+		// same as the dialer of the default transport
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		t.opened(address)
+		return &trackedConn{Conn: conn, close: func() { t.closed(address) }}, nil
+	}
+}
+
+func (t *connectionTracker) opened(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.hosts[host]
+	if !ok {
+		h = &HostConnections{}
+		t.hosts[host] = h
+	}
+	h.Opened++
+	h.Open++
+	if h.Open > h.Peak {
+		h.Peak = h.Open
+	}
+}
+
+func (t *connectionTracker) closed(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.hosts[host].Open--
+}
+
+// snapshot returns the connections opened so far by host.
+func (t *connectionTracker) snapshot() map[string]HostConnections {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	hosts := make(map[string]HostConnections, len(t.hosts))
+	for host, h := range t.hosts {
+		hosts[host] = *h
+	}
+	return hosts
+}
+
+// trackedConn is a connection that reports when it is closed, only once.
+type trackedConn struct {
+	net.Conn
+	once  sync.Once
+	close func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.close)
+	return c.Conn.Close()
+}
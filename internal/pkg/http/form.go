@@ -0,0 +1,69 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"mittens/internal/pkg/placeholders"
+	"net/url"
+	"strings"
+)
+
+// formPrefix marks request bodies given as form fields, e.g. `form:user=john&tag=a&tag=b`.
+const formPrefix = "form:"
+
+// FormContentType is the content type of the request bodies built from form fields.
+const FormContentType = "application/x-www-form-urlencoded"
+
+// encodeForm URL-encodes form fields given as `key=value` pairs separated by `&`.
+// Keys can be repeated and fields keep their order. Placeholders in the values are interpolated before encoding.
+// Keys and values are taken literally apart from percent-encoded sequences, which are decoded first so that e.g. `%26` can be used for `&`.
+func encodeForm(fields string) string {
+	var encoded []string
+	for _, field := range strings.Split(fields, "&") {
+		if field == "" {
+			continue
+		}
+		kv := strings.SplitN(field, "=", 2)
+		key := unescape(kv[0])
+		var value string
+		if len(kv) == 2 {
+			value = unescape(placeholders.InterpolatePlaceholders(kv[1]))
+		}
+		encoded = append(encoded, url.QueryEscape(key)+"="+url.QueryEscape(value))
+	}
+	return strings.Join(encoded, "&")
+}
+
+// unescape decodes percent-encoded sequences, leaving the value untouched if it is not validly encoded.
+func unescape(s string) string {
+	unescaped, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+	return unescaped
+}
+
+// WithContentType returns the headers plus the content type of the request, unless the headers already set one.
+func (r Request) WithContentType(headers []string) []string {
+	if r.ContentType == "" {
+		return headers
+	}
+	for _, header := range headers {
+		if strings.EqualFold(strings.TrimSpace(strings.SplitN(header, ":", 2)[0]), "Content-Type") {
+			return headers
+		}
+	}
+	return append(append([]string{}, headers...), "Content-Type: "+r.ContentType)
+}
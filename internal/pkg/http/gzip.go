@@ -0,0 +1,56 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+)
+
+// WithGzip returns a copy of the client that compresses the body of its requests with gzip
+// and sends them with Content-Encoding: gzip. The copy shares the connections of the client.
+func (c Client) WithGzip() Client {
+	c.gzip = true
+	return c
+}
+
+// gzipRequest compresses the body and replaces the content encoding set in the headers, if any, with gzip.
+func gzipRequest(headers map[string]string, body string) (string, error) {
+	compressed, err := gzipBody(body)
+	if err != nil {
+		return "", err
+	}
+	for k := range headers {
+		if strings.EqualFold(k, "Content-Encoding") {
+			delete(headers, k)
+		}
+	}
+	headers["Content-Encoding"] = "gzip"
+	return compressed, nil
+}
+
+// gzipBody returns the body compressed with gzip.
+func gzipBody(body string) (string, error) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return compressed.String(), nil
+}
@@ -0,0 +1,176 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// hedgeWindowSize is the number of recent latencies used to compute the hedge delay.
+	hedgeWindowSize = 100
+	// hedgeMinSamples is the number of latencies needed before requests start being hedged.
+	hedgeMinSamples = 20
+)
+
+// hedger sends a second copy of a request if the first one has not responded within a percentile of the recent latencies.
+type hedger struct {
+	percentile float64
+	mu         sync.Mutex
+	latencies  []time.Duration
+	next       int
+}
+
+func newHedger(percentile float64) *hedger {
+	if percentile <= 0 {
+		return nil
+	}
+	return &hedger{percentile: percentile}
+}
+
+// canHedge returns true for the methods that are safe to send twice.
+func canHedge(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// record adds a latency to the window of recent latencies.
+func (h *hedger) record(latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < hedgeWindowSize {
+		h.latencies = append(h.latencies, latency)
+		return
+	}
+	h.latencies[h.next] = latency
+	h.next = (h.next + 1) % hedgeWindowSize
+}
+
+// delay returns the time after which a request is hedged, or false if there are not enough samples yet.
+func (h *hedger) delay() (time.Duration, bool) {
+	h.mu.Lock()
+	latencies := append([]time.Duration(nil), h.latencies...)
+	h.mu.Unlock()
+
+	if len(latencies) < hedgeMinSamples {
+		return 0, false
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	i := int(h.percentile / 100 * float64(len(latencies)))
+	if i >= len(latencies) {
+		i = len(latencies) - 1
+	}
+	return latencies[i], true
+}
+
+type hedgeResult struct {
+	resp *http.Response
+	err  error
+	// index of the copy of the request
+	index int
+}
+
+// do sends the request built by newRequest and, if it has not responded within the hedge delay, a second copy of it.
+// It returns the first successful response and whether the request was hedged. The other copy is cancelled.
+func (h *hedger) do(client *http.Client, newRequest func() (*http.Request, error)) (*http.Response, bool, error) {
+	results := make(chan hedgeResult, 2)
+	var cancels []context.CancelFunc
+	send := func() error {
+		req, err := newRequest()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := client.Do(req.WithContext(ctx))
+			results <- hedgeResult{resp: resp, err: err, index: index}
+		}()
+		return nil
+	}
+
+	start := time.Now()
+	if err := send(); err != nil {
+		return nil, false, err
+	}
+	pending := 1
+
+	var hedge <-chan time.Time
+	if delay, ok := h.delay(); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+
+	hedged := false
+	var result hedgeResult
+	for pending > 0 {
+		select {
+		case <-hedge:
+			hedge = nil
+			if err := send(); err == nil {
+				hedged = true
+				pending++
+			}
+			continue
+		case result = <-results:
+			pending--
+		}
+		if result.err == nil {
+			break
+		}
+		cancels[result.index]()
+	}
+
+	if pending > 0 {
+		// cancel and discard the slower copy
+		for i, cancel := range cancels {
+			if i != result.index {
+				cancel()
+			}
+		}
+		go func() {
+			late := <-results
+			if late.resp != nil {
+				io.Copy(io.Discard, late.resp.Body)
+				late.resp.Body.Close()
+			}
+		}()
+	}
+	if result.err != nil {
+		return nil, hedged, result.err
+	}
+	h.record(time.Since(start))
+	// the context of the request must stay alive until the body is read
+	result.resp.Body = &cancelOnClose{ReadCloser: result.resp.Body, cancel: cancels[result.index]}
+	return result.resp, hedged, nil
+}
+
+// cancelOnClose cancels the context of a request once its response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
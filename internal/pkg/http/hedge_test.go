@@ -0,0 +1,85 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedgerDelay(t *testing.T) {
+	h := newHedger(90)
+	for i := 1; i < hedgeMinSamples; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := h.delay()
+	assert.False(t, ok)
+
+	h.record(hedgeMinSamples * time.Millisecond)
+	delay, ok := h.delay()
+	assert.True(t, ok)
+	assert.Equal(t, 19*time.Millisecond, delay)
+}
+
+// startSlowFirstServer starts a server that takes a long time to answer the first request only.
+func startSlowFirstServer(t *testing.T) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-time.After(2 * time.Second):
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestSlowRequestIsHedged(t *testing.T) {
+	server, calls := startSlowFirstServer(t)
+
+	c := newClient(t, server.URL, false, ClientOptions{HedgePercentile: 95})
+	for i := 0; i < hedgeMinSamples; i++ {
+		c.hedger.record(10 * time.Millisecond)
+	}
+
+	resp := c.SendRequest("GET", "/", []string{}, nil)
+	require.Nil(t, resp.Err)
+	assert.True(t, resp.Hedged)
+	assert.Less(t, resp.Duration, time.Second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestUnsafeMethodIsNotHedged(t *testing.T) {
+	server, calls := startSlowFirstServer(t)
+
+	c := newClient(t, server.URL, false, ClientOptions{HedgePercentile: 95})
+	for i := 0; i < hedgeMinSamples; i++ {
+		c.hedger.record(10 * time.Millisecond)
+	}
+
+	body := "{}"
+	resp := c.SendRequest("POST", "/", []string{}, &body)
+	require.Nil(t, resp.Err)
+	assert.False(t, resp.Hedged)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
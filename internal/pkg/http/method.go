@@ -0,0 +1,60 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"strings"
+)
+
+// knownHTTPMethods are the standard HTTP methods and the extension ones understood by caching proxies.
+var knownHTTPMethods = map[string]interface{}{
+	"GET":     nil,
+	"HEAD":    nil,
+	"POST":    nil,
+	"PUT":     nil,
+	"PATCH":   nil,
+	"DELETE":  nil,
+	"CONNECT": nil,
+	"OPTIONS": nil,
+	"TRACE":   nil,
+	"PURGE":   nil,
+	"BAN":     nil,
+}
+
+// NormalizeMethod trims and uppercases an HTTP method. It returns an error if the method is not a valid RFC 7230 token,
+// so that custom methods, e.g. the REFRESH of a caching proxy, are supported. See IsKnownMethod.
+func NormalizeMethod(method string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(method))
+	if normalized == "" {
+		return "", fmt.Errorf("HTTP method is empty")
+	}
+	if strings.IndexFunc(normalized, func(r rune) bool { return !isTokenChar(r) }) >= 0 {
+		return "", fmt.Errorf("method %s is not a valid HTTP method, expected a token such as GET or PURGE", normalized)
+	}
+	return normalized, nil
+}
+
+// IsKnownMethod returns true if the normalized method is a standard HTTP method or one understood by caching proxies,
+// i.e. PURGE or BAN.
+func IsKnownMethod(method string) bool {
+	_, ok := knownHTTPMethods[method]
+	return ok
+}
+
+// isTokenChar returns true if the character is allowed in an RFC 7230 token.
+func isTokenChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
@@ -0,0 +1,48 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeMethod(t *testing.T) {
+	for method, expected := range map[string]string{"GET": "GET", "get": "GET", " Post\t": "POST", "GET ": "GET", "purge": "PURGE", "refresh": "REFRESH", "PROPFIND": "PROPFIND", "M-SEARCH": "M-SEARCH"} {
+		normalized, err := NormalizeMethod(method)
+		require.NoError(t, err, method)
+		assert.Equal(t, expected, normalized, method)
+	}
+}
+
+func TestNormalizeMethod_Invalid(t *testing.T) {
+	_, err := NormalizeMethod(" inv@lid ")
+	assert.EqualError(t, err, "method INV@LID is not a valid HTTP method, expected a token such as GET or PURGE")
+
+	_, err = NormalizeMethod("GE T")
+	assert.Error(t, err)
+
+	_, err = NormalizeMethod("  ")
+	assert.EqualError(t, err, "HTTP method is empty")
+}
+
+func TestIsKnownMethod(t *testing.T) {
+	assert.True(t, IsKnownMethod("GET"))
+	assert.True(t, IsKnownMethod("PURGE"))
+	assert.False(t, IsKnownMethod("REFRESH"))
+	assert.False(t, IsKnownMethod("get"))
+}
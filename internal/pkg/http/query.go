@@ -0,0 +1,40 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"mittens/internal/pkg/placeholders"
+	"net/url"
+	"strings"
+)
+
+// WithQuery returns the request with its query parameters appended to the path, after any query already in the path.
+// The placeholders of the values are interpolated before the values are URL encoded, so that they are sent as a whole.
+func (r Request) WithQuery() Request {
+	if len(r.Query) == 0 {
+		return r
+	}
+	values := url.Values{}
+	for name, value := range r.Query {
+		values.Set(name, placeholders.InterpolatePlaceholders(value))
+	}
+	separator := "?"
+	if strings.Contains(r.Path, "?") {
+		separator = "&"
+	}
+	r.Path += separator + values.Encode()
+	r.Query = nil
+	return r
+}
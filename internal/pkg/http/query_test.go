@@ -0,0 +1,30 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithQuery(t *testing.T) {
+	request := Request{Method: "GET", Path: "/search", Query: map[string]string{"q": "café au lait", "page": "2"}}
+
+	assert.Equal(t, "/search?page=2&q=caf%C3%A9+au+lait", request.WithQuery().Path)
+	assert.Nil(t, request.WithQuery().Query)
+	assert.Equal(t, "/search?q=1&page=2", Request{Path: "/search?q=1", Query: map[string]string{"page": "2"}}.WithQuery().Path)
+	assert.Equal(t, "/search", Request{Path: "/search"}.WithQuery().Path)
+}
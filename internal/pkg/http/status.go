@@ -0,0 +1,71 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusCodes is a set of status codes with which a response succeeds. An empty set accepts the 2xx status codes.
+type StatusCodes []statusCodeRange
+
+// statusCodeRange holds the status codes from min to max, both included.
+type statusCodeRange struct {
+	min int
+	max int
+}
+
+// ParseStatusCodes parses status codes separated by |, each either a code, e.g. 404, a class, e.g. 3xx, or a range, e.g. 200-204.
+func ParseStatusCodes(value string) (StatusCodes, error) {
+	var codes StatusCodes
+	for _, part := range strings.Split(value, "|") {
+		part = strings.TrimSpace(part)
+		var r statusCodeRange
+		var err error
+		if class := strings.TrimSuffix(strings.ToLower(part), "xx"); class != strings.ToLower(part) {
+			var c int
+			c, err = strconv.Atoi(class)
+			r = statusCodeRange{min: c * 100, max: c*100 + 99}
+		} else if bounds := strings.SplitN(part, "-", 2); len(bounds) == 2 {
+			r.min, err = strconv.Atoi(strings.TrimSpace(bounds[0]))
+			if err == nil {
+				r.max, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			}
+		} else {
+			r.min, err = strconv.Atoi(part)
+			r.max = r.min
+		}
+		if err != nil || r.min < 100 || r.max > 599 || r.min > r.max {
+			return nil, fmt.Errorf("invalid status codes: %s, expected codes, classes or ranges separated by |, e.g. 2xx|404|300-308", value)
+		}
+		codes = append(codes, r)
+	}
+	return codes, nil
+}
+
+// Accepts returns true if the status code is in the set or, if the set is empty, if it is a 2xx status code.
+func (s StatusCodes) Accepts(statusCode int) bool {
+	if len(s) == 0 {
+		return statusCode/100 == 2
+	}
+	for _, r := range s {
+		if statusCode >= r.min && statusCode <= r.max {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	codes, err := ParseStatusCodes("2xx|404|300-308")
+	require.NoError(t, err)
+
+	for _, accepted := range []int{200, 204, 299, 301, 308, 404} {
+		assert.True(t, codes.Accepts(accepted), accepted)
+	}
+	for _, rejected := range []int{309, 400, 500} {
+		assert.False(t, codes.Accepts(rejected), rejected)
+	}
+}
+
+func TestParseStatusCodes_Invalid(t *testing.T) {
+	for _, value := range []string{"", "abc", "xx", "9xx", "404-400", "200-", "42"} {
+		_, err := ParseStatusCodes(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestStatusCodes_DefaultsTo2xx(t *testing.T) {
+	var codes StatusCodes
+
+	assert.True(t, codes.Accepts(200))
+	assert.False(t, codes.Accepts(301))
+	assert.False(t, codes.Accepts(404))
+}
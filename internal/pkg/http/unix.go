@@ -0,0 +1,41 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// unixSocketPrefix marks the hosts that are the path of a UNIX domain socket, e.g. unix:///var/run/app.sock.
+const unixSocketPrefix = "unix://"
+
+// unixSocketURL is the URL of the host of the requests sent over a UNIX domain socket.
+// Its host name is only a placeholder as the socket is dialled whatever the address.
+const unixSocketURL = "http://unix"
+
+// IsUnixSocket returns true if the host is the path of a UNIX domain socket in the unix:///path/to/socket form.
+func IsUnixSocket(host string) bool {
+	return strings.HasPrefix(host, unixSocketPrefix)
+}
+
+// unixSocketDialContext returns a function that connects to the UNIX domain socket at path whatever the address.
+func unixSocketDialContext(path string) func(ctx context.Context, network string, address string) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	return func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", path)
+	}
+}
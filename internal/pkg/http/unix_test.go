@@ -0,0 +1,59 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "app.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	var host, path string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		host, path = r.Host, r.URL.Path
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	c := newClient(t, "unix://"+socket, false, ClientOptions{})
+	resp := c.SendRequest("GET", "/ping", nil, nil)
+
+	require.NoError(t, resp.Err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "unix", host)
+	assert.Equal(t, "/ping", path)
+}
+
+func TestUnixSocket_NotListening(t *testing.T) {
+	c := newClient(t, "unix://"+filepath.Join(t.TempDir(), "missing.sock"), false, ClientOptions{})
+
+	assert.Error(t, c.SendRequest("GET", "/ping", nil, nil).Err)
+}
+
+func TestIsUnixSocket(t *testing.T) {
+	assert.True(t, IsUnixSocket("unix:///var/run/app.sock"))
+	assert.False(t, IsUnixSocket("http://localhost"))
+	assert.False(t, IsUnixSocket("/var/run/app.sock"))
+}
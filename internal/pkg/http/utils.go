@@ -16,8 +16,14 @@ package http
 
 import (
 	"fmt"
+	"log"
+	"mittens/internal/pkg/golden"
+	"mittens/internal/pkg/jsonschema"
+	"mittens/internal/pkg/marker"
 	"mittens/internal/pkg/placeholders"
+	"mittens/internal/pkg/requestoptions"
 	"strings"
+	"time"
 )
 
 // Request represents an HTTP request.
@@ -25,56 +31,134 @@ type Request struct {
 	Method string
 	Path   string
 	Body   *string
+	// Query holds query parameters appended to the path, URL encoded, when the request is sent, see WithQuery.
+	Query map[string]string
+	// BodyFile, if set and Body is nil, is the path of a file whose content is sent as the body, see WithBodyFromFile.
+	BodyFile string
+	// Burst is the number of times the request is sent back-to-back every time it is selected.
+	Burst int
+	// Golden, if set, holds the expected response body.
+	Golden *golden.File
+	// MaxLatency, if greater than 0, is the latency above which a successful response violates the latency SLO of the request.
+	MaxLatency time.Duration
+	// ContentType, if set, is sent as the Content-Type header unless the headers already set one.
+	ContentType string
+	// Conditional, if true, means the request is followed by the same request with If-None-Match set to the ETag of its response,
+	// which is expected to return 304 Not Modified.
+	Conditional bool
+	// ExpectedBodySubstring, if set, must be contained in the body of the response for the request to succeed.
+	ExpectedBodySubstring string
+	// Weight is how often the request is selected compared to the others. Weights lower than 1 count as 1.
+	Weight int
+	// Timeout, if greater than 0, is the time after which the request is cancelled instead of the timeout of the client.
+	Timeout time.Duration
+	// BodySchema, if set, is the JSON schema from which a new random body is generated every time the request is sent.
+	BodySchema *jsonschema.Schema
+	// Gzip, if true, means the body is compressed with gzip and sent with Content-Encoding: gzip.
+	Gzip bool
+	// AcceptedStatusCodes are the status codes with which the response succeeds, the 2xx ones if empty.
+	AcceptedStatusCodes StatusCodes
 }
 
-var allowedHTTPMethods = map[string]interface{}{
-	"GET":     nil,
-	"HEAD":    nil,
-	"POST":    nil,
-	"PUT":     nil,
-	"PATCH":   nil,
-	"DELETE":  nil,
-	"CONNECT": nil,
-	"OPTIONS": nil,
-	"TRACE":   nil,
-}
+// schemaPrefix marks request bodies generated from a JSON schema file, e.g. `schema:/schemas/order.json`.
+const schemaPrefix = "schema:"
+
+// JSONContentType is the content type of the request bodies generated from a JSON schema.
+const JSONContentType = "application/json"
 
-//
 // ToHTTPRequest parses an HTTP request which is in a string format and stores it in a struct.
 func ToHTTPRequest(requestString string) (Request, error) {
-	parts := strings.SplitN(requestString, ":", 3)
+	options, request, err := requestoptions.Parse(requestString, requestoptions.Burst, requestoptions.Golden, requestoptions.MaxLatency, requestoptions.Conditional, requestoptions.Timeout, requestoptions.Weight, requestoptions.ExpectBody, requestoptions.Gzip, requestoptions.Status)
+	if err != nil {
+		return Request{}, err
+	}
+	burst, err := options.PositiveInt(requestoptions.Burst, 1)
+	if err != nil {
+		return Request{}, err
+	}
+	goldenFile, err := options.GoldenFile()
+	if err != nil {
+		return Request{}, err
+	}
+	maxLatency, err := options.PositiveDuration(requestoptions.MaxLatency)
+	if err != nil {
+		return Request{}, err
+	}
+	conditional, err := options.Bool(requestoptions.Conditional)
+	if err != nil {
+		return Request{}, err
+	}
+	timeout, err := options.PositiveDuration(requestoptions.Timeout)
+	if err != nil {
+		return Request{}, err
+	}
+	weight, err := options.PositiveInt(requestoptions.Weight, 1)
+	if err != nil {
+		return Request{}, err
+	}
+	gzip, err := options.Bool(requestoptions.Gzip)
+	if err != nil {
+		return Request{}, err
+	}
+	var acceptedStatusCodes StatusCodes
+	if status, ok := options[requestoptions.Status]; ok {
+		if acceptedStatusCodes, err = ParseStatusCodes(status); err != nil {
+			return Request{}, err
+		}
+	}
+
+	parts := strings.SplitN(request, ":", 3)
 	if len(parts) < 2 {
 		return Request{}, fmt.Errorf("invalid request flag: %s, expected format <http-method>:<path>[:body]", requestString)
 	}
 
-	method := strings.ToUpper(parts[0])
-	_, ok := allowedHTTPMethods[method]
-	if !ok {
-		return Request{}, fmt.Errorf("invalid request flag: %s, method %s is not supported", requestString, method)
+	method, err := NormalizeMethod(parts[0])
+	if err != nil {
+		return Request{}, fmt.Errorf("invalid request flag: %s, %v", requestString, err)
+	}
+	if !IsKnownMethod(method) {
+		log.Printf("%s HTTP method %s of request %s is not a standard one, check it is not a typo", marker.Warning(), method, requestString)
 	}
 
 	// <method>:<path>
+	// placeholders in the path and the body are interpolated every time the request is sent
+	parsed := Request{
+		Method:                method,
+		Path:                  parts[1],
+		Burst:                 burst,
+		Golden:                goldenFile,
+		MaxLatency:            maxLatency,
+		Timeout:               timeout,
+		Weight:                weight,
+		ExpectedBodySubstring: options[requestoptions.ExpectBody],
+		Conditional:           conditional,
+		Gzip:                  gzip,
+		AcceptedStatusCodes:   acceptedStatusCodes,
+	}
 	if len(parts) == 2 {
-		path := placeholders.InterpolatePlaceholders(parts[1])
-
-		return Request{
-			Method: method,
-			Path:   path,
-			Body:   nil,
-		}, nil
+		return parsed, nil
 	}
 
-	path := placeholders.InterpolatePlaceholders(parts[1])
-	// the body of the request can either be inlined, or come from a file
-	rawBody, err := placeholders.GetBodyFromFileOrInlined(parts[2])
-	if err != nil {
-		return Request{}, fmt.Errorf("unable to parse body for request: %s", parts[2])
+	switch {
+	case strings.HasPrefix(parts[2], formPrefix):
+		body := encodeForm(strings.TrimPrefix(parts[2], formPrefix))
+		parsed.Body = &body
+		parsed.ContentType = FormContentType
+	case strings.HasPrefix(parts[2], schemaPrefix):
+		schemaFile := strings.TrimPrefix(parts[2], schemaPrefix)
+		schema, err := jsonschema.Load(schemaFile)
+		if err != nil {
+			return Request{}, fmt.Errorf("unable to load JSON schema %s: %v", schemaFile, err)
+		}
+		parsed.BodySchema = schema
+		parsed.ContentType = JSONContentType
+	default:
+		// the body of the request can either be inlined, or come from a file
+		rawBody, err := placeholders.GetBodyFromFileOrInlined(parts[2])
+		if err != nil {
+			return Request{}, fmt.Errorf("unable to parse body for request: %s", parts[2])
+		}
+		parsed.Body = rawBody
 	}
-	var body = placeholders.InterpolatePlaceholders(*rawBody)
-
-	return Request{
-		Method: method,
-		Path:   path,
-		Body:   &body,
-	}, nil
+	return parsed, nil
 }
@@ -17,8 +17,8 @@ package http
 import (
 	"net/http"
 	"os"
-	"regexp"
 	"testing"
+	"time"
 
 	"mittens/internal/pkg/internal"
 
@@ -59,44 +59,159 @@ func TestHttp_FlagWithoutBodyToHttpRequest(t *testing.T) {
 	assert.Equal(t, http.MethodGet, request.Method)
 	assert.Equal(t, "ping", request.Path)
 	assert.Nil(t, request.Body)
+	assert.Equal(t, 1, request.Burst)
 }
 
 func TestHttp_FlagWithInvalidMethodToHttpRequest(t *testing.T) {
-	requestFlag := `hmm:/ping:all=true`
+	requestFlag := `h(m)m:/ping:all=true`
 	_, err := ToHTTPRequest(requestFlag)
 	require.Error(t, err)
 }
 
-func TestHttp_TimestampInterpolation(t *testing.T) {
-	requestFlag := `post:/path_{$currentTimestamp}:{"body": "{$currentTimestamp}"}`
+func TestHttp_FlagWithCustomMethodToHttpRequest(t *testing.T) {
+	request, err := ToHTTPRequest(`refresh:/ping`)
+	require.NoError(t, err)
+	assert.Equal(t, "REFRESH", request.Method)
+}
+
+func TestHttp_FlagWithBurstToHttpRequest(t *testing.T) {
+	requestFlag := `[burst=3]get:/ping`
 	request, err := ToHTTPRequest(requestFlag)
 	require.NoError(t, err)
 
-	assert.Equal(t, http.MethodPost, request.Method)
+	assert.Equal(t, http.MethodGet, request.Method)
+	assert.Equal(t, "/ping", request.Path)
+	assert.Equal(t, 3, request.Burst)
+}
+
+func TestHttp_FlagWithInvalidBurstToHttpRequest(t *testing.T) {
+	requestFlag := `[burst=0]get:/ping`
+	_, err := ToHTTPRequest(requestFlag)
+	require.Error(t, err)
+}
+
+func TestHttp_FlagWithMaxLatencyToHttpRequest(t *testing.T) {
+	requestFlag := `[burst=2,max-latency=250ms]post:/ping:{}`
+	request, err := ToHTTPRequest(requestFlag)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, request.Burst)
+	assert.Equal(t, 250*time.Millisecond, request.MaxLatency)
+
+	_, err = ToHTTPRequest(`[max-latency=250]get:/ping`)
+	require.Error(t, err)
+}
+
+func TestHttp_FlagWithConditionalToHttpRequest(t *testing.T) {
+	request, err := ToHTTPRequest(`[conditional=true]get:/logo.png`)
+	require.NoError(t, err)
+	assert.True(t, request.Conditional)
+
+	request, err = ToHTTPRequest(`get:/logo.png`)
+	require.NoError(t, err)
+	assert.False(t, request.Conditional)
+}
+
+func TestHttp_FlagWithGzipToHttpRequest(t *testing.T) {
+	request, err := ToHTTPRequest(`[gzip=true]post:/orders:{"id": 1}`)
+	require.NoError(t, err)
+	assert.True(t, request.Gzip)
+	assert.Empty(t, request.WithContentType([]string{}))
+
+	request, err = ToHTTPRequest(`post:/orders:{"id": 1}`)
+	require.NoError(t, err)
+	assert.False(t, request.Gzip)
+
+	_, err = ToHTTPRequest(`[gzip=maybe]post:/orders:{"id": 1}`)
+	assert.Error(t, err)
+}
+
+func TestHttp_FlagWithStatusToHttpRequest(t *testing.T) {
+	request, err := ToHTTPRequest(`[status=2xx|404,burst=2]get:/missing`)
+	require.NoError(t, err)
+	assert.True(t, request.AcceptedStatusCodes.Accepts(404))
+	assert.Equal(t, 2, request.Burst)
 
-	var numbersRegex = regexp.MustCompile("\\d+")
-	matchPath := numbersRegex.MatchString(request.Path)
-	matchBody := numbersRegex.MatchString(*request.Body)
+	_, err = ToHTTPRequest(`[status=4o4]get:/missing`)
+	assert.ErrorContains(t, err, "invalid status codes: 4o4")
+}
+
+func TestHttp_FlagWithTimeoutToHttpRequest(t *testing.T) {
+	request, err := ToHTTPRequest(`[timeout=30s]get:/slow`)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, request.Timeout)
+
+	_, err = ToHTTPRequest(`[timeout=0s]get:/slow`)
+	require.Error(t, err)
+}
+
+func TestHttp_FlagWithWeightToHttpRequest(t *testing.T) {
+	request, err := ToHTTPRequest(`[weight=5]get:/hot`)
+	require.NoError(t, err)
+	assert.Equal(t, 5, request.Weight)
+
+	request, err = ToHTTPRequest(`get:/cold`)
+	require.NoError(t, err)
+	assert.Equal(t, 1, request.Weight)
+
+	_, err = ToHTTPRequest(`[weight=0]get:/cold`)
+	require.Error(t, err)
+}
+
+func TestHttp_FlagWithExpectBodyToHttpRequest(t *testing.T) {
+	request, err := ToHTTPRequest(`[expect-body="status":"UP"]get:/health`)
+	require.NoError(t, err)
+	assert.Equal(t, `"status":"UP"`, request.ExpectedBodySubstring)
+	assert.Equal(t, "/health", request.Path)
 
-	assert.True(t, matchPath)
-	assert.True(t, matchBody)
-	assert.Equal(t, len(request.Path), 19)  //  "path_ + 13 numbers for timestamp
-	assert.Equal(t, len(*request.Body), 25) // { "body": 13 numbers for timestamp
+	request, err = ToHTTPRequest(`[expect-body='"status":"UP","checks":[]',burst=2]get:/health`)
+	require.NoError(t, err)
+	assert.Equal(t, `"status":"UP","checks":[]`, request.ExpectedBodySubstring)
+	assert.Equal(t, 2, request.Burst)
+	assert.Equal(t, "/health", request.Path)
 }
 
-func TestHttp_Interpolation(t *testing.T) {
-	requestFlag := `post:/path_{$range|min=1,max=2}_{$random|foo,bar}:{"body": "{$random|foo,bar} {$range|min=1,max=2}"}`
+func TestHttp_FlagWithFormBodyToHttpRequest(t *testing.T) {
+	requestFlag := `post:/login:form:user=john doe&tag=a&tag=b/c&note=1%262&name={$random|foo}`
 	request, err := ToHTTPRequest(requestFlag)
 	require.NoError(t, err)
 
 	assert.Equal(t, http.MethodPost, request.Method)
+	assert.Equal(t, "/login", request.Path)
+	assert.Equal(t, "user=john+doe&tag=a&tag=b%2Fc&note=1%262&name=foo", *request.Body)
+	assert.Equal(t, "application/x-www-form-urlencoded", request.ContentType)
+}
+
+func TestHttp_FlagWithSchemaBodyToHttpRequest(t *testing.T) {
+	file := internal.CreateTempFile(`{"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}}}`)
+	defer os.Remove(file)
 
-	var pathRegex = regexp.MustCompile(`/path_\d_(foo|bar)`)
-	matchPath := pathRegex.MatchString(request.Path)
+	request, err := ToHTTPRequest(`post:/orders:schema:` + file)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/orders", request.Path)
+	assert.Nil(t, request.Body)
+	require.NotNil(t, request.BodySchema)
+	assert.Equal(t, "application/json", request.ContentType)
 
-	var bodyRegex = regexp.MustCompile("{\"body\": \"(foo|bar) \\d\"}")
-	matchBody := bodyRegex.MatchString(*request.Body)
+	_, err = ToHTTPRequest(`post:/orders:schema:/this_file_does_not_exist.json`)
+	assert.ErrorContains(t, err, "unable to load JSON schema")
+}
 
-	assert.True(t, matchPath)
-	assert.True(t, matchBody)
+func TestHttp_WithContentType(t *testing.T) {
+	request := Request{ContentType: FormContentType}
+
+	assert.Equal(t, []string{"X-Foo: bar", "Content-Type: application/x-www-form-urlencoded"}, request.WithContentType([]string{"X-Foo: bar"}))
+	assert.Equal(t, []string{"content-type: text/plain"}, request.WithContentType([]string{"content-type: text/plain"}))
+	assert.Equal(t, []string{"X-Foo: bar"}, Request{}.WithContentType([]string{"X-Foo: bar"}))
+}
+
+func TestHttp_PlaceholdersAreKeptUntilSent(t *testing.T) {
+	requestFlag := `post:/path_{$range|min=1,max=2}_{$random|foo,bar}:{"body": "{$random|foo,bar} {$currentTimestamp}"}`
+	request, err := ToHTTPRequest(requestFlag)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.MethodPost, request.Method)
+	assert.Equal(t, "/path_{$range|min=1,max=2}_{$random|foo,bar}", request.Path)
+	assert.Equal(t, `{"body": "{$random|foo,bar} {$currentTimestamp}"}`, *request.Body)
 }
@@ -0,0 +1,172 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Rand is the source of randomness of the generated documents, e.g. a *rand.Rand.
+type Rand interface {
+	Intn(n int) int
+	Float64() float64
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Generate returns a random JSON document that is valid against the schema: required properties are always present,
+// optional ones half of the time, and values respect their type, enum, const and bounds.
+func (s *Schema) Generate(rnd Rand) string {
+	document, err := json.Marshal(s.value(s, rnd))
+	if err != nil {
+		// values are built from JSON types only
+		panic(err)
+	}
+	return string(document)
+}
+
+func (s *Schema) value(root *Schema, rnd Rand) interface{} {
+	if s.Ref != "" {
+		target, _ := root.resolve(s.Ref)
+		return target.value(root, rnd)
+	}
+	if len(s.Const) > 0 {
+		return s.Const
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[rnd.Intn(len(s.Enum))]
+	}
+	if options := append(append([]*Schema{}, s.AnyOf...), s.OneOf...); len(options) > 0 {
+		return options[rnd.Intn(len(options))].value(root, rnd)
+	}
+
+	switch s.pickType(rnd) {
+	case "object":
+		object := make(map[string]interface{})
+		required := make(map[string]bool)
+		for _, name := range s.Required {
+			required[name] = true
+		}
+		// in a stable order so that the same random values give the same document
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if required[name] || rnd.Intn(2) == 0 {
+				object[name] = s.Properties[name].value(root, rnd)
+			}
+		}
+		return object
+	case "array":
+		min, max := lengthBounds(s.MinItems, s.MaxItems, 3)
+		items := make([]interface{}, min+rnd.Intn(max-min+1))
+		itemSchema := s.Items
+		if itemSchema == nil {
+			itemSchema = &Schema{Type: "string"}
+		}
+		for i := range items {
+			items[i] = itemSchema.value(root, rnd)
+		}
+		return items
+	case "integer":
+		min, max := s.bounds()
+		lo, hi := math.Ceil(min), math.Floor(max)
+		if s.ExclusiveMinimum != nil && lo == *s.ExclusiveMinimum {
+			lo++
+		}
+		if s.ExclusiveMaximum != nil && hi == *s.ExclusiveMaximum {
+			hi--
+		}
+		if hi < lo {
+			hi = lo
+		}
+		if hi-lo < math.MaxInt32 {
+			return int64(lo) + int64(rnd.Intn(int(hi-lo)+1))
+		}
+		return int64(lo + math.Floor(rnd.Float64()*(hi-lo)))
+	case "number":
+		min, max := s.bounds()
+		return min + rnd.Float64()*(max-min)
+	case "boolean":
+		return rnd.Intn(2) == 0
+	case "null":
+		return nil
+	default:
+		return s.stringValue(rnd)
+	}
+}
+
+// pickType returns one of the types of the schema, or the type implied by its keywords if it has none.
+func (s *Schema) pickType(rnd Rand) string {
+	types := s.types()
+	switch {
+	case len(types) > 0:
+		return types[rnd.Intn(len(types))]
+	case s.Properties != nil:
+		return "object"
+	case s.Items != nil:
+		return "array"
+	case s.Minimum != nil || s.Maximum != nil:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func (s *Schema) stringValue(rnd Rand) string {
+	switch s.Format {
+	case "date-time":
+		return randomTime(rnd).Format(time.RFC3339)
+	case "date":
+		return randomTime(rnd).Format("2006-01-02")
+	case "uuid":
+		return fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x", rnd.Intn(math.MaxInt32), rnd.Intn(1<<16), rnd.Intn(1<<12), 0x8000|rnd.Intn(1<<14), int64(rnd.Float64()*(1<<48)))
+	case "email":
+		return fmt.Sprintf("user%d@example.com", rnd.Intn(100000))
+	}
+	min, max := lengthBounds(s.MinLength, s.MaxLength, 16)
+	b := make([]byte, min+rnd.Intn(max-min+1))
+	for i := range b {
+		b[i] = letters[rnd.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// randomTime returns a time within a year around now.
+func randomTime(rnd Rand) time.Time {
+	return time.Now().UTC().Add(time.Duration(rnd.Intn(365*24)-182*24) * time.Hour).Truncate(time.Second)
+}
+
+// lengthBounds returns the inclusive range of lengths allowed by min and max, spanning span if max is not set.
+func lengthBounds(min *int, max *int, span int) (int, int) {
+	lo := 0
+	if min != nil && *min > 0 {
+		lo = *min
+	}
+	hi := lo + span
+	if max != nil {
+		hi = *max
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
+}
@@ -0,0 +1,185 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Random JSON documents that are valid against a JSON Schema, used as request bodies.
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema that documents can be generated from.
+type Schema struct {
+	Type                 interface{}        `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                json.RawMessage    `json:"const"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+	Ref                  string             `json:"$ref"`
+	Definitions          map[string]*Schema `json:"definitions"`
+	Defs                 map[string]*Schema `json:"$defs"`
+	Format               string             `json:"format"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Pattern              string             `json:"pattern"`
+	AllOf                []*Schema          `json:"allOf"`
+	Not                  *Schema            `json:"not"`
+	AdditionalProperties interface{}        `json:"additionalProperties"`
+}
+
+var types = map[string]bool{"object": true, "array": true, "string": true, "integer": true, "number": true, "boolean": true, "null": true}
+
+// Load reads a JSON Schema file and checks that documents can be generated from it.
+func Load(path string) (*Schema, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(content)
+}
+
+// Parse parses a JSON Schema and checks that documents can be generated from it.
+func Parse(content []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+	if err := schema.check(&schema, "#", 0); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// maxDepth bounds the nesting of the schemas, which also stops recursive references.
+const maxDepth = 32
+
+// check returns an error if the schema, at the given location of root, uses a keyword that is not supported or has impossible constraints.
+func (s *Schema) check(root *Schema, location string, depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("%s: schema nested too deeply, recursive schemas are not supported", location)
+	}
+	if s.Ref != "" {
+		target, err := root.resolve(s.Ref)
+		if err != nil {
+			return fmt.Errorf("%s: %v", location, err)
+		}
+		return target.check(root, s.Ref, depth+1)
+	}
+	if s.Pattern != "" || len(s.AllOf) > 0 || s.Not != nil {
+		return fmt.Errorf("%s: pattern, allOf and not are not supported", location)
+	}
+	for _, t := range s.types() {
+		if !types[t] {
+			return fmt.Errorf("%s: unknown type %s", location, t)
+		}
+	}
+	if s.Minimum != nil && s.Maximum != nil && *s.Minimum > *s.Maximum {
+		return fmt.Errorf("%s: minimum is greater than maximum", location)
+	}
+	if s.MinLength != nil && s.MaxLength != nil && *s.MinLength > *s.MaxLength {
+		return fmt.Errorf("%s: minLength is greater than maxLength", location)
+	}
+	if s.MinItems != nil && s.MaxItems != nil && *s.MinItems > *s.MaxItems {
+		return fmt.Errorf("%s: minItems is greater than maxItems", location)
+	}
+	for name, property := range s.Properties {
+		if err := property.check(root, location+"/properties/"+name, depth+1); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		if err := s.Items.check(root, location+"/items", depth+1); err != nil {
+			return err
+		}
+	}
+	for i, option := range append(append([]*Schema{}, s.AnyOf...), s.OneOf...) {
+		if err := option.check(root, fmt.Sprintf("%s/anyOf/%d", location, i), depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve returns the schema a local reference, e.g. #/definitions/Address or #/$defs/Address, points to.
+func (s *Schema) resolve(ref string) (*Schema, error) {
+	var definitions map[string]*Schema
+	var name string
+	if strings.HasPrefix(ref, "#/definitions/") {
+		definitions, name = s.Definitions, strings.TrimPrefix(ref, "#/definitions/")
+	} else if strings.HasPrefix(ref, "#/$defs/") {
+		definitions, name = s.Defs, strings.TrimPrefix(ref, "#/$defs/")
+	} else {
+		return nil, fmt.Errorf("reference %s is not supported, only #/definitions/ and #/$defs/ references are", ref)
+	}
+	target, ok := definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("reference %s not found", ref)
+	}
+	return target, nil
+}
+
+// types returns the types allowed by the schema, which may be none.
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var names []string
+		for _, name := range t {
+			names = append(names, fmt.Sprint(name))
+		}
+		return names
+	}
+	return nil
+}
+
+// bounds returns the inclusive range of numbers allowed by the schema. Missing bounds are set to span 1000 from the other one, or from 0.
+func (s *Schema) bounds() (float64, float64) {
+	min, max := math.Inf(-1), math.Inf(1)
+	if s.Minimum != nil {
+		min = *s.Minimum
+	}
+	if s.ExclusiveMinimum != nil {
+		min = math.Max(min, *s.ExclusiveMinimum)
+	}
+	if s.Maximum != nil {
+		max = *s.Maximum
+	}
+	if s.ExclusiveMaximum != nil {
+		max = math.Min(max, *s.ExclusiveMaximum)
+	}
+	switch {
+	case math.IsInf(min, -1) && math.IsInf(max, 1):
+		min, max = 0, 1000
+	case math.IsInf(min, -1):
+		min = max - 1000
+	case math.IsInf(max, 1):
+		max = min + 1000
+	}
+	return min, max
+}
@@ -0,0 +1,110 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"math/rand"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const orderSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"required": ["id", "status", "quantity", "items", "customer"],
+	"properties": {
+		"id": {"type": "string", "format": "uuid"},
+		"status": {"enum": ["new", "paid", "shipped"]},
+		"quantity": {"type": "integer", "minimum": 1, "maximum": 5},
+		"price": {"type": "number", "exclusiveMinimum": 0, "maximum": 100},
+		"gift": {"type": "boolean"},
+		"version": {"const": 2},
+		"createdAt": {"type": "string", "format": "date-time"},
+		"note": {"type": ["string", "null"], "minLength": 2, "maxLength": 4},
+		"items": {"type": "array", "minItems": 1, "maxItems": 3, "items": {"type": "string", "minLength": 3, "maxLength": 3}},
+		"customer": {"$ref": "#/$defs/customer"}
+	},
+	"$defs": {
+		"customer": {"type": "object", "required": ["email"], "properties": {"email": {"type": "string", "format": "email"}}}
+	}
+}`
+
+func TestGenerate(t *testing.T) {
+	schema, err := Parse([]byte(orderSchema))
+	require.NoError(t, err)
+	rnd := rand.New(rand.NewSource(1))
+
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		var order map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(schema.Generate(rnd)), &order))
+
+		assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), order["id"])
+		assert.Contains(t, []interface{}{"new", "paid", "shipped"}, order["status"])
+		quantity := order["quantity"].(float64)
+		assert.True(t, quantity >= 1 && quantity <= 5 && quantity == float64(int(quantity)), "quantity %v", quantity)
+		if price, ok := order["price"]; ok {
+			assert.True(t, price.(float64) > 0 && price.(float64) <= 100, "price %v", price)
+		}
+		if version, ok := order["version"]; ok {
+			assert.Equal(t, float64(2), version)
+		}
+		if createdAt, ok := order["createdAt"]; ok {
+			_, err := time.Parse(time.RFC3339, createdAt.(string))
+			assert.NoError(t, err)
+		}
+		if note, ok := order["note"].(string); ok {
+			assert.True(t, len(note) >= 2 && len(note) <= 4, "note %q", note)
+		}
+		items := order["items"].([]interface{})
+		assert.True(t, len(items) >= 1 && len(items) <= 3)
+		for _, item := range items {
+			assert.Len(t, item, 3)
+		}
+		assert.Regexp(t, `^user\d+@example.com$`, order["customer"].(map[string]interface{})["email"])
+		for key := range order {
+			seen[key] = true
+		}
+	}
+	// optional properties are sent some of the time
+	assert.Len(t, seen, 10)
+}
+
+func TestGenerate_Deterministic(t *testing.T) {
+	schema, err := Parse([]byte(orderSchema))
+	require.NoError(t, err)
+
+	first := schema.Generate(rand.New(rand.NewSource(7)))
+	assert.Equal(t, first, schema.Generate(rand.New(rand.NewSource(7))))
+}
+
+func TestParse_Unsupported(t *testing.T) {
+	for schema, message := range map[string]string{
+		`{"type": "string", "pattern": "^a+$"}`: "#: pattern, allOf and not are not supported",
+		`{"type": "strings"}`:                   "#: unknown type strings",
+		`{"properties": {"a": {"type": "integer", "minimum": 5, "maximum": 1}}}`:           "#/properties/a: minimum is greater than maximum",
+		`{"$ref": "#/definitions/missing"}`:                                                "#: reference #/definitions/missing not found",
+		`{"$ref": "other.json"}`:                                                           "#: reference other.json is not supported, only #/definitions/ and #/$defs/ references are",
+		`{"$ref": "#/$defs/node", "$defs": {"node": {"items": {"$ref": "#/$defs/node"}}}}`: "schema nested too deeply",
+	} {
+		_, err := Parse([]byte(schema))
+		assert.ErrorContains(t, err, message, schema)
+	}
+}
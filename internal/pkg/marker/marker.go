@@ -0,0 +1,93 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Markers that prefix log lines to flag successes, failures, warnings and slow requests.
+
+package marker
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// Auto uses emoji if the logs are written to a terminal and plain text otherwise.
+	Auto = "auto"
+	// Emoji uses 🟢, 🔴, ⚠️ and 🐢.
+	Emoji = "emoji"
+	// Color uses text highlighted with ANSI colors.
+	Color = "color"
+	// Plain uses plain text.
+	Plain = "plain"
+)
+
+type markers struct {
+	success string
+	failure string
+	warning string
+	slow    string
+}
+
+var markersByMode = map[string]markers{
+	Emoji: {success: "🟢", failure: "🔴", warning: "⚠️", slow: "🐢"},
+	Color: {success: "\033[32mOK\033[0m", failure: "\033[31mERR\033[0m", warning: "\033[33mWARN\033[0m", slow: "\033[35mSLOW\033[0m"},
+	Plain: {success: "OK", failure: "ERR", warning: "WARN", slow: "SLOW"},
+}
+
+var current = markersByMode[Emoji]
+
+// SetMode sets the markers used from now on. One of auto, emoji, color, or plain.
+func SetMode(mode string) error {
+	if mode == Auto {
+		mode = Plain
+		if isTerminal(os.Stderr) {
+			mode = Emoji
+		}
+	}
+	m, ok := markersByMode[mode]
+	if !ok {
+		return fmt.Errorf("markers %s not supported, please use %s, %s, %s or %s", mode, Auto, Emoji, Color, Plain)
+	}
+	current = m
+	return nil
+}
+
+// Success returns the marker of a successful outcome.
+func Success() string {
+	return current.success
+}
+
+// Failure returns the marker of a failed outcome.
+func Failure() string {
+	return current.failure
+}
+
+// Warning returns the marker of a warning.
+func Warning() string {
+	return current.warning
+}
+
+// Slow returns the marker of a request slower than the slow request threshold.
+func Slow() string {
+	return current.slow
+}
+
+// isTerminal returns true if the file is a terminal (character device) rather than e.g. a pipe or a regular file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
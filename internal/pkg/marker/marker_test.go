@@ -0,0 +1,60 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package marker
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMode(t *testing.T) {
+	defer SetMode(Emoji)
+
+	err := SetMode(Plain)
+	assert.NoError(t, err)
+	assert.Equal(t, "OK", Success())
+	assert.Equal(t, "ERR", Failure())
+	assert.Equal(t, "WARN", Warning())
+	assert.Equal(t, "SLOW", Slow())
+
+	err = SetMode(Color)
+	assert.NoError(t, err)
+	assert.Equal(t, "\033[32mOK\033[0m", Success())
+
+	err = SetMode(Emoji)
+	assert.NoError(t, err)
+	assert.Equal(t, "🟢", Success())
+	assert.Equal(t, "🔴", Failure())
+	assert.Equal(t, "🐢", Slow())
+}
+
+func TestSetModeAutoFallsBackToPlainWhenNotATerminal(t *testing.T) {
+	defer SetMode(Emoji)
+
+	// stderr is not a terminal when running under go test
+	if isTerminal(os.Stderr) {
+		t.Skip("stderr is a terminal")
+	}
+	err := SetMode(Auto)
+	assert.NoError(t, err)
+	assert.Equal(t, "OK", Success())
+}
+
+func TestSetModeUnknown(t *testing.T) {
+	err := SetMode("sparkles")
+	assert.Error(t, err)
+}
@@ -0,0 +1,184 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Metrics of the warmup exposed in the Prometheus text format.
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mittens/internal/pkg/safe"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Path is the path of the endpoint that serves the metrics.
+const Path = "/metrics"
+
+// contentType is the content type of the Prometheus text format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// durationBuckets are the upper bounds, in seconds, of the buckets of the request duration histogram.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics holds the counters of the requests sent and failed, the histogram of their durations, all by protocol,
+// and the gauge of the concurrency. It is safe for concurrent use. A nil *Metrics records nothing, so that the
+// metrics are only collected when requested.
+type Metrics struct {
+	mu        sync.Mutex
+	sent      map[string]int64
+	failed    map[string]int64
+	durations map[string]*histogram
+	workers   int64
+}
+
+// histogram counts observations in cumulative buckets like a Prometheus histogram.
+type histogram struct {
+	// counts[i] is the number of observations lower than or equal to durationBuckets[i]
+	counts []int64
+	count  int64
+	sum    float64
+}
+
+// New returns metrics with no request recorded.
+func New() *Metrics {
+	return &Metrics{sent: make(map[string]int64), failed: make(map[string]int64), durations: make(map[string]*histogram)}
+}
+
+// RecordRequest records a request of a protocol, e.g. http or grpc, whatever its outcome, along with its duration.
+func (m *Metrics) RecordRequest(protocol string, duration time.Duration, ok bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent[protocol]++
+	if !ok {
+		m.failed[protocol]++
+	}
+	h, found := m.durations[protocol]
+	if !found {
+		h = &histogram{counts: make([]int64, len(durationBuckets))}
+		m.durations[protocol] = h
+	}
+	seconds := duration.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// AddWorkers changes the number of workers currently sending requests by delta.
+func (m *Metrics) AddWorkers(delta int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.workers += int64(delta)
+}
+
+// Write writes the metrics in the Prometheus text format. Protocols are sorted so that the output is stable.
+func (m *Metrics) Write(out io.Writer) error {
+	for _, line := range m.lines() {
+		if _, err := fmt.Fprintln(out, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lines returns the lines of the text format, taken at once so that slow scrapes do not hold up the workers.
+func (m *Metrics) lines() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var protocols []string
+	for protocol := range m.sent {
+		protocols = append(protocols, protocol)
+	}
+	sort.Strings(protocols)
+
+	var lines []string
+	lines = append(lines,
+		"# HELP mittens_requests_total Number of warmup requests sent, whatever their outcome, by protocol.",
+		"# TYPE mittens_requests_total counter")
+	for _, protocol := range protocols {
+		lines = append(lines, fmt.Sprintf(`mittens_requests_total{protocol="%s"} %d`, protocol, m.sent[protocol]))
+	}
+	lines = append(lines,
+		"# HELP mittens_request_failures_total Number of warmup requests that failed, by protocol.",
+		"# TYPE mittens_request_failures_total counter")
+	for _, protocol := range protocols {
+		lines = append(lines, fmt.Sprintf(`mittens_request_failures_total{protocol="%s"} %d`, protocol, m.failed[protocol]))
+	}
+	lines = append(lines,
+		"# HELP mittens_request_duration_seconds Duration of the warmup requests, by protocol.",
+		"# TYPE mittens_request_duration_seconds histogram")
+	for _, protocol := range protocols {
+		h := m.durations[protocol]
+		for i, bound := range durationBuckets {
+			lines = append(lines, fmt.Sprintf(`mittens_request_duration_seconds_bucket{protocol="%s",le="%s"} %d`, protocol, formatFloat(bound), h.counts[i]))
+		}
+		lines = append(lines,
+			fmt.Sprintf(`mittens_request_duration_seconds_bucket{protocol="%s",le="+Inf"} %d`, protocol, h.count),
+			fmt.Sprintf(`mittens_request_duration_seconds_sum{protocol="%s"} %s`, protocol, formatFloat(h.sum)),
+			fmt.Sprintf(`mittens_request_duration_seconds_count{protocol="%s"} %d`, protocol, h.count))
+	}
+	lines = append(lines,
+		"# HELP mittens_concurrency Number of workers currently sending warmup requests.",
+		"# TYPE mittens_concurrency gauge",
+		fmt.Sprintf("mittens_concurrency %d", m.workers))
+	return lines
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Handler returns the handler of the metrics endpoint. It can be mounted on any server.
+func (m *Metrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		if err := m.Write(w); err != nil {
+			log.Printf("Cannot write the metrics: %v", err)
+		}
+	})
+	return mux
+}
+
+// Start serves the metrics on address in the background.
+func Start(address string, m *Metrics) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	log.Printf("Metrics endpoint listening on %s", listener.Addr())
+	go safe.Do(func() {
+		if err := http.Serve(listener, m.Handler()); err != nil {
+			log.Printf("Metrics endpoint stopped: %v", err)
+		}
+	})
+	return nil
+}
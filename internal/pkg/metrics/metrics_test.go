@@ -0,0 +1,55 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.RecordRequest("http", 3*time.Millisecond, true)
+	m.RecordRequest("http", 200*time.Millisecond, false)
+	m.RecordRequest("grpc", 20*time.Second, true)
+	m.AddWorkers(3)
+	m.AddWorkers(-1)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, contentType, rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Contains(t, body, "# TYPE mittens_requests_total counter\nmittens_requests_total{protocol=\"grpc\"} 1\nmittens_requests_total{protocol=\"http\"} 2\n")
+	assert.Contains(t, body, "mittens_request_failures_total{protocol=\"grpc\"} 0\nmittens_request_failures_total{protocol=\"http\"} 1\n")
+	assert.Contains(t, body, "mittens_request_duration_seconds_bucket{protocol=\"http\",le=\"0.005\"} 1\n")
+	assert.Contains(t, body, "mittens_request_duration_seconds_bucket{protocol=\"http\",le=\"0.25\"} 2\n")
+	assert.Contains(t, body, "mittens_request_duration_seconds_bucket{protocol=\"grpc\",le=\"10\"} 0\n")
+	assert.Contains(t, body, "mittens_request_duration_seconds_bucket{protocol=\"grpc\",le=\"+Inf\"} 1\n")
+	assert.Contains(t, body, "mittens_request_duration_seconds_sum{protocol=\"http\"} 0.203\n")
+	assert.Contains(t, body, "mittens_request_duration_seconds_count{protocol=\"http\"} 2\n")
+	assert.Contains(t, body, "# TYPE mittens_concurrency gauge\nmittens_concurrency 2\n")
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *Metrics
+	m.RecordRequest("http", time.Millisecond, true)
+	m.AddWorkers(1)
+}
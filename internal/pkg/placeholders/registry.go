@@ -0,0 +1,72 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package placeholders
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var placeholderNameRegex = regexp.MustCompile(`^\w+$`)
+
+// providers resolve the placeholders by name. A provider gets the whole placeholder, e.g. {$range|min=1,max=9},
+// so that it can read its arguments.
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]func(placeholder string) string)
+)
+
+func init() {
+	register("currentDate", dateElements)
+	register("currentTimestamp", func(string) string { return timestampElements() })
+	register("random", randomElements)
+	register("range", rangeElements)
+	register("uuid", func(string) string { return UUID() })
+}
+
+// Register adds the placeholder {$name}, which is replaced with the value returned by fn every time a request is sent,
+// e.g. Register("tenant", func() string { return os.Getenv("TENANT") }).
+// It is meant to be called at startup and panics if the name is not made of word characters, is reserved for the
+// worker placeholders or is already registered.
+func Register(name string, fn func() string) {
+	register(name, func(string) string { return fn() })
+}
+
+func register(name string, provider func(placeholder string) string) {
+	if !placeholderNameRegex.MatchString(name) {
+		panic(fmt.Sprintf("placeholders: invalid name %q, only word characters are allowed", name))
+	}
+	if strings.HasPrefix("{$"+name, workerPrefix) {
+		panic(fmt.Sprintf("placeholders: name %q is reserved for the worker placeholders", name))
+	}
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("placeholders: %q is already registered", name))
+	}
+	providers[name] = provider
+}
+
+// provider returns the provider of a placeholder, looked up by the name before its arguments.
+func provider(placeholder string) (func(placeholder string) string, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(placeholder, "{$"), "}")
+	name, _, _ = strings.Cut(name, "|")
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
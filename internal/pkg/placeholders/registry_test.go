@@ -0,0 +1,43 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package placeholders
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	calls := 0
+	Register("testCounter", func() string {
+		calls++
+		return strconv.Itoa(calls)
+	})
+
+	assert.Equal(t, "/items/1/2", InterpolatePlaceholders("/items/{$testCounter}/{$testCounter}"))
+	assert.Equal(t, "/items/3", InterpolatePlaceholders("/items/{$testCounter}"))
+}
+
+func TestRegisterRejectsInvalidNames(t *testing.T) {
+	assert.Panics(t, func() { Register("with space", func() string { return "" }) })
+	assert.Panics(t, func() { Register("workerTenant", func() string { return "" }) })
+	assert.Panics(t, func() { Register("uuid", func() string { return "" }) })
+}
+
+func TestUnknownPlaceholdersAreKept(t *testing.T) {
+	assert.Equal(t, `{"id": "{$unknown}", "seed": "{$workerSeed}"}`, InterpolatePlaceholders(`{"id": "{$unknown}", "seed": "{$workerSeed}"}`))
+}
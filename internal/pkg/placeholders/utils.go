@@ -15,9 +15,11 @@
 package placeholders
 
 import (
+	"crypto/rand"
+	"fmt"
 	"io/ioutil"
 	"log"
-	"math/rand"
+	mathrand "math/rand"
 	"regexp"
 	"strconv"
 	"strings"
@@ -79,10 +81,13 @@ func randomElements(source string) string {
 		return source
 	}
 
-	s := strings.Split(r[1], ",")
-	number := rand.Intn(len(s))
+	return pickElement(r[1], mathrand.Intn)
+}
 
-	return s[number]
+// pickElement returns an element of a comma-separated list selected with intn.
+func pickElement(elements string, intn func(n int) int) string {
+	s := strings.Split(elements, ",")
+	return s[intn(len(s))]
 }
 
 // rangeElements replaces range element placeholders with random integers within the specified range.
@@ -92,36 +97,51 @@ func rangeElements(source string) string {
 		return source
 	}
 
-	min, _ := strconv.Atoi(r[1])
-	max, _ := strconv.Atoi(r[2])
+	return pickInRange(source, r[1], r[2], mathrand.Intn)
+}
+
+// pickInRange returns an integer between min and max selected with intn, or source if the range is invalid.
+func pickInRange(source string, minValue string, maxValue string, intn func(n int) int) string {
+	min, _ := strconv.Atoi(minValue)
+	max, _ := strconv.Atoi(maxValue)
 
 	if min > max {
 		log.Printf("Invalid range. min > max")
 		return source
 	}
 
-	number := rand.Intn(max-min+1) + min
+	number := intn(max-min+1) + min
 
 	return strconv.Itoa(number)
 }
 
+// UUID returns a random (version 4) UUID.
+func UUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand should never fail, fall back to math/rand rather than sending an empty id
+		mathrand.Read(b)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
 // InterpolatePlaceholders scans a string and replaces placeholders with actual values.
-// At the moment this supports; dates, timestamps, random values from a list, and random integers.
+// It supports the built-in placeholders, i.e. dates, timestamps, random values from a list, random integers and UUIDs,
+// and the ones added with Register. Worker placeholders, see Worker, and unknown placeholders are left as they are.
 func InterpolatePlaceholders(source string) string {
 
 	return templatePlaceholderRegex.ReplaceAllStringFunc(source, func(templateString string) string {
 
-		if strings.Contains(templateString, "currentDate") {
-			return dateElements(templateString)
-		} else if strings.Contains(templateString, "currentTimestamp") {
-			return timestampElements()
-		} else if strings.Contains(templateString, "random") {
-			return randomElements(templateString)
-		} else if strings.Contains(templateString, "range") {
-			return rangeElements(templateString)
-		} else {
-			return source
+		if strings.HasPrefix(templateString, workerPrefix) {
+			// resolved by every worker with its own seed when the request is sent
+			return templateString
+		}
+		if provider, ok := provider(templateString); ok {
+			return provider(templateString)
 		}
+		return templateString
 	})
 }
 
@@ -107,3 +107,12 @@ func TestHttp_RandomElementInterpolation(t *testing.T) {
 
 	assert.True(t, matchOutput)
 }
+
+func TestHttp_UUIDInterpolation(t *testing.T) {
+	input := `get:/path_{$uuid}`
+	output := InterpolatePlaceholders(input)
+
+	var outputRegex = regexp.MustCompile("^get:/path_[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$")
+	assert.True(t, outputRegex.MatchString(output))
+	assert.NotEqual(t, output, InterpolatePlaceholders(input))
+}
@@ -0,0 +1,83 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package placeholders
+
+import (
+	mathrand "math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const workerPrefix = "{$worker"
+
+var workerPlaceholderRegex = regexp.MustCompile(`{\$worker\w*(?:\|[\w+-=,]+)?}`)
+var workerRangeRegex = regexp.MustCompile(`{\$workerRange\|min=(?P<Min>\d+),max=(?P<Max>\d+)}`)
+var workerElementsRegex = regexp.MustCompile(`{\$workerRandom\|(?P<Elements>[,\w-]+)}`)
+
+// Worker resolves the worker placeholders of the requests sent by a single worker:
+// {$workerSeed} is replaced with the seed of the worker while {$workerRandom|a,b} and {$workerRange|min=1,max=9}
+// work like their random and range counterparts but draw from a random stream of the worker seeded with its seed.
+// Like the other placeholders, they are resolved every time a request is sent. A Worker is not safe for concurrent use.
+type Worker struct {
+	Seed int64
+	rnd  *mathrand.Rand
+}
+
+// NewWorker returns the placeholders of a worker with the given seed.
+func NewWorker(seed int64) *Worker {
+	return &Worker{Seed: seed, rnd: mathrand.New(mathrand.NewSource(seed))}
+}
+
+// Interpolate replaces the worker placeholders of a string. Other placeholders are left as they are.
+func (w *Worker) Interpolate(source string) string {
+	if w == nil || !strings.Contains(source, workerPrefix) {
+		return source
+	}
+	return workerPlaceholderRegex.ReplaceAllStringFunc(source, func(templateString string) string {
+		if templateString == "{$workerSeed}" {
+			return strconv.FormatInt(w.Seed, 10)
+		} else if r := workerElementsRegex.FindStringSubmatch(templateString); r != nil {
+			return pickElement(r[1], w.rnd.Intn)
+		} else if r := workerRangeRegex.FindStringSubmatch(templateString); r != nil {
+			return pickInRange(templateString, r[1], r[2], w.rnd.Intn)
+		}
+		return templateString
+	})
+}
+
+// Rand returns the random stream of the worker, or a new one if w is nil.
+func (w *Worker) Rand() *mathrand.Rand {
+	if w == nil {
+		return mathrand.New(mathrand.NewSource(time.Now().UnixNano()))
+	}
+	return w.rnd
+}
+
+// InterpolateAll replaces the worker placeholders of every string. The strings are only copied if any of them changed.
+func (w *Worker) InterpolateAll(sources []string) []string {
+	for i, source := range sources {
+		if interpolated := w.Interpolate(source); interpolated != source {
+			copied := append([]string{}, sources...)
+			copied[i] = interpolated
+			for j := i + 1; j < len(copied); j++ {
+				copied[j] = w.Interpolate(copied[j])
+			}
+			return copied
+		}
+	}
+	return sources
+}
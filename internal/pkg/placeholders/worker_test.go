@@ -0,0 +1,64 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package placeholders
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorker_Interpolate(t *testing.T) {
+	worker := NewWorker(42)
+
+	assert.Equal(t, "/keys/42/items", worker.Interpolate("/keys/{$workerSeed}/items"))
+	assert.Contains(t, []string{"a", "b", "c"}, worker.Interpolate("{$workerRandom|a,b,c}"))
+	number, err := strconv.Atoi(worker.Interpolate("{$workerRange|min=10,max=20}"))
+	assert.NoError(t, err)
+	assert.True(t, number >= 10 && number <= 20)
+	// other placeholders are not resolved by the worker
+	assert.Equal(t, "{$uuid}", worker.Interpolate("{$uuid}"))
+}
+
+func TestWorker_DeterministicStreams(t *testing.T) {
+	sequence := func(seed int64) []string {
+		worker := NewWorker(seed)
+		var values []string
+		for i := 0; i < 10; i++ {
+			values = append(values, worker.Interpolate("{$workerRange|min=0,max=1000000}"))
+		}
+		return values
+	}
+
+	assert.Equal(t, sequence(1), sequence(1))
+	assert.NotEqual(t, sequence(1), sequence(2))
+}
+
+func TestWorker_InterpolateAll(t *testing.T) {
+	headers := []string{"X-Static: 1", "X-Worker: {$workerSeed}"}
+
+	interpolated := NewWorker(7).InterpolateAll(headers)
+	assert.Equal(t, []string{"X-Static: 1", "X-Worker: 7"}, interpolated)
+	// the original headers are shared by all the requests and must not change
+	assert.Equal(t, "X-Worker: {$workerSeed}", headers[1])
+
+	static := []string{"X-Static: 1"}
+	assert.Equal(t, &static[0], &NewWorker(7).InterpolateAll(static)[0])
+}
+
+func TestInterpolatePlaceholders_KeepsWorkerPlaceholders(t *testing.T) {
+	assert.Equal(t, "/keys/{$workerSeed}/{$workerRange|min=1,max=9}", InterpolatePlaceholders("/keys/{$workerSeed}/{$workerRange|min=1,max=9}"))
+}
@@ -0,0 +1,55 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Rate limiting of the warmup requests.
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket holding a single token that is refilled at a fixed rate. It is safe for concurrent use.
+// A nil limiter does not limit anything.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// New returns a limiter that allows requestsPerSecond requests per second, or nil if requestsPerSecond is not greater than 0.
+func New(requestsPerSecond float64) *Limiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &Limiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+// Wait blocks until a request is allowed.
+func (l *Limiter) Wait() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(wait)
+}
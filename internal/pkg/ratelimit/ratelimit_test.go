@@ -0,0 +1,54 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := New(100)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				limiter.Wait()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the first request is allowed immediately and the other 19 every 10ms
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+}
+
+func TestNilLimiter(t *testing.T) {
+	limiter := New(0)
+	assert.Nil(t, limiter)
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		limiter.Wait()
+	}
+	assert.Less(t, time.Since(start), 10*time.Millisecond)
+}
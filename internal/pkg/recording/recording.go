@@ -0,0 +1,156 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Recording of the warmup requests as newline-delimited JSON so that they can be replayed later.
+
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxLineBytes is the size of the longest line, i.e. request, that can be replayed.
+const maxLineBytes = 16 * 1024 * 1024
+
+// Entry is a request sent during the warmup along with a summary of its response.
+// Headers are not recorded as they often hold credentials.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Protocol string    `json:"protocol"`
+	// HTTP requests
+	Method      string  `json:"method,omitempty"`
+	Path        string  `json:"path,omitempty"`
+	Body        *string `json:"body,omitempty"`
+	ContentType string  `json:"contentType,omitempty"`
+	// gRPC requests
+	ServiceMethod string `json:"serviceMethod,omitempty"`
+	Message       string `json:"message,omitempty"`
+	// response summary
+	StatusCode int    `json:"statusCode,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Recorder appends entries to a file, one JSON object per line. It is safe for concurrent use.
+// A nil recorder does not record anything.
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// NewRecorder creates, or truncates, the file at path and returns a recorder that writes to it.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create recording %s: %v", path, err)
+	}
+	return &Recorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Record writes an entry. Entries are written straight to the file so that nothing is lost if mittens is killed.
+func (r *Recorder) Record(entry Entry) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.encoder.Encode(entry)
+}
+
+// Close closes the file.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// Replay reads the entries of a recording one at a time, without loading the whole file, and calls fn for each of them.
+func Replay(path string, fn func(entry Entry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open recording %s: %v", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("invalid recording %s, line %d: %v", path, line, err)
+		}
+		if err := fn(entry); err != nil {
+			return fmt.Errorf("invalid recording %s, line %d: %v", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read recording %s: %v", path, err)
+	}
+	return nil
+}
+
+// Requests returns the distinct HTTP and gRPC requests of a recording in the order they were first recorded.
+// Only the distinct requests are kept in memory, so large recordings can be replayed.
+func Requests(path string) ([]http.Request, []grpc.Request, error) {
+	var httpRequests []http.Request
+	var grpcRequests []grpc.Request
+	seen := make(map[string]bool)
+	err := Replay(path, func(entry Entry) error {
+		key, err := json.Marshal(Entry{Protocol: entry.Protocol, Method: entry.Method, Path: entry.Path, Body: entry.Body,
+			ContentType: entry.ContentType, ServiceMethod: entry.ServiceMethod, Message: entry.Message})
+		if err != nil {
+			return err
+		}
+		if seen[string(key)] {
+			return nil
+		}
+		seen[string(key)] = true
+
+		switch entry.Protocol {
+		case "http":
+			if entry.Method == "" {
+				return fmt.Errorf("missing method")
+			}
+			httpRequests = append(httpRequests, http.Request{Method: entry.Method, Path: entry.Path, Body: entry.Body, ContentType: entry.ContentType})
+		case "grpc":
+			if entry.ServiceMethod == "" {
+				return fmt.Errorf("missing serviceMethod")
+			}
+			grpcRequests = append(grpcRequests, grpc.Request{ServiceMethod: entry.ServiceMethod, Message: entry.Message})
+		default:
+			return fmt.Errorf("unknown protocol %q", entry.Protocol)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return httpRequests, grpcRequests, nil
+}
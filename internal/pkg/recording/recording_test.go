@@ -0,0 +1,70 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package recording
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+
+	body := `{"id": 1}`
+	require.NoError(t, recorder.Record(Entry{Protocol: "http", Method: "GET", Path: "/ping", StatusCode: 200, DurationMs: 3}))
+	require.NoError(t, recorder.Record(Entry{Protocol: "http", Method: "POST", Path: "/items", Body: &body, StatusCode: 201}))
+	require.NoError(t, recorder.Record(Entry{Protocol: "http", Method: "GET", Path: "/ping", StatusCode: 503, DurationMs: 9}))
+	require.NoError(t, recorder.Record(Entry{Protocol: "grpc", ServiceMethod: "health/ping", Message: `{"db": "true"}`, Error: "unavailable"}))
+	require.NoError(t, recorder.Close())
+
+	var entries int
+	require.NoError(t, Replay(path, func(entry Entry) error {
+		entries++
+		return nil
+	}))
+	assert.Equal(t, 4, entries)
+
+	httpRequests, grpcRequests, err := Requests(path)
+	require.NoError(t, err)
+	require.Len(t, httpRequests, 2, "duplicate requests are replayed once")
+	assert.Equal(t, "GET", httpRequests[0].Method)
+	assert.Equal(t, "/ping", httpRequests[0].Path)
+	assert.Nil(t, httpRequests[0].Body)
+	assert.Equal(t, body, *httpRequests[1].Body)
+	require.Len(t, grpcRequests, 1)
+	assert.Equal(t, "health/ping", grpcRequests[0].ServiceMethod)
+	assert.Equal(t, `{"db": "true"}`, grpcRequests[0].Message)
+}
+
+func TestReplay_InvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"protocol": "http", "method": "GET", "path": "/"}`+"\n\n"+`{"protocol": "ftp"}`+"\n"), 0644))
+
+	_, _, err := Requests(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 3")
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var recorder *Recorder
+	assert.NoError(t, recorder.Record(Entry{Protocol: "http"}))
+	assert.NoError(t, recorder.Close())
+}
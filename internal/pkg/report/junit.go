@@ -0,0 +1,96 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Reports of the outcome of the warmup in formats understood by other tools.
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"mittens/internal/pkg/warmup"
+	"os"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the summary to a file as JUnit XML, with a test suite per protocol and a test case per endpoint.
+// An endpoint fails if it never returned a successful response.
+func WriteJUnit(path string, summary *warmup.Summary) error {
+	content, err := toJUnit(summary)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("unable to write JUnit report: %v", err)
+	}
+	return nil
+}
+
+func toJUnit(summary *warmup.Summary) ([]byte, error) {
+	report := junitTestSuites{}
+	suites := make(map[string]int)
+	for _, e := range summary.Endpoints() {
+		i, ok := suites[e.Protocol]
+		if !ok {
+			i = len(report.Suites)
+			suites[e.Protocol] = i
+			report.Suites = append(report.Suites, junitTestSuite{Name: "mittens." + e.Protocol})
+		}
+		suite := &report.Suites[i]
+
+		testCase := junitTestCase{ClassName: "mittens." + e.Protocol, Name: e.Endpoint}
+		if e.Successes == 0 {
+			message := fmt.Sprintf("no successful response (%d sent, %d failed)", e.Sent, e.Failures)
+			testCase.Failure = &junitFailure{Message: message, Text: e.LastFailure}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+	if errs := summary.PreflightErrors(); len(errs) > 0 {
+		suite := junitTestSuite{Name: "mittens.preflight", Tests: len(errs), Failures: len(errs)}
+		for i, err := range errs {
+			suite.TestCases = append(suite.TestCases, junitTestCase{ClassName: "mittens.preflight", Name: fmt.Sprintf("validation %d", i+1), Failure: &junitFailure{Message: err}})
+		}
+		report.Suites = append(report.Suites, suite)
+	}
+
+	content, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode JUnit report: %v", err)
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
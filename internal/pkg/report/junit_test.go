@@ -0,0 +1,60 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"mittens/internal/pkg/warmup"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	summary := warmup.NewSummary()
+	summary.Record("http", "GET /ping", true)
+	summary.RecordFailure("http", `POST /search?q=<a&b>`, `status code 500 "oops"`)
+	summary.Record("grpc", "health/ping", true)
+
+	path := filepath.Join(t.TempDir(), "results.xml")
+	require.NoError(t, WriteJUnit(path, summary))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="mittens.http" tests="2" failures="1">
+    <testcase classname="mittens.http" name="GET /ping"></testcase>
+    <testcase classname="mittens.http" name="POST /search?q=&lt;a&amp;b&gt;">
+      <failure message="no successful response (1 sent, 1 failed)">status code 500 &#34;oops&#34;</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="mittens.grpc" tests="1" failures="0">
+    <testcase classname="mittens.grpc" name="health/ping"></testcase>
+  </testsuite>
+</testsuites>
+`, string(content))
+}
+
+func TestWriteJUnitWithPreflightErrors(t *testing.T) {
+	summary := warmup.NewSummary()
+	summary.AddPreflightError("unknown gRPC methods [foo/bar]")
+
+	content, err := toJUnit(summary)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `<failure message="unknown gRPC methods [foo/bar]"></failure>`)
+}
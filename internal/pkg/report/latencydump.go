@@ -0,0 +1,63 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mittens/internal/pkg/stats"
+	"os"
+	"time"
+)
+
+// ticksPerHalfDistance is the number of percentiles reported in every half of the distance to 100%, as by default in HDR histogram logs.
+const ticksPerHalfDistance = 5
+
+// WriteLatencyDump writes the percentile distribution of the latencies to a file in the .hgrm format of HDR histograms,
+// with values in milliseconds, which tools like hdr-plot can render.
+func WriteLatencyDump(path string, latencies *stats.Histogram) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to write latency dump: %v", err)
+	}
+	defer file.Close()
+	if err := writeHgrm(file, latencies); err != nil {
+		return fmt.Errorf("unable to write latency dump: %v", err)
+	}
+	return file.Close()
+}
+
+func writeHgrm(out io.Writer, latencies *stats.Histogram) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "%12s %14s %10s %14s\n\n", "Value", "Percentile", "TotalCount", "1/(1-Percentile)")
+	for _, point := range latencies.Distribution(ticksPerHalfDistance) {
+		percentile := point.Percentile / 100
+		if point.Percentile == 100 {
+			fmt.Fprintf(w, "%12.3f %2.12f %10d\n", toMilliseconds(point.Value), percentile, point.TotalCount)
+		} else {
+			fmt.Fprintf(w, "%12.3f %2.12f %10d %14.2f\n", toMilliseconds(point.Value), percentile, point.TotalCount, 1/(1-percentile))
+		}
+	}
+	buckets, subBuckets := latencies.Layout()
+	fmt.Fprintf(w, "#[Mean    = %12.3f, StdDeviation   = %12.3f]\n", toMilliseconds(latencies.Mean()), toMilliseconds(latencies.StdDev()))
+	fmt.Fprintf(w, "#[Max     = %12.3f, Total count    = %12d]\n", toMilliseconds(latencies.Max()), latencies.Count())
+	fmt.Fprintf(w, "#[Buckets = %12d, SubBuckets     = %12d]\n", buckets, subBuckets)
+	return w.Flush()
+}
+
+func toMilliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
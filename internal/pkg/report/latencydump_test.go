@@ -0,0 +1,58 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"mittens/internal/pkg/stats"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteLatencyDump(t *testing.T) {
+	latencies := stats.NewHistogram()
+	for i := 1; i <= 100; i++ {
+		latencies.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	path := filepath.Join(t.TempDir(), "latencies.hgrm")
+	require.NoError(t, WriteLatencyDump(path, latencies))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	assert.Equal(t, "       Value     Percentile TotalCount 1/(1-Percentile)", lines[0])
+	assert.Equal(t, "", lines[1])
+	assert.Equal(t, "       1.000 0.000000000000          1           1.00", lines[2])
+	assert.Equal(t, "      50.175 0.500000000000         50           2.00", lines[7])
+	assert.Equal(t, "     100.000 1.000000000000        100", lines[len(lines)-4])
+	assert.Equal(t, "#[Mean    =       50.500, StdDeviation   =       28.866]", lines[len(lines)-3])
+	assert.Equal(t, "#[Max     =      100.000, Total count    =          100]", lines[len(lines)-2])
+	assert.Equal(t, "#[Buckets =           31, SubBuckets     =          128]", lines[len(lines)-1])
+}
+
+func TestWriteLatencyDump_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "latencies.hgrm")
+	require.NoError(t, WriteLatencyDump(path, stats.NewHistogram()))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Total count    =            0]")
+}
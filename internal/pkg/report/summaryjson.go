@@ -0,0 +1,131 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"mittens/internal/pkg/stats"
+	"mittens/internal/pkg/warmup"
+	"os"
+)
+
+// summaryJSON is the JSON form of the summary of the warmup.
+type summaryJSON struct {
+	Ready     bool           `json:"ready"`
+	Sent      int            `json:"sent"`
+	Successes int            `json:"successes"`
+	Failures  int            `json:"failures"`
+	Latency   latencyJSON    `json:"latency"`
+	Endpoints []endpointJSON `json:"endpoints"`
+	// SlowRequests are the slowest requests above the slow request threshold, the slowest first.
+	SlowRequests []slowRequestJSON `json:"slow_requests,omitempty"`
+}
+
+type endpointJSON struct {
+	Protocol  string      `json:"protocol"`
+	Endpoint  string      `json:"endpoint"`
+	Sent      int         `json:"sent"`
+	Successes int         `json:"successes"`
+	Failures  int         `json:"failures"`
+	Latency   latencyJSON `json:"latency"`
+	// Histogram is the percentile distribution of the latencies, see histogramTicksPerHalfDistance.
+	Histogram []histogramPointJSON `json:"histogram,omitempty"`
+	// LatencyTrend compares the latencies of the first and the last responses.
+	LatencyTrend *latencyTrendJSON `json:"latency_trend,omitempty"`
+}
+
+type histogramPointJSON struct {
+	Percentile float64 `json:"percentile"`
+	ValueMs    float64 `json:"value_ms"`
+	Count      uint64  `json:"count"`
+}
+
+type latencyTrendJSON struct {
+	FirstP50Ms float64 `json:"first_p50_ms"`
+	LastP50Ms  float64 `json:"last_p50_ms"`
+	// Stalled is true if the latency did not improve during the warmup.
+	Stalled bool `json:"stalled"`
+}
+
+type slowRequestJSON struct {
+	Protocol   string  `json:"protocol"`
+	Endpoint   string  `json:"endpoint"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// histogramTicksPerHalfDistance keeps the histograms of the endpoints small: a point at 0%, 50%, 75%, 87.5%... and 100%.
+const histogramTicksPerHalfDistance = 1
+
+// latencyJSON holds the number of responses and their latency statistics, in milliseconds. They are 0 if no response was received.
+type latencyJSON struct {
+	Count  uint64  `json:"count"`
+	MinMs  float64 `json:"min_ms"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// WriteSummaryJSON writes the summary to a file as JSON: the totals of all the requests and, for every endpoint,
+// the requests sent, successes, failures, latency statistics, histogram and trend, followed by the slowest requests if any.
+func WriteSummaryJSON(path string, summary *warmup.Summary, ready bool) error {
+	content, err := toSummaryJSON(summary, ready)
+	if err != nil {
+		return fmt.Errorf("unable to write summary: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("unable to write summary: %v", err)
+	}
+	return nil
+}
+
+func toSummaryJSON(summary *warmup.Summary, ready bool) ([]byte, error) {
+	result := summaryJSON{Ready: ready, Latency: toLatencyJSON(summary.Latencies()), Endpoints: []endpointJSON{}}
+	for _, e := range summary.Endpoints() {
+		result.Sent += e.Sent
+		result.Successes += e.Successes
+		result.Failures += e.Failures
+		endpoint := endpointJSON{Protocol: e.Protocol, Endpoint: e.Endpoint, Sent: e.Sent,
+			Successes: e.Successes, Failures: e.Failures, Latency: toLatencyJSON(e.Latencies)}
+		if e.Latencies != nil {
+			for _, point := range e.Latencies.Distribution(histogramTicksPerHalfDistance) {
+				endpoint.Histogram = append(endpoint.Histogram, histogramPointJSON{Percentile: point.Percentile, ValueMs: toMilliseconds(point.Value), Count: point.TotalCount})
+			}
+		}
+		if e.FirstLatencies != nil {
+			endpoint.LatencyTrend = &latencyTrendJSON{FirstP50Ms: toMilliseconds(e.FirstLatencies.Percentile(50)),
+				LastP50Ms: toMilliseconds(e.LastLatencies.Percentile(50)), Stalled: e.LatencyStalled()}
+		}
+		result.Endpoints = append(result.Endpoints, endpoint)
+	}
+	for _, sample := range summary.SlowSamples() {
+		result.SlowRequests = append(result.SlowRequests, slowRequestJSON{Protocol: sample.Protocol, Endpoint: sample.Endpoint, DurationMs: toMilliseconds(sample.Duration)})
+	}
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(content, '\n'), nil
+}
+
+func toLatencyJSON(latencies *stats.Histogram) latencyJSON {
+	if latencies == nil {
+		return latencyJSON{}
+	}
+	return latencyJSON{Count: latencies.Count(), MinMs: toMilliseconds(latencies.Min()), MeanMs: toMilliseconds(latencies.Mean()), P50Ms: toMilliseconds(latencies.Percentile(50)), P90Ms: toMilliseconds(latencies.Percentile(90)),
+		P99Ms: toMilliseconds(latencies.Percentile(99)), MaxMs: toMilliseconds(latencies.Max())}
+}
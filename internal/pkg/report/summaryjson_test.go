@@ -0,0 +1,62 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"mittens/internal/pkg/warmup"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSummaryJSON(t *testing.T) {
+	summary := warmup.NewSummary()
+	summary.Register("grpc", "health/ping")
+	summary.Record("http", "GET /ping", true)
+	summary.RecordLatency("http", "GET /ping", 10*time.Millisecond)
+	summary.RecordFailure("http", "GET /ping", "status code 500")
+	summary.RecordSlow("http", "GET /ping", 1500*time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	require.NoError(t, WriteSummaryJSON(path, summary, true))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var result summaryJSON
+	require.NoError(t, json.Unmarshal(content, &result))
+	assert.True(t, result.Ready)
+	assert.Equal(t, 2, result.Sent)
+	assert.Equal(t, 1, result.Successes)
+	assert.Equal(t, 1, result.Failures)
+	require.Len(t, result.Endpoints, 2)
+	assert.Equal(t, endpointJSON{Protocol: "grpc", Endpoint: "health/ping"}, result.Endpoints[0])
+	assert.Equal(t, "GET /ping", result.Endpoints[1].Endpoint)
+	assert.Equal(t, 2, result.Endpoints[1].Sent)
+	assert.InDelta(t, 10, result.Endpoints[1].Latency.P99Ms, 0.1)
+	assert.Equal(t, uint64(1), result.Endpoints[1].Latency.Count)
+	assert.InDelta(t, 10, result.Endpoints[1].Latency.MinMs, 0.1)
+	assert.InDelta(t, 10, result.Endpoints[1].Latency.MeanMs, 0.1)
+	require.NotEmpty(t, result.Endpoints[1].Histogram)
+	assert.Equal(t, 100.0, result.Endpoints[1].Histogram[len(result.Endpoints[1].Histogram)-1].Percentile)
+	require.NotNil(t, result.Endpoints[1].LatencyTrend)
+	assert.False(t, result.Endpoints[1].LatencyTrend.Stalled)
+	assert.Equal(t, result.Endpoints[1].Latency, result.Latency)
+	assert.Equal(t, []slowRequestJSON{{Protocol: "http", Endpoint: "GET /ping", DurationMs: 1500}}, result.SlowRequests)
+}
@@ -0,0 +1,43 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"fmt"
+	"mittens/internal/pkg/warmup"
+	"time"
+)
+
+// SummaryLine returns a single line summarising the warmup for shell scripts, e.g.
+// `MITTENS_SUMMARY total=1000 ok=980 fail=20 p50_ms=12 p90_ms=30 p95_ms=42 p99_ms=80 max_ms=95 ready=true`.
+// The keys and their order are stable: new keys may only be appended. Latencies are in whole milliseconds
+// and are 0 if no response was received.
+func SummaryLine(summary *warmup.Summary, ready bool) string {
+	var total, ok, fail int
+	for _, e := range summary.Endpoints() {
+		total += e.Sent
+		ok += e.Successes
+		fail += e.Failures
+	}
+	latencies := summary.Latencies()
+	return fmt.Sprintf("MITTENS_SUMMARY total=%d ok=%d fail=%d p50_ms=%d p90_ms=%d p95_ms=%d p99_ms=%d max_ms=%d ready=%t",
+		total, ok, fail,
+		milliseconds(latencies.Percentile(50)), milliseconds(latencies.Percentile(90)), milliseconds(latencies.Percentile(95)),
+		milliseconds(latencies.Percentile(99)), milliseconds(latencies.Max()), ready)
+}
+
+func milliseconds(d time.Duration) int64 {
+	return int64(d.Round(time.Millisecond) / time.Millisecond)
+}
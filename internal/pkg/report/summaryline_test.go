@@ -0,0 +1,38 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"mittens/internal/pkg/warmup"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummaryLine(t *testing.T) {
+	summary := warmup.NewSummary()
+	for i := 1; i <= 100; i++ {
+		summary.Record("http", "GET /ping", true)
+		summary.RecordLatency("http", "GET /ping", time.Duration(i)*time.Millisecond)
+	}
+	summary.RecordFailure("grpc", "health/ping", "connection refused")
+
+	assert.Equal(t, "MITTENS_SUMMARY total=101 ok=100 fail=1 p50_ms=50 p90_ms=90 p95_ms=95 p99_ms=99 max_ms=100 ready=true", SummaryLine(summary, true))
+}
+
+func TestSummaryLineWithoutWarmup(t *testing.T) {
+	assert.Equal(t, "MITTENS_SUMMARY total=0 ok=0 fail=0 p50_ms=0 p90_ms=0 p95_ms=0 p99_ms=0 max_ms=0 ready=false", SummaryLine(nil, false))
+}
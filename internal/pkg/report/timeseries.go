@@ -0,0 +1,74 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mittens/internal/pkg/stats"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// second is the JSON form of a second of the time series.
+type second struct {
+	Second    int     `json:"second"`
+	Sent      int     `json:"sent"`
+	Successes int     `json:"successes"`
+	Failures  int     `json:"failures"`
+	P95Ms     float64 `json:"p95_ms"`
+}
+
+// WriteTimeseries writes the outcome of the requests per second to a file, as JSON if its extension is .json and as CSV otherwise.
+func WriteTimeseries(path string, seconds []stats.Second) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to write time series: %v", err)
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = writeTimeseriesJSON(file, seconds)
+	} else {
+		err = writeTimeseriesCSV(file, seconds)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write time series: %v", err)
+	}
+	return file.Close()
+}
+
+func writeTimeseriesJSON(out io.Writer, seconds []stats.Second) error {
+	series := make([]second, 0, len(seconds))
+	for _, s := range seconds {
+		series = append(series, second{Second: s.Second, Sent: s.Sent, Successes: s.Successes, Failures: s.Failures, P95Ms: toMilliseconds(s.P95)})
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(series)
+}
+
+func writeTimeseriesCSV(out io.Writer, seconds []stats.Second) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"second", "sent", "successes", "failures", "p95_ms"})
+	for _, s := range seconds {
+		w.Write([]string{strconv.Itoa(s.Second), strconv.Itoa(s.Sent), strconv.Itoa(s.Successes), strconv.Itoa(s.Failures), strconv.FormatFloat(toMilliseconds(s.P95), 'f', 3, 64)})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,52 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package report
+
+import (
+	"mittens/internal/pkg/stats"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSeconds = []stats.Second{
+	{Second: 0, Sent: 10, Successes: 7, Failures: 3, P95: 1500 * time.Millisecond},
+	{Second: 1, Sent: 20, Successes: 20, P95: 12 * time.Millisecond},
+}
+
+func TestWriteTimeseries_CSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeseries.csv")
+	require.NoError(t, WriteTimeseries(path, testSeconds))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second,sent,successes,failures,p95_ms\n0,10,7,3,1500.000\n1,20,20,0,12.000\n", string(content))
+}
+
+func TestWriteTimeseries_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeseries.json")
+	require.NoError(t, WriteTimeseries(path, testSeconds))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"second": 0, "sent": 10, "successes": 7, "failures": 3, "p95_ms": 1500},
+		{"second": 1, "sent": 20, "successes": 20, "failures": 0, "p95_ms": 12}
+	]`, string(content))
+}
@@ -0,0 +1,176 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Options that can prefix the HTTP and gRPC request flags, e.g. `[burst=3]get:/ping`.
+
+package requestoptions
+
+import (
+	"fmt"
+	"mittens/internal/pkg/golden"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Burst is the number of times a request is sent back-to-back every time it is selected.
+	Burst = "burst"
+	// Weight is how often a request is selected compared to the others, e.g. a request with weight 3 is selected
+	// three times as often as a request without weight.
+	Weight = "weight"
+	// Golden is the path of a golden file holding the expected response body.
+	Golden = "golden"
+	// MaxLatency is the latency above which a successful response violates the latency SLO of the request, e.g. 200ms.
+	MaxLatency = "max-latency"
+	// MetadataFile is the path of a file with the gRPC metadata sent with a request only, in the format of the grpc-metadata-file flag.
+	MetadataFile = "metadata-file"
+	// Timeout is the time after which an HTTP request is cancelled, e.g. 30s. It overrides the timeout of the client.
+	Timeout = "timeout"
+	// ExpectBody is a substring the body of a successful HTTP response must contain, e.g. "status":"UP".
+	ExpectBody = "expect-body"
+	// Conditional, if true, makes an HTTP request followed by the same request with If-None-Match set to the ETag of its response.
+	Conditional = "conditional"
+	// Gzip, if true, compresses the body of an HTTP request with gzip and sends it with Content-Encoding: gzip.
+	Gzip = "gzip"
+	// Status are the status codes with which an HTTP response succeeds instead of the 2xx ones, e.g. 2xx|404|300-308.
+	Status = "status"
+)
+
+// Options holds the options of a request by name.
+type Options map[string]string
+
+// Parse splits a request flag into its options and the rest of the flag.
+// Options are optional and in the `[name=value,name=value]` format. Only the names in allowed are accepted.
+// Values can be quoted with single quotes, e.g. `[expect-body='"a":1,"b":[2]']`, to contain commas and `]`,
+// in which case a single quote of the value is written twice.
+func Parse(requestFlag string, allowed ...string) (Options, string, error) {
+	options := Options{}
+	if !strings.HasPrefix(requestFlag, "[") {
+		return options, requestFlag, nil
+	}
+
+	// start of the current option, and the index of its = if already found
+	start, eq := 1, -1
+	var value strings.Builder
+	quoted, closedQuote := false, false
+	for i := 1; i < len(requestFlag); i++ {
+		c := requestFlag[i]
+		switch {
+		case quoted && c == '\'':
+			if i+1 < len(requestFlag) && requestFlag[i+1] == '\'' {
+				value.WriteByte(c)
+				i++
+			} else {
+				quoted, closedQuote = false, true
+			}
+		case quoted:
+			value.WriteByte(c)
+		case c == ',' || c == ']':
+			option := requestFlag[start:i]
+			if eq == -1 {
+				return nil, "", fmt.Errorf("invalid request option: %s, expected format <name>=<value>", option)
+			}
+			name := strings.TrimSpace(requestFlag[start:eq])
+			if !contains(allowed, name) {
+				return nil, "", fmt.Errorf("invalid request option: %s, supported options are %v", name, allowed)
+			}
+			if closedQuote {
+				options[name] = value.String()
+			} else {
+				options[name] = strings.TrimSpace(value.String())
+			}
+			if c == ']' {
+				return options, requestFlag[i+1:], nil
+			}
+			start, eq = i+1, -1
+			value.Reset()
+			closedQuote = false
+		case closedQuote:
+			if c != ' ' {
+				return nil, "", fmt.Errorf("invalid request option: %s, expected , or ] after the quoted value", requestFlag[start:i+1])
+			}
+		case eq == -1:
+			if c == '=' {
+				eq = i
+			}
+		case c == '\'' && strings.TrimSpace(value.String()) == "":
+			quoted = true
+			value.Reset()
+		default:
+			value.WriteByte(c)
+		}
+	}
+	if quoted {
+		return nil, "", fmt.Errorf("invalid request flag: %s, quoted option value is not closed with '", requestFlag)
+	}
+	return nil, "", fmt.Errorf("invalid request flag: %s, options are not closed with ]", requestFlag)
+}
+
+// PositiveInt returns the value of an option that must be a positive integer, or the fallback if the option is not set.
+func (o Options) PositiveInt(name string, fallback int) (int, error) {
+	value, ok := o[name]
+	if !ok {
+		return fallback, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 1 {
+		return 0, fmt.Errorf("invalid request option: %s=%s, expected a positive integer", name, value)
+	}
+	return i, nil
+}
+
+// PositiveDuration returns the value of an option that must be a positive duration, e.g. 200ms, or 0 if the option is not set.
+func (o Options) PositiveDuration(name string) (time.Duration, error) {
+	value, ok := o[name]
+	if !ok {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid request option: %s=%s, expected a positive duration, e.g. 200ms", name, value)
+	}
+	return d, nil
+}
+
+// Bool returns the value of an option that must be true or false, or false if the option is not set.
+func (o Options) Bool(name string) (bool, error) {
+	value, ok := o[name]
+	if !ok {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid request option: %s=%s, expected true or false", name, value)
+	}
+	return b, nil
+}
+
+// GoldenFile loads the golden file of the request, or returns nil if the option is not set.
+func (o Options) GoldenFile() (*golden.File, error) {
+	path, ok := o[Golden]
+	if !ok {
+		return nil, nil
+	}
+	return golden.Load(path)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package requestoptions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	options, rest, err := Parse("[burst=3]get:/ping", Burst)
+	require.NoError(t, err)
+
+	assert.Equal(t, Options{Burst: "3"}, options)
+	assert.Equal(t, "get:/ping", rest)
+}
+
+func TestParseQuotedValue(t *testing.T) {
+	options, rest, err := Parse(`[expect-body='"a":1,"b":[2]', burst=2]get:/ping`, ExpectBody, Burst)
+	require.NoError(t, err)
+	assert.Equal(t, Options{ExpectBody: `"a":1,"b":[2]`, Burst: "2"}, options)
+	assert.Equal(t, "get:/ping", rest)
+
+	// quotes are kept as they are in unquoted values, and written twice in quoted ones
+	options, _, err = Parse(`[expect-body=it's, burst=' 2 ']get:/ping`, ExpectBody, Burst)
+	require.NoError(t, err)
+	assert.Equal(t, Options{ExpectBody: "it's", Burst: " 2 "}, options)
+
+	options, _, err = Parse(`[expect-body='it''s']get:/ping`, ExpectBody)
+	require.NoError(t, err)
+	assert.Equal(t, Options{ExpectBody: "it's"}, options)
+}
+
+func TestParseWithoutOptions(t *testing.T) {
+	options, rest, err := Parse("get:/ping", Burst)
+	require.NoError(t, err)
+
+	assert.Empty(t, options)
+	assert.Equal(t, "get:/ping", rest)
+}
+
+func TestParseInvalidOptions(t *testing.T) {
+	_, _, err := Parse("[burst=3get:/ping", Burst)
+	assert.Error(t, err)
+
+	_, _, err = Parse("[burst]get:/ping", Burst)
+	assert.Error(t, err)
+
+	_, _, err = Parse("[sparkles=3]get:/ping", Burst)
+	assert.Error(t, err)
+
+	_, _, err = Parse("[expect-body='a,b]get:/ping", ExpectBody)
+	assert.Error(t, err)
+
+	_, _, err = Parse("[expect-body='a'b]get:/ping", ExpectBody)
+	assert.Error(t, err)
+}
+
+func TestPositiveInt(t *testing.T) {
+	options := Options{Burst: "3"}
+
+	burst, err := options.PositiveInt(Burst, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, burst)
+
+	burst, err = Options{}.PositiveInt(Burst, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, burst)
+
+	_, err = Options{Burst: "-1"}.PositiveInt(Burst, 1)
+	assert.Error(t, err)
+}
+
+func TestPositiveDuration(t *testing.T) {
+	maxLatency, err := Options{MaxLatency: "1.5s"}.PositiveDuration(MaxLatency)
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, maxLatency)
+
+	maxLatency, err = Options{}.PositiveDuration(MaxLatency)
+	require.NoError(t, err)
+	assert.Zero(t, maxLatency)
+
+	_, err = Options{MaxLatency: "0s"}.PositiveDuration(MaxLatency)
+	assert.Error(t, err)
+}
+
+func TestBool(t *testing.T) {
+	conditional, err := Options{Conditional: "true"}.Bool(Conditional)
+	require.NoError(t, err)
+	assert.True(t, conditional)
+
+	conditional, err = Options{}.Bool(Conditional)
+	require.NoError(t, err)
+	assert.False(t, conditional)
+
+	_, err = Options{Conditional: "maybe"}.Bool(Conditional)
+	assert.Error(t, err)
+}
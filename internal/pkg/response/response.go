@@ -14,7 +14,12 @@
 
 package response
 
-import "time"
+import (
+	"net/textproto"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
 
 // Response represents an HTTP or gRPC response.
 type Response struct {
@@ -22,4 +27,33 @@ type Response struct {
 	Err        error
 	Type       string
 	StatusCode int
+	// GrpcStatus is the status code of a gRPC response. It is only meaningful for gRPC responses that have no Err.
+	GrpcStatus codes.Code
+	// GrpcMessages is the number of messages of a gRPC response, which can be more than one for server-streaming methods.
+	GrpcMessages int
+	// Body is the captured response body. It is only set if the body was requested to be captured.
+	Body []byte
+	// BodySize is the size in bytes of the body of an HTTP response, whether it was captured or not.
+	BodySize int64
+	// BodyTruncated is true if the body was larger than the capture limit and only its beginning was captured.
+	BodyTruncated bool
+	// Attempts is the number of times the request was sent, more than 1 if it was retried. 0 means 1.
+	Attempts int
+	// Hedged is true if a second copy of the request was sent because the first one was slow.
+	Hedged bool
+	// TimedOut is true if the request failed because it did not complete within its timeout.
+	TimedOut bool
+	// Protocol is the protocol of an HTTP response, HTTP/1.1 or HTTP/2.0.
+	Protocol string
+	// Headers are the headers of an HTTP response.
+	Headers map[string][]string
+}
+
+// Header returns the first value of the response header with the given case-insensitive name, or "" if there is none.
+func (r Response) Header(name string) string {
+	values := r.Headers[textproto.CanonicalMIMEHeaderKey(name)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
 }
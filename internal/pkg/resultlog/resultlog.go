@@ -0,0 +1,95 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Logging of the results of the warmup requests, either as text lines or as JSON objects for log aggregators.
+
+package resultlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+const (
+	// Text logs every result as a line prefixed with a marker. It is the default.
+	Text = "text"
+	// JSON writes every result as a JSON object, one per line.
+	JSON = "json"
+)
+
+// Outcomes of a request.
+const (
+	Success = "success"
+	Failure = "failure"
+	// Warning is the outcome of a successful request that exceeded its max latency.
+	Warning = "warning"
+	// Slow is the outcome of a successful request slower than the slow request threshold.
+	Slow = "slow"
+)
+
+// Result is the result of a warmup request.
+type Result struct {
+	Protocol string `json:"protocol"`
+	// HTTP requests
+	Method string `json:"method,omitempty"`
+	Path   string `json:"path,omitempty"`
+	// gRPC requests
+	ServiceMethod string `json:"serviceMethod,omitempty"`
+	// Status is the status code of an HTTP response or the status of a gRPC response, empty if there is no response.
+	Status     string `json:"status,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	// Attempt is the number of times the request was sent, more than 1 if it was retried.
+	Attempt int    `json:"attempt"`
+	Outcome string `json:"outcome"`
+}
+
+// Logger writes the results as JSON objects. It is safe for concurrent use.
+// A nil logger logs the results as text lines instead.
+type Logger struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONLogger returns a logger that writes the results to w as JSON objects, one per line.
+func NewJSONLogger(w io.Writer) *Logger {
+	return &Logger{encoder: json.NewEncoder(w)}
+}
+
+// New returns the logger of a format, Text or JSON, that writes the JSON objects to w.
+func New(format string, w io.Writer) (*Logger, error) {
+	switch format {
+	case Text:
+		return nil, nil
+	case JSON:
+		return NewJSONLogger(w), nil
+	}
+	return nil, fmt.Errorf("result log format %s not supported, please use %s or %s", format, Text, JSON)
+}
+
+// Log writes the result as a JSON object or, if the logger is nil, logs the text line made of the format and its arguments.
+func (l *Logger) Log(result Result, format string, args ...interface{}) {
+	if l == nil {
+		log.Printf(format, args...)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.encoder.Encode(result); err != nil {
+		log.Printf("Cannot write request result: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package resultlog
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_JSON(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(JSON, &out)
+	require.NoError(t, err)
+
+	logger.Log(Result{Protocol: "http", Method: "GET", Path: "/ping", Status: "200", DurationMs: 12, Attempt: 1, Outcome: Success}, "not %s", "logged")
+	logger.Log(Result{Protocol: "grpc", ServiceMethod: "health/ping", Error: "connection refused", Attempt: 2, Outcome: Failure}, "not logged")
+
+	assert.Equal(t, `{"protocol":"http","method":"GET","path":"/ping","status":"200","duration_ms":12,"attempt":1,"outcome":"success"}
+{"protocol":"grpc","serviceMethod":"health/ping","duration_ms":0,"error":"connection refused","attempt":2,"outcome":"failure"}
+`, out.String())
+}
+
+func TestLogger_Text(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+	logger, err := New(Text, &bytes.Buffer{})
+	require.NoError(t, err)
+
+	logger.Log(Result{Protocol: "http"}, "%s response", "http")
+
+	assert.Contains(t, out.String(), "http response\n")
+}
+
+func TestNew_UnknownFormat(t *testing.T) {
+	_, err := New("xml", &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -30,7 +30,7 @@ func Do(f func()) {
 
 // DoAndReturn wraps a function with recover logic to catch unexpected panics.
 // It returns the result of the function if no panic occurred, or the fallback result otherwise.
-func DoAndReturn(f func() int, fallback int) (result int) {
+func DoAndReturn[T any](f func() T, fallback T) (result T) {
 	defer func() {
 		if err := recover(); err != nil {
 			log.Println("Unexpected panic was caught:", err)
@@ -0,0 +1,128 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Self-test of the latencies measured by mittens against a mock server whose latencies are known.
+
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mittens/fixture"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/marker"
+	"mittens/internal/pkg/stats"
+	"mittens/internal/pkg/warmup"
+	"time"
+)
+
+// latencies returns the latencies of the mock server, served in turn: 12 of 20 responses take 20ms, 7 take 50ms
+// and 1 takes 100ms, so the p50, p90 and p99 are 20ms, 50ms and 100ms with a wide margin on either side.
+func latencies() []time.Duration {
+	var l []time.Duration
+	for i := 0; i < 12; i++ {
+		l = append(l, 20*time.Millisecond)
+	}
+	for i := 0; i < 7; i++ {
+		l = append(l, 50*time.Millisecond)
+	}
+	return append(l, 100*time.Millisecond)
+}
+
+// expected are the percentiles of the latencies of the mock server.
+var expected = []Percentile{
+	{Percentile: 50, Expected: 20 * time.Millisecond},
+	{Percentile: 90, Expected: 50 * time.Millisecond},
+	{Percentile: 99, Expected: 100 * time.Millisecond},
+}
+
+const (
+	concurrency     = 10
+	durationSeconds = 3
+)
+
+// Percentile is a latency percentile measured during the self-test along with its expected value.
+type Percentile struct {
+	Percentile float64
+	Expected   time.Duration
+	Measured   time.Duration
+}
+
+// Ok returns true if the measured value is within the tolerance of the expected one. Measurements may be slightly
+// lower because the histogram buckets are rounded, and higher because of the overhead of the client and the scheduling
+// of the mock server: 10ms plus 10% is allowed.
+func (p Percentile) Ok() bool {
+	return p.Measured >= p.Expected-time.Millisecond && p.Measured <= p.Expected+10*time.Millisecond+p.Expected/10
+}
+
+func (p Percentile) String() string {
+	return fmt.Sprintf("p%v measured %v, expected %v", p.Percentile, p.Measured.Round(100*time.Microsecond), p.Expected)
+}
+
+// Run warms up a mock server whose latencies are known and compares the percentiles measured by mittens with the
+// expected ones. It logs the outcome and returns true if every percentile is within the tolerance.
+func Run() bool {
+	server, port := fixture.StartLatencyTestServer(latencies())
+	defer server.Close()
+
+	log.Printf("Running self-test against a mock server for %d seconds", durationSeconds)
+	httpClient, err := http.NewClient(fmt.Sprintf("http://127.0.0.1:%d", port), false, http.ClientOptions{})
+	if err != nil {
+		log.Printf("%s Self-test did not run: %v", marker.Failure(), err)
+		return false
+	}
+	w := &warmup.Warmup{
+		Target:       warmup.NewTarget(httpClient, grpc.Client{}, httpClient, grpc.Client{}, warmup.TargetOptions{}),
+		Concurrency:  concurrency,
+		HttpRequests: []http.Request{{Method: "GET", Path: "/"}},
+	}
+	var requestsSent int64
+	summary, err := w.Run(context.Background(), true, false, durationSeconds, &requestsSent)
+	if err != nil {
+		log.Printf("%s Self-test did not run: %v", marker.Failure(), err)
+		return false
+	}
+
+	percentiles := Measure(summary.Latencies())
+	passed := summary.Latencies().Count() > 0
+	if !passed {
+		log.Printf("%s Self-test did not measure any latency", marker.Failure())
+	}
+	for _, p := range percentiles {
+		if p.Ok() {
+			log.Printf("%s Self-test %s", marker.Success(), p)
+		} else {
+			log.Printf("%s Self-test %s", marker.Failure(), p)
+			passed = false
+		}
+	}
+	if passed {
+		log.Printf("%s Self-test passed: %d latencies measured", marker.Success(), summary.Latencies().Count())
+	} else {
+		log.Printf("%s Self-test failed: %d latencies measured", marker.Failure(), summary.Latencies().Count())
+	}
+	return passed
+}
+
+// Measure returns the expected percentiles along with their value in the given latencies.
+func Measure(latencies *stats.Histogram) []Percentile {
+	percentiles := make([]Percentile, len(expected))
+	for i, p := range expected {
+		p.Measured = latencies.Percentile(p.Percentile)
+		percentiles[i] = p
+	}
+	return percentiles
+}
@@ -0,0 +1,53 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package selftest
+
+import (
+	"mittens/internal/pkg/stats"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	assert.True(t, Run())
+}
+
+func TestMeasure(t *testing.T) {
+	h := stats.NewHistogram()
+	for _, l := range latencies() {
+		h.Record(l + 2*time.Millisecond)
+	}
+
+	percentiles := Measure(h)
+	assert.Equal(t, 3, len(percentiles))
+	for _, p := range percentiles {
+		assert.True(t, p.Ok(), p.String())
+	}
+}
+
+func TestPercentile_Ok(t *testing.T) {
+	p := Percentile{Percentile: 99, Expected: 100 * time.Millisecond}
+
+	p.Measured = 99500 * time.Microsecond
+	assert.True(t, p.Ok())
+	p.Measured = 120 * time.Millisecond
+	assert.True(t, p.Ok())
+	p.Measured = 90 * time.Millisecond
+	assert.False(t, p.Ok())
+	p.Measured = 121 * time.Millisecond
+	assert.False(t, p.Ok())
+}
@@ -0,0 +1,235 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Latency statistics of the warmup requests.
+
+package stats
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	// subBuckets is the number of linear buckets each power of two is split into above the exact range,
+	// which bounds the relative error of the percentiles to 1/subBuckets.
+	subBuckets = 64
+	// exactBuckets is the number of values, in microseconds, that are counted exactly.
+	exactBuckets = 2 * subBuckets
+	// maxShift bounds the values that are counted, larger ones are counted in the last bucket (about 19 hours).
+	maxShift    = 30
+	bucketCount = exactBuckets + maxShift*subBuckets
+)
+
+// Histogram counts durations in log-linear buckets, in the fashion of HDR histograms: durations below 128µs are counted
+// exactly and every larger power of two is split into 64 linear buckets. This keeps the memory used constant, about
+// 16KiB, regardless of the number of durations while percentiles are accurate to within 1.6%.
+// A Histogram is not safe for concurrent use.
+type Histogram struct {
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+	// sum of the squares of the durations in microseconds, used for the standard deviation
+	sumSquares float64
+	min        time.Duration
+	max        time.Duration
+}
+
+// NewHistogram returns an empty histogram.
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]uint64, bucketCount)}
+}
+
+// Record counts a duration. Negative durations are counted as 0.
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[bucketIndex(d)]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+	h.sumSquares += math.Pow(float64(d/time.Microsecond), 2)
+}
+
+// Merge adds the durations counted by other.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.count == 0 {
+		return
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.count += other.count
+	h.sum += other.sum
+	h.sumSquares += other.sumSquares
+}
+
+// Count returns the number of durations counted.
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Min returns the smallest duration counted, or 0 if there is none.
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+// Max returns the largest duration counted, or 0 if there is none.
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Mean returns the average of the durations counted, or 0 if there is none.
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// StdDev returns the standard deviation of the durations counted, to the microsecond, or 0 if there is none.
+func (h *Histogram) StdDev() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	mean := float64(h.sum/time.Microsecond) / float64(h.count)
+	variance := h.sumSquares/float64(h.count) - mean*mean
+	if variance <= 0 {
+		return 0
+	}
+	return time.Duration(math.Sqrt(variance)) * time.Microsecond
+}
+
+// Percentile returns the duration below which p percent, between 0 and 100, of the durations fall (nearest rank),
+// or 0 if there is none. The duration is the upper bound of its bucket, within the smallest and largest durations counted.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return h.min
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(h.count)))
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return h.clamp(bucketUpperBound(i))
+		}
+	}
+	return h.max
+}
+
+// PercentilePoint is a point of the percentile distribution of a histogram.
+type PercentilePoint struct {
+	// Percentile is between 0 and 100.
+	Percentile float64
+	Value      time.Duration
+	// TotalCount is the number of durations counted up to Value.
+	TotalCount uint64
+}
+
+// Distribution returns the percentile distribution of the durations in the fashion of HDR histograms: the distance to
+// 100% is halved repeatedly and every half is split into ticksPerHalfDistance points, so that the tail is detailed.
+// The last point is always at 100%. It returns nil if no duration was counted.
+func (h *Histogram) Distribution(ticksPerHalfDistance int) []PercentilePoint {
+	if h.count == 0 {
+		return nil
+	}
+	var points []PercentilePoint
+	for p := 0.0; ; {
+		value := h.Percentile(p)
+		count := h.countUpTo(value)
+		if count >= h.count {
+			return append(points, PercentilePoint{Percentile: 100, Value: h.max, TotalCount: h.count})
+		}
+		points = append(points, PercentilePoint{Percentile: p, Value: value, TotalCount: count})
+		halvings := math.Floor(math.Log2(100 / (100 - p)))
+		p += 100 / (float64(ticksPerHalfDistance) * math.Pow(2, halvings+1))
+	}
+}
+
+// countUpTo returns the number of durations counted in the buckets up to the one of d.
+func (h *Histogram) countUpTo(d time.Duration) uint64 {
+	var count uint64
+	for i := 0; i <= bucketIndex(d); i++ {
+		count += h.counts[i]
+	}
+	return count
+}
+
+// Layout returns the number of power of two ranges the histogram covers and the number of buckets of the first one,
+// as reported in HDR histogram logs.
+func (h *Histogram) Layout() (int, int) {
+	return maxShift + 1, exactBuckets
+}
+
+// clamp bounds d to the durations counted.
+func (h *Histogram) clamp(d time.Duration) time.Duration {
+	if d < h.min {
+		return h.min
+	}
+	if d > h.max {
+		return h.max
+	}
+	return d
+}
+
+// Copy returns a copy of the histogram.
+func (h *Histogram) Copy() *Histogram {
+	c := *h
+	c.counts = append([]uint64(nil), h.counts...)
+	return &c
+}
+
+// bucketIndex returns the index of the bucket of a duration.
+func bucketIndex(d time.Duration) int {
+	v := uint64(d / time.Microsecond)
+	if v < exactBuckets {
+		return int(v)
+	}
+	// shift v so that it falls in [subBuckets, 2*subBuckets)
+	shift := bits.Len64(v) - bits.Len64(subBuckets)
+	if shift > maxShift {
+		return bucketCount - 1
+	}
+	return exactBuckets + (shift-1)*subBuckets + int(v>>shift) - subBuckets
+}
+
+// bucketUpperBound returns the largest duration counted in a bucket.
+func bucketUpperBound(index int) time.Duration {
+	if index == bucketCount-1 {
+		return math.MaxInt64
+	}
+	if index < exactBuckets {
+		return time.Duration(index) * time.Microsecond
+	}
+	k := index - exactBuckets
+	shift := k/subBuckets + 1
+	m := uint64(k%subBuckets + subBuckets)
+	return time.Duration(((m+1)<<shift)-1) * time.Microsecond
+}
@@ -0,0 +1,136 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package stats
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram_Empty(t *testing.T) {
+	h := NewHistogram()
+
+	assert.Zero(t, h.Count())
+	assert.Zero(t, h.Percentile(99))
+	assert.Zero(t, h.Mean())
+}
+
+func TestHistogram_Stats(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(t, uint64(100), h.Count())
+	assert.Equal(t, time.Millisecond, h.Min())
+	assert.Equal(t, 100*time.Millisecond, h.Max())
+	assert.Equal(t, 50500*time.Microsecond, h.Mean())
+	assert.InEpsilon(t, float64(50*time.Millisecond), float64(h.Percentile(50)), 0.016)
+	assert.InEpsilon(t, float64(90*time.Millisecond), float64(h.Percentile(90)), 0.016)
+	assert.Equal(t, 100*time.Millisecond, h.Percentile(100))
+	assert.Equal(t, time.Millisecond, h.Percentile(0))
+}
+
+func TestHistogram_PercentilesAreAccurate(t *testing.T) {
+	h := NewHistogram()
+	rnd := rand.New(rand.NewSource(1))
+	var durations []time.Duration
+	for i := 0; i < 10000; i++ {
+		d := time.Duration(rnd.ExpFloat64() * float64(20*time.Millisecond))
+		durations = append(durations, d)
+		h.Record(d)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		exact := durations[int(p/100*float64(len(durations)))-1]
+		assert.InEpsilon(t, float64(exact), float64(h.Percentile(p)), 0.02, "p%v", p)
+	}
+}
+
+func TestHistogram_SmallAndHugeDurations(t *testing.T) {
+	h := NewHistogram()
+	h.Record(-time.Second)
+	h.Record(5 * time.Microsecond)
+	h.Record(100 * time.Hour)
+
+	assert.Equal(t, time.Duration(0), h.Percentile(1))
+	assert.Equal(t, 5*time.Microsecond, h.Percentile(50))
+	assert.Equal(t, 100*time.Hour, h.Percentile(100))
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	a := NewHistogram()
+	a.Record(10 * time.Millisecond)
+	b := NewHistogram()
+	b.Record(time.Millisecond)
+	b.Record(30 * time.Millisecond)
+
+	a.Merge(b)
+	assert.Equal(t, uint64(3), a.Count())
+	assert.Equal(t, time.Millisecond, a.Min())
+	assert.Equal(t, 30*time.Millisecond, a.Max())
+
+	c := a.Copy()
+	c.Record(time.Hour)
+	assert.Equal(t, uint64(3), a.Count())
+}
+
+func TestBucketIndex_IsMonotonic(t *testing.T) {
+	previous := 0
+	for v := time.Duration(0); v < 10*time.Second; v = v*11/10 + time.Microsecond {
+		index := bucketIndex(v)
+		assert.GreaterOrEqual(t, index, previous)
+		assert.GreaterOrEqual(t, bucketUpperBound(index), v.Truncate(time.Microsecond))
+		previous = index
+	}
+}
+
+func TestHistogram_StdDev(t *testing.T) {
+	h := NewHistogram()
+	for _, d := range []time.Duration{2, 4, 4, 4, 5, 5, 7, 9} {
+		h.Record(d * time.Millisecond)
+	}
+
+	assert.Equal(t, 2*time.Millisecond, h.StdDev())
+	assert.Zero(t, NewHistogram().StdDev())
+}
+
+func TestHistogram_Distribution(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	points := h.Distribution(5)
+	require.NotEmpty(t, points)
+	assert.Equal(t, PercentilePoint{Percentile: 0, Value: time.Millisecond, TotalCount: 1}, points[0])
+	assert.Equal(t, 10.0, points[1].Percentile)
+	assert.Equal(t, 50.0, points[5].Percentile)
+	assert.Equal(t, 55.0, points[6].Percentile)
+	assert.Equal(t, PercentilePoint{Percentile: 100, Value: time.Second, TotalCount: 1000}, points[len(points)-1])
+	for i := 1; i < len(points); i++ {
+		assert.Greater(t, points[i].Percentile, points[i-1].Percentile)
+		assert.GreaterOrEqual(t, points[i].Value, points[i-1].Value)
+		assert.GreaterOrEqual(t, points[i].TotalCount, points[i-1].TotalCount)
+	}
+
+	assert.Nil(t, NewHistogram().Distribution(5))
+}
@@ -0,0 +1,117 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package stats
+
+import (
+	"time"
+)
+
+// openSeconds is the number of most recent seconds whose latencies are kept in full, so that requests completing
+// slightly out of order still count towards the right second.
+const openSeconds = 3
+
+// Second holds the outcome of the requests that completed during one second of the warmup.
+type Second struct {
+	// Second is the number of whole seconds elapsed since the start of the warmup.
+	Second    int
+	Sent      int
+	Successes int
+	Failures  int
+	// P95 is the 95th percentile of the latencies of the responses, 0 if there were none.
+	P95 time.Duration
+}
+
+// Timeseries aggregates the outcome of requests into one-second buckets as they are recorded.
+// Only the latencies of the most recent seconds are kept in full, older seconds are reduced to their 95th percentile,
+// so the memory used grows by a few bytes per second. A Timeseries is not safe for concurrent use.
+type Timeseries struct {
+	start   time.Time
+	seconds []Second
+	// latencies of the seconds that are still open, by second
+	latencies map[int]*Histogram
+}
+
+// NewTimeseries returns an empty time series whose seconds are counted from start.
+func NewTimeseries(start time.Time) *Timeseries {
+	return &Timeseries{start: start, latencies: make(map[int]*Histogram)}
+}
+
+// Record counts a request that completed at the given time.
+func (t *Timeseries) Record(at time.Time, success bool) {
+	s := t.second(at)
+	s.Sent++
+	if success {
+		s.Successes++
+	} else {
+		s.Failures++
+	}
+}
+
+// RecordLatency counts the latency of a response received at the given time.
+// Latencies of seconds that were already closed are ignored.
+func (t *Timeseries) RecordLatency(at time.Time, d time.Duration) {
+	s := t.second(at)
+	if h, ok := t.latencies[s.Second]; ok {
+		h.Record(d)
+	}
+}
+
+// Seconds returns every second from the start of the time series to the last one recorded.
+func (t *Timeseries) Seconds() []Second {
+	seconds := make([]Second, len(t.seconds))
+	copy(seconds, t.seconds)
+	for second, h := range t.latencies {
+		seconds[second].P95 = h.Percentile(95)
+	}
+	return seconds
+}
+
+// Recent returns the number of requests sent and failed during the given number of seconds up to at.
+func (t *Timeseries) Recent(at time.Time, seconds int) (int, int) {
+	n := int(at.Sub(t.start) / time.Second)
+	sent, failures := 0, 0
+	for i := n - seconds + 1; i <= n && i < len(t.seconds); i++ {
+		if i >= 0 {
+			sent += t.seconds[i].Sent
+			failures += t.seconds[i].Failures
+		}
+	}
+	return sent, failures
+}
+
+// second returns the bucket of the given time, adding the seconds up to it and closing the ones that are no longer open.
+func (t *Timeseries) second(at time.Time) *Second {
+	n := int(at.Sub(t.start) / time.Second)
+	if n < 0 {
+		n = 0
+	}
+	if n < len(t.seconds) {
+		return &t.seconds[n]
+	}
+	for len(t.seconds) <= n {
+		second := len(t.seconds)
+		t.seconds = append(t.seconds, Second{Second: second})
+		if second > n-openSeconds {
+			t.latencies[second] = NewHistogram()
+		}
+	}
+	for second, h := range t.latencies {
+		if second <= n-openSeconds {
+			t.seconds[second].P95 = h.Percentile(95)
+			delete(t.latencies, second)
+		}
+	}
+	return &t.seconds[n]
+}
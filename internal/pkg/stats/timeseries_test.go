@@ -0,0 +1,84 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeseries(t *testing.T) {
+	start := time.Now()
+	ts := NewTimeseries(start)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	for i := 1; i <= 20; i++ {
+		ts.Record(at(100*time.Millisecond), true)
+		ts.RecordLatency(at(100*time.Millisecond), time.Duration(i)*time.Millisecond)
+	}
+	ts.Record(at(2500*time.Millisecond), false)
+	ts.RecordLatency(at(2500*time.Millisecond), 7*time.Millisecond)
+
+	seconds := ts.Seconds()
+	// percentiles are accurate to within 1.6%
+	assert.InDelta(t, 19*time.Millisecond, seconds[0].P95, float64(300*time.Microsecond))
+	seconds[0].P95 = 0
+	assert.Equal(t, []Second{
+		{Second: 0, Sent: 20, Successes: 20},
+		{Second: 1},
+		{Second: 2, Sent: 1, Failures: 1, P95: 7 * time.Millisecond},
+	}, seconds)
+}
+
+func TestTimeseries_Recent(t *testing.T) {
+	start := time.Now()
+	ts := NewTimeseries(start)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	ts.Record(at(100*time.Millisecond), true)
+	ts.Record(at(1100*time.Millisecond), false)
+	ts.Record(at(2100*time.Millisecond), false)
+	ts.Record(at(2200*time.Millisecond), true)
+
+	sent, failures := ts.Recent(at(2500*time.Millisecond), 2)
+	assert.Equal(t, 3, sent)
+	assert.Equal(t, 2, failures)
+	sent, failures = ts.Recent(at(2500*time.Millisecond), 10)
+	assert.Equal(t, 4, sent)
+	assert.Equal(t, 2, failures)
+	// nothing was recorded during the last seconds
+	sent, failures = ts.Recent(at(10*time.Second), 2)
+	assert.Equal(t, 0, sent)
+	assert.Equal(t, 0, failures)
+}
+
+func TestTimeseries_ClosesOldSeconds(t *testing.T) {
+	start := time.Now()
+	ts := NewTimeseries(start)
+
+	ts.Record(start, true)
+	ts.RecordLatency(start, 5*time.Millisecond)
+	ts.Record(start.Add(time.Hour), true)
+	assert.Len(t, ts.latencies, openSeconds)
+
+	// the first second was closed so late latencies no longer count
+	ts.RecordLatency(start, 50*time.Millisecond)
+	seconds := ts.Seconds()
+	assert.Len(t, seconds, 3601)
+	assert.Equal(t, Second{Second: 0, Sent: 1, Successes: 1, P95: 5 * time.Millisecond}, seconds[0])
+	assert.Equal(t, Second{Second: 3600, Sent: 1, Successes: 1}, seconds[3600])
+}
@@ -0,0 +1,75 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package stats
+
+import (
+	"time"
+)
+
+// Window keeps the latencies of the last few seconds so that percentiles can be computed over a rolling window.
+// The latencies are counted in a histogram per second, so the memory used only depends on the size of the window.
+// A Window is not safe for concurrent use.
+type Window struct {
+	start time.Time
+	// histograms of the seconds of the window, second n is at index n % len(seconds)
+	seconds []*Histogram
+	// second counted by each histogram, since start
+	counted []int
+}
+
+// NewWindow returns an empty window of the given number of seconds, counted from start.
+func NewWindow(seconds int, start time.Time) *Window {
+	if seconds < 1 {
+		seconds = 1
+	}
+	w := &Window{start: start, seconds: make([]*Histogram, seconds), counted: make([]int, seconds)}
+	for i := range w.seconds {
+		w.seconds[i] = NewHistogram()
+		w.counted[i] = -1
+	}
+	return w
+}
+
+// Record counts a latency measured at the given time.
+func (w *Window) Record(at time.Time, d time.Duration) {
+	second := w.second(at)
+	i := second % len(w.seconds)
+	if w.counted[i] != second {
+		// the histogram still counts a second that left the window
+		w.seconds[i] = NewHistogram()
+		w.counted[i] = second
+	}
+	w.seconds[i].Record(d)
+}
+
+// Latencies returns the latencies counted during the window that ends at the given time.
+func (w *Window) Latencies(at time.Time) *Histogram {
+	latencies := NewHistogram()
+	second := w.second(at)
+	for i, h := range w.seconds {
+		if w.counted[i] > second-len(w.seconds) && w.counted[i] <= second {
+			latencies.Merge(h)
+		}
+	}
+	return latencies
+}
+
+func (w *Window) second(at time.Time) int {
+	second := int(at.Sub(w.start) / time.Second)
+	if second < 0 {
+		return 0
+	}
+	return second
+}
@@ -0,0 +1,45 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	start := time.Now()
+	w := NewWindow(3, start)
+	at := func(second int) time.Time { return start.Add(time.Duration(second)*time.Second + 500*time.Millisecond) }
+
+	w.Record(at(0), 100*time.Millisecond)
+	w.Record(at(1), 10*time.Millisecond)
+	w.Record(at(2), 20*time.Millisecond)
+	assert.Equal(t, uint64(3), w.Latencies(at(2)).Count())
+	assert.Equal(t, 100*time.Millisecond, w.Latencies(at(2)).Max())
+
+	// the first second left the window
+	assert.Equal(t, uint64(2), w.Latencies(at(3)).Count())
+	assert.Equal(t, 20*time.Millisecond, w.Latencies(at(3)).Max())
+
+	// its histogram is reused for the new second
+	w.Record(at(3), 30*time.Millisecond)
+	assert.Equal(t, uint64(3), w.Latencies(at(3)).Count())
+	assert.Equal(t, 30*time.Millisecond, w.Latencies(at(3)).Max())
+
+	assert.Equal(t, uint64(0), w.Latencies(at(10)).Count())
+}
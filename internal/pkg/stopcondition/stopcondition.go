@@ -0,0 +1,118 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// External conditions that stop the warmup.
+
+package stopcondition
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	filePrefix = "file:"
+	// StopBody is the body a control endpoint returns to stop the warmup.
+	StopBody = "stop"
+)
+
+// Condition is an external signal that the warmup should stop.
+type Condition interface {
+	// Satisfied returns true once the warmup should stop.
+	Satisfied() bool
+	String() string
+}
+
+// Parse parses a stop condition. It is either `file:<path>`, satisfied once the file exists,
+// or an http(s) URL, satisfied once the URL responds 200 with the body "stop".
+func Parse(condition string) (Condition, error) {
+	switch {
+	case strings.HasPrefix(condition, filePrefix):
+		path := strings.TrimPrefix(condition, filePrefix)
+		if path == "" {
+			return nil, fmt.Errorf("missing file path in stop condition %q", condition)
+		}
+		return fileCondition{path: path}, nil
+	case strings.HasPrefix(condition, "http://") || strings.HasPrefix(condition, "https://"):
+		return httpCondition{url: condition, client: &http.Client{Timeout: 5 * time.Second}}, nil
+	default:
+		return nil, fmt.Errorf("invalid stop condition %q, it must be file:<path> or an http(s) URL", condition)
+	}
+}
+
+// Watch returns a context that is cancelled once the condition is satisfied, which is polled every interval, or once ctx is done.
+func Watch(ctx context.Context, condition Condition, interval time.Duration) context.Context {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if condition.Satisfied() {
+				log.Printf("Stop condition %s is satisfied, stopping the warmup", condition)
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ctx
+}
+
+// fileCondition is satisfied once a file exists.
+type fileCondition struct {
+	path string
+}
+
+func (c fileCondition) Satisfied() bool {
+	_, err := os.Stat(c.path)
+	return err == nil
+}
+
+func (c fileCondition) String() string {
+	return filePrefix + c.path
+}
+
+// httpCondition is satisfied once a control endpoint responds 200 with the body "stop".
+type httpCondition struct {
+	url    string
+	client *http.Client
+}
+
+func (c httpCondition) Satisfied() bool {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		log.Printf("Stop condition %s is not reachable: %v", c.url, err)
+		return false
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return false
+	}
+	return resp.StatusCode == http.StatusOK && strings.EqualFold(strings.TrimSpace(string(body)), StopBody)
+}
+
+func (c httpCondition) String() string {
+	return c.url
+}
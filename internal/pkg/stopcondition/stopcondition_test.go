@@ -0,0 +1,82 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package stopcondition
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, condition := range []string{"", "file:", "stop", "ftp://host/stop"} {
+		_, err := Parse(condition)
+		assert.Error(t, err, condition)
+	}
+}
+
+func TestFileCondition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stop")
+	condition, err := Parse("file:" + path)
+	require.NoError(t, err)
+
+	assert.False(t, condition.Satisfied())
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	assert.True(t, condition.Satisfied())
+}
+
+func TestHTTPCondition(t *testing.T) {
+	var stop atomic.Value
+	stop.Store("continue")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(stop.Load().(string)))
+	}))
+	defer server.Close()
+
+	condition, err := Parse(server.URL)
+	require.NoError(t, err)
+
+	assert.False(t, condition.Satisfied())
+	stop.Store("STOP\n")
+	assert.True(t, condition.Satisfied())
+}
+
+func TestWatch_CancelsOnceSatisfied(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stop")
+	condition, err := Parse("file:" + path)
+	require.NoError(t, err)
+
+	ctx := Watch(context.Background(), condition, 10*time.Millisecond)
+	select {
+	case <-ctx.Done():
+		t.Fatal("context cancelled before the condition was satisfied")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context not cancelled once the condition was satisfied")
+	}
+}
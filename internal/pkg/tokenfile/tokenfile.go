@@ -0,0 +1,72 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Tokens read from files that are rotated by another process, e.g. short-lived bearer tokens.
+
+package tokenfile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File is a token read from a file. The file is read again once the token is older than the TTL so that rotated
+// tokens take effect without reading the file for every request. It is safe for concurrent use.
+type File struct {
+	path string
+	ttl  time.Duration
+	// now returns the current time, it is replaced in tests
+	now    func() time.Time
+	mu     sync.Mutex
+	token  string
+	readAt time.Time
+}
+
+// New returns the token of the file at path, read again when older than ttl. A ttl of 0 reads the file every time.
+func New(path string, ttl time.Duration) *File {
+	return &File{path: path, ttl: ttl, now: time.Now}
+}
+
+// Token returns the token, the content of the file without its surrounding whitespace.
+func (f *File) Token() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if f.token != "" && now.Sub(f.readAt) < f.ttl {
+		return f.token, nil
+	}
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read token file: %v", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		// e.g. the file is being rewritten
+		return "", fmt.Errorf("token file %s is empty", f.path)
+	}
+	f.token, f.readAt = token, now
+	return token, nil
+}
+
+// Bearer returns the value of the Authorization header of the token.
+func (f *File) Bearer() (string, error) {
+	token, err := f.Token()
+	if err != nil {
+		return "", err
+	}
+	return "Bearer " + token, nil
+}
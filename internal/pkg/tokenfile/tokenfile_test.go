@@ -0,0 +1,58 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package tokenfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_ReadsTheRotatedTokenOnceTheTTLExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0600))
+	now := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	f := New(path, time.Second)
+	f.now = func() time.Time { return now }
+
+	bearer, err := f.Bearer()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer first", bearer)
+
+	require.NoError(t, os.WriteFile(path, []byte("second"), 0600))
+	token, err := f.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "first", token, "the token is cached for the TTL")
+
+	now = now.Add(time.Second)
+	token, err = f.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "second", token)
+}
+
+func TestFile_Errors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := New(filepath.Join(dir, "missing"), 0).Token()
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, "empty")
+	require.NoError(t, os.WriteFile(path, []byte(" \n"), 0600))
+	_, err = New(path, 0).Bearer()
+	assert.EqualError(t, err, "token file "+path+" is empty")
+}
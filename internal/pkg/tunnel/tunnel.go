@@ -0,0 +1,116 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+// Tunnels through a forward proxy using HTTP CONNECT.
+
+package tunnel
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Dialer opens connections to the target through a CONNECT tunnel established with a forward proxy.
+type Dialer struct {
+	proxy *url.URL
+	// dial opens the connection to the proxy
+	dial func(ctx context.Context, network string, address string) (net.Conn, error)
+}
+
+// NewDialer returns a dialer that tunnels through the proxy, given as `[http://][user:password@]host:port`.
+// If dial is nil the connections to the proxy are opened with a default net.Dialer.
+func NewDialer(proxy string, dial func(ctx context.Context, network string, address string) (net.Conn, error)) (*Dialer, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		// accept plain host:port
+		proxyURL, err = url.Parse("http://" + proxy)
+	}
+	if err != nil || proxyURL.Host == "" || proxyURL.Port() == "" {
+		return nil, fmt.Errorf("invalid proxy: %s, expected format [http://][user:password@]host:port", proxy)
+	}
+	if proxyURL.Scheme != "http" {
+		return nil, fmt.Errorf("invalid proxy: %s, only http proxies are supported", proxy)
+	}
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return &Dialer{proxy: proxyURL, dial: dial}, nil
+}
+
+// DialContext connects to the proxy and asks it to open a tunnel to the address. It logs how long establishing the tunnel took.
+func (d *Dialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	start := time.Now()
+	conn, err := d.dial(ctx, network, d.proxy.Host)
+	if err != nil {
+		return nil, fmt.Errorf("connect to proxy %s: %v", d.proxy.Host, err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := d.proxy.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send CONNECT to proxy %s: %v", d.proxy.Host, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read CONNECT response from proxy %s: %v", d.proxy.Host, err)
+	}
+	// the body of a successful CONNECT response is the tunnel itself, so it is not read
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused tunnel to %s: %s", d.proxy.Host, address, resp.Status)
+	}
+
+	log.Printf("Tunnel to %s through proxy %s established in %d ms", address, d.proxy.Host, time.Since(start)/time.Millisecond)
+	if reader.Buffered() > 0 {
+		// the target already sent data that was read along with the CONNECT response
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were already read into a buffer.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,127 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package tunnel
+
+import (
+	"context"
+	"io"
+	"mittens/fixture"
+	"mittens/internal/pkg/grpc"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startConnectProxy starts a forward proxy that only supports CONNECT and records the requested addresses.
+func startConnectProxy(t *testing.T, tunnels *[]string) *httptest.Server {
+	proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Proxy-Authorization") != "Basic dXNlcjpzZWNyZXQ=" {
+			rw.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		*tunnels = append(*tunnels, r.Host)
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			io.Copy(target, conn)
+			target.Close()
+		}()
+		io.Copy(conn, target)
+		conn.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestDialThroughTunnel(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("pong"))
+	}))
+	defer target.Close()
+
+	var tunnels []string
+	proxy := startConnectProxy(t, &tunnels)
+
+	dialer, err := NewDialer("http://user:secret@"+proxy.Listener.Addr().String(), nil)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+
+	resp, err := client.Get(target.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, "pong", string(body))
+	assert.Equal(t, []string{target.Listener.Addr().String()}, tunnels)
+}
+
+func TestGrpcThroughTunnel(t *testing.T) {
+	target := fixture.StartGrpcTargetTestServerOnFreePort(t)
+
+	var tunnels []string
+	proxy := startConnectProxy(t, &tunnels)
+	dialer, err := NewDialer("http://user:secret@"+proxy.Listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	client, err := grpc.NewClient(target, true, grpc.ClientOptions{DialContext: dialer.DialContext})
+	require.NoError(t, err)
+	require.NoError(t, client.Connect(nil))
+	defer client.Close()
+
+	// the methods are listed over the tunnel with the reflection service
+	methods, err := client.DiscoverMethods(nil)
+	require.NoError(t, err)
+	assert.Contains(t, methods, "grpc.testing.TestService/EmptyCall")
+	assert.Equal(t, []string{target}, tunnels)
+}
+
+func TestDialRefusedByProxy(t *testing.T) {
+	var tunnels []string
+	proxy := startConnectProxy(t, &tunnels)
+
+	// no credentials
+	dialer, err := NewDialer(proxy.Listener.Addr().String(), nil)
+	require.NoError(t, err)
+
+	_, err = dialer.DialContext(context.Background(), "tcp", "localhost:1234")
+	assert.ErrorContains(t, err, "407")
+	assert.Empty(t, tunnels)
+}
+
+func TestNewDialerInvalidProxy(t *testing.T) {
+	_, err := NewDialer("localhost", nil)
+	assert.Error(t, err)
+
+	_, err = NewDialer("https://localhost:3128", nil)
+	assert.Error(t, err)
+}
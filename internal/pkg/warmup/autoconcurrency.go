@@ -0,0 +1,91 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"log"
+	"math"
+	"mittens/internal/pkg/placeholders"
+	"runtime"
+	"time"
+)
+
+// latencySamples is the number of requests sent to measure the latency of the target before picking the concurrency.
+const latencySamples = 5
+
+// autoConcurrency measures the latency of the target and returns the concurrency needed to reach TargetRequestsPerSecond.
+// The latency is measured with the HTTP requests if there are any, and with the gRPC requests otherwise.
+func (w *Warmup) autoConcurrency(hasHttpRequests bool, hasGrpcRequests bool, requestsSentCounter *int64) int {
+	var total time.Duration
+	var measured int
+	// the probes are sent by a worker of their own that comes before the actual workers
+	worker := placeholders.NewWorker(w.WorkerSeed)
+	for i := 0; i < latencySamples && !w.stopped(); i++ {
+		if hasHttpRequests && len(w.HttpRequests) > 0 {
+			resp := w.sendHTTPRequest(w.HttpRequests[i%len(w.HttpRequests)], w.HttpHeaders, worker, requestsSentCounter)
+			if resp.Err == nil {
+				total += resp.Duration
+				measured++
+			}
+		} else if hasGrpcRequests && len(w.GrpcRequests) > 0 {
+			resp := w.sendGrpcRequest(w.GrpcRequests[i%len(w.GrpcRequests)], w.HttpHeaders, worker, requestsSentCounter)
+			if resp.Err == nil {
+				total += resp.Duration
+				measured++
+			}
+		}
+	}
+	if measured == 0 {
+		concurrency := boundConcurrency(w.Concurrency, w.MinConcurrency, w.MaxConcurrency)
+		log.Printf("Could not measure the latency of the target, using a concurrency of %d", concurrency)
+		return concurrency
+	}
+
+	latency := total / time.Duration(measured)
+	concurrency := concurrencyForRate(w.TargetRequestsPerSecond, latency, time.Duration(w.RequestDelayMilliseconds)*time.Millisecond, w.MinConcurrency, w.MaxConcurrency)
+	log.Printf("Average latency of %d ms, using a concurrency of %d to reach %d reqs/s", latency/time.Millisecond, concurrency, w.TargetRequestsPerSecond)
+	return concurrency
+}
+
+// concurrencyForRate applies Little's Law: every worker sends a request every delay+latency so
+// the number of workers needed to reach the rate is rate*(delay+latency), bounded by min and max.
+func concurrencyForRate(requestsPerSecond int, latency time.Duration, delay time.Duration, min int, max int) int {
+	concurrency := int(math.Ceil(float64(requestsPerSecond) * (latency + delay).Seconds()))
+	return boundConcurrency(concurrency, min, max)
+}
+
+// boundConcurrency keeps the concurrency between min and max. It is always at least 1 and max is ignored if it is not greater than 0.
+func boundConcurrency(concurrency int, min int, max int) int {
+	if max > 0 && concurrency > max {
+		concurrency = max
+	}
+	if concurrency < min {
+		concurrency = min
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return concurrency
+}
+
+// cpuConcurrency returns the concurrency, or the number of CPUs if it is not greater than 0.
+func cpuConcurrency(concurrency int) int {
+	if concurrency > 0 {
+		return concurrency
+	}
+	cpus := runtime.NumCPU()
+	log.Printf("Concurrency not set, using the %d CPU(s)", cpus)
+	return cpus
+}
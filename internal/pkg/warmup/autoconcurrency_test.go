@@ -0,0 +1,56 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPUConcurrency(t *testing.T) {
+	assert.Equal(t, 3, cpuConcurrency(3))
+	assert.Equal(t, runtime.NumCPU(), cpuConcurrency(0))
+	assert.Equal(t, runtime.NumCPU(), cpuConcurrency(-2))
+}
+
+func TestConcurrencyForRate(t *testing.T) {
+	// every worker sends 1 req every 100ms + 400ms
+	assert.Equal(t, 50, concurrencyForRate(100, 100*time.Millisecond, 400*time.Millisecond, 1, 0))
+	assert.Equal(t, 1, concurrencyForRate(1, 10*time.Millisecond, 0, 1, 0))
+	assert.Equal(t, 3, concurrencyForRate(11, 250*time.Millisecond, 0, 1, 0))
+}
+
+func TestConcurrencyForRateIsBounded(t *testing.T) {
+	assert.Equal(t, 20, concurrencyForRate(100, 100*time.Millisecond, 400*time.Millisecond, 1, 20))
+	assert.Equal(t, 5, concurrencyForRate(1, 10*time.Millisecond, 0, 5, 20))
+	assert.Equal(t, 1, concurrencyForRate(0, 10*time.Millisecond, 0, 0, 0))
+}
+
+func TestAutoConcurrency_NoLatencyLogsTheBoundedConcurrency(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	w := Warmup{Concurrency: 50, MaxConcurrency: 10}
+
+	var requestsSent int64
+	assert.Equal(t, 10, w.autoConcurrency(false, false, &requestsSent))
+	assert.Contains(t, logs.String(), "using a concurrency of 10")
+}
@@ -0,0 +1,58 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// requestBudget is the number of requests that can still be fed to the workers of a run, shared by all its feeders.
+// A nil budget is unlimited.
+type requestBudget struct {
+	max       int
+	remaining int64
+	once      sync.Once
+	spent     chan struct{}
+}
+
+func newRequestBudget(max int) *requestBudget {
+	return &requestBudget{max: max, remaining: int64(max), spent: make(chan struct{})}
+}
+
+// take takes n requests from the budget and returns true if any was left.
+// A burst is taken as a whole, so the last one may exceed the budget.
+func (b *requestBudget) take(n int) bool {
+	if b == nil {
+		return true
+	}
+	remaining := atomic.AddInt64(&b.remaining, -int64(n))
+	if remaining <= 0 {
+		b.once.Do(func() {
+			log.Printf("Budget of %d request(s) reached, no more requests are sent", b.max)
+			close(b.spent)
+		})
+	}
+	return remaining+int64(n) > 0
+}
+
+// done returns a channel that is closed once the budget is spent. It is never closed for a nil budget.
+func (b *requestBudget) done() <-chan struct{} {
+	if b == nil {
+		return nil
+	}
+	return b.spent
+}
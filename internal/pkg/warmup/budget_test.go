@@ -0,0 +1,41 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestBudget(t *testing.T) {
+	budget := newRequestBudget(5)
+
+	assert.True(t, budget.take(3))
+	assert.True(t, budget.take(3), "a burst is taken as a whole")
+	assert.False(t, budget.take(1))
+	select {
+	case <-budget.done():
+	default:
+		t.Fatal("the budget should be spent")
+	}
+}
+
+func TestRequestBudget_NilIsUnlimited(t *testing.T) {
+	var budget *requestBudget
+
+	assert.True(t, budget.take(1000))
+	assert.Nil(t, budget.done())
+}
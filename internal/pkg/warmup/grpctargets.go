@@ -0,0 +1,99 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"fmt"
+	"log"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/marker"
+	"mittens/internal/pkg/safe"
+	"sync"
+	"sync/atomic"
+)
+
+// grpcTargets spreads the gRPC warmup requests across the clients connected to the gRPC hosts, in turn.
+type grpcTargets struct {
+	clients []*grpc.Client
+	sent    uint64
+}
+
+// next returns the client the next request is sent with.
+func (t *grpcTargets) next() *grpc.Client {
+	if len(t.clients) == 1 {
+		return t.clients[0]
+	}
+	n := atomic.AddUint64(&t.sent, 1)
+	return t.clients[(n-1)%uint64(len(t.clients))]
+}
+
+// connectGrpcTargets connects, in parallel, the gRPC warmup client and the clients of the additional gRPC hosts that are not
+// connected yet. Hosts that cannot be connected to are logged and left out, so that the others are still warmed up.
+// An error is only returned if no host could be connected to.
+func (w *Warmup) connectGrpcTargets() (*grpcTargets, error) {
+	clients := w.Target.grpcClients()
+	headers := w.withGrpcMetadata(w.HttpHeaders)
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		if client.Connected() {
+			continue
+		}
+		log.Printf("gRPC client of %s connecting...", client.Host())
+		i, client := i, client
+		wg.Add(1)
+		go safe.Do(func() {
+			defer wg.Done()
+			errs[i] = client.Connect(headers)
+		})
+	}
+	wg.Wait()
+
+	targets := &grpcTargets{}
+	for i, client := range clients {
+		if errs[i] != nil {
+			if len(clients) > 1 {
+				log.Printf("%s gRPC client of %s connect error, no warmup requests will be sent to it: %v", marker.Failure(), client.Host(), errs[i])
+			}
+			continue
+		}
+		targets.clients = append(targets.clients, client)
+	}
+	if len(targets.clients) == 0 {
+		if len(clients) > 1 {
+			return nil, fmt.Errorf("none of the %d gRPC hosts could be connected to", len(clients))
+		}
+		return nil, errs[0]
+	}
+	return targets, nil
+}
+
+// nextGrpcClient returns the client the next gRPC warmup request is sent with: the connected clients in turn once Run
+// has connected them, the gRPC warmup client of the target otherwise.
+func (w Warmup) nextGrpcClient() *grpc.Client {
+	if w.grpcTargets == nil {
+		return &w.Target.grpcClient
+	}
+	return w.grpcTargets.next()
+}
+
+// descriptorGrpcClient returns the client whose descriptor source resolves the gRPC methods: the first connected client
+// once Run has connected them, the gRPC warmup client of the target otherwise.
+func (w Warmup) descriptorGrpcClient() *grpc.Client {
+	if w.grpcTargets == nil {
+		return &w.Target.grpcClient
+	}
+	return w.grpcTargets.clients[0]
+}
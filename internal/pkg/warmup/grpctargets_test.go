@@ -0,0 +1,58 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"mittens/fixture"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrpcTargets_Next(t *testing.T) {
+	first, second := &grpc.Client{}, &grpc.Client{}
+	targets := &grpcTargets{clients: []*grpc.Client{first, second}}
+
+	assert.Same(t, first, targets.next())
+	assert.Same(t, second, targets.next())
+	assert.Same(t, first, targets.next())
+}
+
+func TestConnectGrpcTargets_SkipsHostsThatCannotBeConnectedTo(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener.Close()
+	down := newGrpcClient(t, listener.Addr().String(), true, grpc.ClientOptions{DialTimeout: 100 * time.Millisecond})
+	up := newGrpcClient(t, address, true, grpc.ClientOptions{})
+	w := Warmup{Target: NewTarget(http.Client{}, down, http.Client{}, down, TargetOptions{}).WithAdditionalGrpcClients(up)}
+
+	targets, err := w.connectGrpcTargets()
+	require.NoError(t, err)
+	defer w.Target.additionalGrpcClients[0].Close()
+	require.Len(t, targets.clients, 1)
+	assert.Equal(t, up.Host(), targets.next().Host())
+	assert.True(t, w.Target.additionalGrpcClients[0].Connected())
+
+	w = Warmup{Target: NewTarget(http.Client{}, down, http.Client{}, down, TargetOptions{}).WithAdditionalGrpcClients(down)}
+	_, err = w.connectGrpcTargets()
+	assert.EqualError(t, err, "none of the 2 gRPC hosts could be connected to")
+}
@@ -0,0 +1,172 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/placeholders"
+	"mittens/internal/pkg/ratelimit"
+	"mittens/internal/pkg/safe"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolMix is the share of the requests sent with each protocol when HTTP and gRPC requests are sent by the same workers.
+type ProtocolMix struct {
+	HTTPWeight int
+	GrpcWeight int
+}
+
+// ParseProtocolMix parses a protocol mix in the `http=<weight>,grpc=<weight>` format, e.g. `http=70,grpc=30`.
+func ParseProtocolMix(mix string) (ProtocolMix, error) {
+	var m ProtocolMix
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(mix, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return ProtocolMix{}, fmt.Errorf("invalid protocol mix %s, expected format http=<weight>,grpc=<weight>", mix)
+		}
+		protocol := strings.ToLower(strings.TrimSpace(kv[0]))
+		weight, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil || weight < 0 {
+			return ProtocolMix{}, fmt.Errorf("invalid protocol mix %s, weights must be non-negative integers", mix)
+		}
+		switch protocol {
+		case "http":
+			m.HTTPWeight = weight
+		case "grpc":
+			m.GrpcWeight = weight
+		default:
+			return ProtocolMix{}, fmt.Errorf("invalid protocol mix %s, protocol %s is not one of [http, grpc]", mix, protocol)
+		}
+		if seen[protocol] {
+			return ProtocolMix{}, fmt.Errorf("invalid protocol mix %s, protocol %s is repeated", mix, protocol)
+		}
+		seen[protocol] = true
+	}
+	if m.HTTPWeight+m.GrpcWeight == 0 {
+		return ProtocolMix{}, fmt.Errorf("invalid protocol mix %s, at least one weight must be greater than 0", mix)
+	}
+	return m, nil
+}
+
+// pickHTTP returns true if the next request should be an HTTP one.
+func (m ProtocolMix) pickHTTP(rnd *rand.Rand) bool {
+	return rnd.Intn(m.HTTPWeight+m.GrpcWeight) < m.HTTPWeight
+}
+
+// mixedRequest is either an HTTP or a gRPC request.
+type mixedRequest struct {
+	http *http.Request
+	grpc *grpc.Request
+}
+
+// GetWarmupMixedRequests continuously picks HTTP or gRPC requests according to the protocol mix for a maximum of maxDurationSeconds or until ctx is done.
+func (w Warmup) GetWarmupMixedRequests(ctx context.Context, mix ProtocolMix, maxDurationSeconds int) chan mixedRequest {
+	requestsChan := make(chan mixedRequest)
+
+	go safe.Do(func() {
+		if len(w.HttpRequests) == 0 {
+			mix.HTTPWeight = 0
+		}
+		if len(w.GrpcRequests) == 0 {
+			mix.GrpcWeight = 0
+		}
+		if mix.HTTPWeight+mix.GrpcWeight == 0 {
+			close(requestsChan)
+			return
+		}
+		timeout := time.After(time.Duration(maxDurationSeconds) * time.Second)
+		rnd := w.newRand()
+		httpSelector := newWeightedRequestSelector(w.RequestOrder, httpWeights(w.HttpRequests), rnd)
+		grpcSelector := newWeightedRequestSelector(w.RequestOrder, grpcWeights(w.GrpcRequests), rnd)
+
+		for {
+			var request mixedRequest
+			var n int
+			if mix.pickHTTP(rnd) {
+				request.http = &w.HttpRequests[httpSelector.next()]
+				n = burst(request.http.Burst)
+			} else {
+				request.grpc = &w.GrpcRequests[grpcSelector.next()]
+				n = burst(request.grpc.Burst)
+			}
+			if !w.budget.take(n) {
+				close(requestsChan)
+				return
+			}
+			select {
+			case <-timeout:
+				close(requestsChan)
+				return
+			case <-ctx.Done():
+				close(requestsChan)
+				return
+			case requestsChan <- request:
+			}
+		}
+	})
+	return requestsChan
+}
+
+// MixedWarmupWorker sends HTTP and gRPC requests to the target.
+func (w Warmup) MixedWarmupWorker(wg *sync.WaitGroup, requests <-chan mixedRequest, headers []string, worker *placeholders.Worker, requestDelayMilliseconds int, requestsSentCounter *int64) {
+	w.Metrics.AddWorkers(1)
+	workerRateLimiter := ratelimit.New(w.PerWorkerRequestsPerSecond)
+	random := w.newRand()
+	for request := range requests {
+		if request.http != nil {
+			for i := 0; i < burst(request.http.Burst) && !w.stopped(); i++ {
+				w.waitForRateLimits(workerRateLimiter)
+				resp := w.sendHTTPRequest(*request.http, headers, worker, requestsSentCounter)
+				if !w.waitForRetryAfter(resp, request.http.Method, request.http.Path) {
+					break
+				}
+			}
+		} else {
+			for i := 0; i < burst(request.grpc.Burst) && !w.stopped(); i++ {
+				w.waitForRateLimits(workerRateLimiter)
+				w.sendGrpcRequest(*request.grpc, headers, worker, requestsSentCounter)
+			}
+		}
+		if !w.sleep(w.requestDelay(requestDelayMilliseconds, random)) {
+			break
+		}
+	}
+	w.Metrics.AddWorkers(-1)
+	wg.Done()
+}
+
+// logProtocolMix logs the share of the requests that were actually sent with each protocol.
+func logProtocolMix(summary *Summary, mix ProtocolMix) {
+	sent := make(map[string]int)
+	var total int
+	for _, e := range summary.Endpoints() {
+		sent[e.Protocol] += e.Sent
+		total += e.Sent
+	}
+	if total == 0 {
+		return
+	}
+	log.Printf("Protocol mix: http %.1f%% (%d), grpc %.1f%% (%d), configured http=%d,grpc=%d",
+		100*float64(sent["http"])/float64(total), sent["http"], 100*float64(sent["grpc"])/float64(total), sent["grpc"], mix.HTTPWeight, mix.GrpcWeight)
+}
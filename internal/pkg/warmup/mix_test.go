@@ -0,0 +1,74 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"context"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProtocolMix(t *testing.T) {
+	mix, err := ParseProtocolMix("http=70, GRPC=30")
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolMix{HTTPWeight: 70, GrpcWeight: 30}, mix)
+
+	mix, err = ParseProtocolMix("grpc=1")
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolMix{GrpcWeight: 1}, mix)
+
+	for _, invalid := range []string{"", "http", "http=-1,grpc=1", "http=0,grpc=0", "http=1,ftp=1", "http=1,http=2"} {
+		_, err := ParseProtocolMix(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestGetWarmupMixedRequests_FollowsTheMix(t *testing.T) {
+	w := Warmup{
+		HttpRequests: []http.Request{{Method: "GET", Path: "/ping"}},
+		GrpcRequests: []grpc.Request{{ServiceMethod: "health/ping"}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	requests := w.GetWarmupMixedRequests(ctx, ProtocolMix{HTTPWeight: 70, GrpcWeight: 30}, 10)
+
+	httpRequests := 0
+	for i := 0; i < 1000; i++ {
+		request := <-requests
+		if request.http != nil {
+			httpRequests++
+		} else {
+			require.NotNil(t, request.grpc)
+		}
+	}
+	assert.InDelta(t, 700, httpRequests, 100)
+}
+
+func TestGetWarmupMixedRequests_WithoutGrpcRequests(t *testing.T) {
+	w := Warmup{HttpRequests: []http.Request{{Method: "GET", Path: "/ping"}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	requests := w.GetWarmupMixedRequests(ctx, ProtocolMix{HTTPWeight: 1, GrpcWeight: 1}, 10)
+
+	for i := 0; i < 100; i++ {
+		require.NotNil(t, (<-requests).http)
+	}
+	cancel()
+	for range requests {
+	}
+}
@@ -0,0 +1,165 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+const (
+	// RandomOrder picks every request at random.
+	RandomOrder = "random"
+	// ShuffleOrder shuffles the requests and sends each of them once before shuffling them again.
+	ShuffleOrder = "shuffle"
+	// SequentialOrder sends the requests in the order they are configured, starting over after the last one,
+	// e.g. for stateful services that must receive a create before a read and a delete.
+	SequentialOrder = "sequential"
+)
+
+// ValidateRequestOrder returns an error if the request order is not supported.
+func ValidateRequestOrder(order string) error {
+	switch order {
+	case RandomOrder, ShuffleOrder, SequentialOrder:
+		return nil
+	default:
+		return fmt.Errorf("request order %s not supported, please use %s, %s or %s", order, RandomOrder, ShuffleOrder, SequentialOrder)
+	}
+}
+
+// requestSelector returns the index of the next request to be sent.
+type requestSelector interface {
+	next() int
+}
+
+// newRequestSelector returns a selector for n requests that follows the given order and draws from rnd.
+// It falls back to random order if the order is not set.
+func newRequestSelector(order string, n int, rnd *rand.Rand) requestSelector {
+	switch order {
+	case ShuffleOrder:
+		return &shuffleSelector{n: n, rnd: rnd}
+	case SequentialOrder:
+		return &sequentialSelector{n: n}
+	}
+	return randomSelector{n: n, rnd: rnd}
+}
+
+// newWeightedRequestSelector returns a selector that picks every request in proportion to its weight.
+// In shuffle order every request is selected as many times as its weight per cycle, in sequential order as many times in a row.
+// Weights lower than 1 count as 1, so that without weights the selector is the same as the one of newRequestSelector.
+func newWeightedRequestSelector(order string, weights []int, rnd *rand.Rand) requestSelector {
+	cumulative := make([]int, len(weights))
+	total := 0
+	for i, w := range weights {
+		total += weight(w)
+		cumulative[i] = total
+	}
+	if total == len(weights) {
+		return newRequestSelector(order, len(weights), rnd)
+	}
+	if order == ShuffleOrder || order == SequentialOrder {
+		indexes := make([]int, 0, total)
+		for i, w := range weights {
+			for j := 0; j < weight(w); j++ {
+				indexes = append(indexes, i)
+			}
+		}
+		if order == SequentialOrder {
+			return &weightedSequentialSelector{indexes: indexes, sequence: sequentialSelector{n: total}}
+		}
+		return &weightedShuffleSelector{indexes: indexes, shuffle: shuffleSelector{n: total, rnd: rnd}}
+	}
+	return weightedRandomSelector{cumulative: cumulative, rnd: rnd}
+}
+
+// weight returns the weight of a request, 1 if it is not set.
+func weight(w int) int {
+	if w < 1 {
+		return 1
+	}
+	return w
+}
+
+type randomSelector struct {
+	n   int
+	rnd *rand.Rand
+}
+
+func (s randomSelector) next() int {
+	return s.rnd.Intn(s.n)
+}
+
+// weightedRandomSelector picks every request at random in proportion to its weight.
+type weightedRandomSelector struct {
+	// cumulative sums of the weights of the requests
+	cumulative []int
+	rnd        *rand.Rand
+}
+
+func (s weightedRandomSelector) next() int {
+	n := s.rnd.Intn(s.cumulative[len(s.cumulative)-1])
+	// the first request whose cumulative weight is greater than n
+	return sort.SearchInts(s.cumulative, n+1)
+}
+
+// weightedShuffleSelector shuffles the indexes of the requests, each repeated as many times as its weight.
+type weightedShuffleSelector struct {
+	indexes []int
+	shuffle shuffleSelector
+}
+
+func (s *weightedShuffleSelector) next() int {
+	return s.indexes[s.shuffle.next()]
+}
+
+// shuffleSelector guarantees that every request is selected once per cycle.
+// A new random order is generated (Fisher-Yates) at the start of every cycle.
+type shuffleSelector struct {
+	n     int
+	rnd   *rand.Rand
+	cycle []int
+}
+
+func (s *shuffleSelector) next() int {
+	if len(s.cycle) == 0 {
+		s.cycle = s.rnd.Perm(s.n)
+	}
+	index := s.cycle[0]
+	s.cycle = s.cycle[1:]
+	return index
+}
+
+// weightedSequentialSelector selects the indexes of the requests in order, each repeated as many times as its weight.
+type weightedSequentialSelector struct {
+	indexes  []int
+	sequence sequentialSelector
+}
+
+func (s *weightedSequentialSelector) next() int {
+	return s.indexes[s.sequence.next()]
+}
+
+// sequentialSelector selects the requests in order and starts over after the last one.
+type sequentialSelector struct {
+	n     int
+	index int
+}
+
+func (s *sequentialSelector) next() int {
+	index := s.index
+	s.index = (s.index + 1) % s.n
+	return index
+}
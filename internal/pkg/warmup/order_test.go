@@ -0,0 +1,119 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShuffleSelector_FullCoveragePerCycle(t *testing.T) {
+	selector := newRequestSelector(ShuffleOrder, 5, testRand())
+
+	for cycle := 0; cycle < 3; cycle++ {
+		seen := make(map[int]int)
+		for i := 0; i < 5; i++ {
+			seen[selector.next()]++
+		}
+		require.Equal(t, 5, len(seen))
+		for index, count := range seen {
+			assert.Equal(t, 1, count, "request %d was not sent exactly once in cycle %d", index, cycle)
+		}
+	}
+}
+
+func TestSequentialSelector_InOrder(t *testing.T) {
+	selector := newRequestSelector(SequentialOrder, 3, testRand())
+
+	var indexes []int
+	for i := 0; i < 7; i++ {
+		indexes = append(indexes, selector.next())
+	}
+	assert.Equal(t, []int{0, 1, 2, 0, 1, 2, 0}, indexes)
+}
+
+func TestWeightedSequentialSelector_WeightsInARow(t *testing.T) {
+	selector := newWeightedRequestSelector(SequentialOrder, []int{2, 0, 3}, testRand())
+
+	var indexes []int
+	for i := 0; i < 8; i++ {
+		indexes = append(indexes, selector.next())
+	}
+	assert.Equal(t, []int{0, 0, 1, 2, 2, 2, 0, 0}, indexes)
+}
+
+func TestRandomSelector_InRange(t *testing.T) {
+	selector := newRequestSelector(RandomOrder, 3, testRand())
+
+	for i := 0; i < 100; i++ {
+		index := selector.next()
+		assert.True(t, index >= 0 && index < 3)
+	}
+}
+
+func TestWeightedRandomSelector_ProportionalToWeights(t *testing.T) {
+	selector := newWeightedRequestSelector(RandomOrder, []int{1, 0, 8}, testRand())
+
+	seen := make(map[int]int)
+	for i := 0; i < 10000; i++ {
+		seen[selector.next()]++
+	}
+	require.Equal(t, 3, len(seen))
+	assert.InDelta(t, 1000, seen[0], 250)
+	assert.InDelta(t, 1000, seen[1], 250)
+	assert.InDelta(t, 8000, seen[2], 400)
+}
+
+func TestWeightedShuffleSelector_WeightsPerCycle(t *testing.T) {
+	selector := newWeightedRequestSelector(ShuffleOrder, []int{2, 1, 3}, testRand())
+
+	for cycle := 0; cycle < 3; cycle++ {
+		seen := make(map[int]int)
+		for i := 0; i < 6; i++ {
+			seen[selector.next()]++
+		}
+		assert.Equal(t, map[int]int{0: 2, 1: 1, 2: 3}, seen)
+	}
+}
+
+func TestWeightedRequestSelector_UniformWithoutWeights(t *testing.T) {
+	rnd := testRand()
+	assert.Equal(t, randomSelector{n: 3, rnd: rnd}, newWeightedRequestSelector(RandomOrder, []int{0, 1, 1}, rnd))
+	assert.Equal(t, &shuffleSelector{n: 2, rnd: rnd}, newWeightedRequestSelector(ShuffleOrder, []int{1, 1}, rnd))
+}
+
+func TestRequestSelector_DeterministicWithSeededSource(t *testing.T) {
+	for _, order := range []string{RandomOrder, ShuffleOrder} {
+		first := newWeightedRequestSelector(order, []int{1, 2, 3}, testRand())
+		second := newWeightedRequestSelector(order, []int{1, 2, 3}, testRand())
+		for i := 0; i < 20; i++ {
+			assert.Equal(t, first.next(), second.next())
+		}
+	}
+}
+
+func TestValidateRequestOrder(t *testing.T) {
+	assert.NoError(t, ValidateRequestOrder(RandomOrder))
+	assert.NoError(t, ValidateRequestOrder(ShuffleOrder))
+	assert.NoError(t, ValidateRequestOrder(SequentialOrder))
+	assert.Error(t, ValidateRequestOrder("sorted"))
+}
+
+func testRand() *rand.Rand {
+	return rand.New(rand.NewSource(1))
+}
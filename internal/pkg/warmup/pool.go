@@ -0,0 +1,187 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mittens/internal/pkg/safe"
+	"sync"
+)
+
+// workerPool runs a number of workers that can be changed while they run. It is safe for concurrent use.
+type workerPool struct {
+	name string
+	ctx  context.Context
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+	// cancel functions of the contexts of the running workers, cancelling one makes its worker exit after its current request
+	cancels []context.CancelFunc
+	// true once the pool was waited for, no workers are started after that
+	closed bool
+	work   func(ctx context.Context, wg *sync.WaitGroup)
+}
+
+// newWorkerPool returns a pool whose workers run work with a context derived from ctx.
+func newWorkerPool(ctx context.Context, name string, work func(ctx context.Context, wg *sync.WaitGroup)) *workerPool {
+	return &workerPool{name: name, ctx: ctx, work: work}
+}
+
+// grow starts workers until at least n of them are running.
+func (p *workerPool) grow(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for !p.closed && len(p.cancels) < n {
+		p.start()
+	}
+}
+
+// resize starts or stops workers until n of them are running.
+func (p *workerPool) resize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for !p.closed && len(p.cancels) < n {
+		p.start()
+	}
+	for len(p.cancels) > n {
+		last := len(p.cancels) - 1
+		p.cancels[last]()
+		p.cancels = p.cancels[:last]
+	}
+}
+
+func (p *workerPool) start() {
+	log.Printf("Spawning new go routine for %s requests", p.name)
+	ctx, cancel := context.WithCancel(p.ctx)
+	p.cancels = append(p.cancels, cancel)
+	p.wg.Add(1)
+	go safe.Do(func() {
+		p.work(ctx, &p.wg)
+	})
+}
+
+// wait blocks until all the workers exited and closes the pool.
+func (p *workerPool) wait() {
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, cancel := range p.cancels {
+		cancel()
+	}
+}
+
+// ConcurrencyControl changes the concurrency of a warmup while it runs. It is safe for concurrent use.
+type ConcurrencyControl struct {
+	mu          sync.Mutex
+	concurrency int
+	// true once the concurrency was set via SetConcurrency, it then overrides the configured one in later warmup cycles
+	set bool
+	// pools of the warmup currently running, if any
+	pools []*workerPool
+}
+
+// NewConcurrencyControl returns a control for a warmup configured with the given concurrency.
+func NewConcurrencyControl(concurrency int) *ConcurrencyControl {
+	return &ConcurrencyControl{concurrency: concurrency}
+}
+
+// Concurrency returns the number of workers per protocol of the running warmup, or of the last one.
+func (c *ConcurrencyControl) Concurrency() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.concurrency
+}
+
+// SetConcurrency changes the number of workers per protocol of the running warmup, and of the later warmup cycles.
+func (c *ConcurrencyControl) SetConcurrency(n int) error {
+	if n < 1 {
+		return fmt.Errorf("concurrency must be greater than 0")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.concurrency = n
+	c.set = true
+	for _, pool := range c.pools {
+		pool.resize(n)
+	}
+	log.Printf("Concurrency set to %d", n)
+	return nil
+}
+
+// attach makes SetConcurrency resize the pools of a running warmup.
+// It returns the concurrency the pools should start with, which is the one set via SetConcurrency, if any.
+func (c *ConcurrencyControl) attach(concurrency int, pools ...*workerPool) int {
+	if c == nil {
+		return concurrency
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.set {
+		concurrency = c.concurrency
+	}
+	c.concurrency = concurrency
+	c.pools = pools
+	return concurrency
+}
+
+// current returns the concurrency of the running warmup, or configured if there is no control.
+func (c *ConcurrencyControl) current(configured int) int {
+	if c == nil {
+		return configured
+	}
+	return c.Concurrency()
+}
+
+// grow starts workers of the pool until n of them are running, but never more than the current concurrency
+// so that the ramp-up does not undo a concurrency lowered via SetConcurrency.
+func (c *ConcurrencyControl) grow(pool *workerPool, n int) {
+	if c == nil {
+		pool.grow(n)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n > c.concurrency {
+		n = c.concurrency
+	}
+	pool.grow(n)
+}
+
+// shrink stops workers of the pool until at most n of them are running, it never starts workers
+// so that the ramp-down does not undo a concurrency lowered via SetConcurrency.
+func (c *ConcurrencyControl) shrink(pool *workerPool, n int) {
+	if c == nil {
+		pool.resize(n)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n > c.concurrency {
+		n = c.concurrency
+	}
+	pool.resize(n)
+}
+
+// detach stops resizing the pools once the warmup is over.
+func (c *ConcurrencyControl) detach() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pools = nil
+}
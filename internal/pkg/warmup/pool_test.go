@@ -0,0 +1,129 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startBlockingPool returns a pool whose workers run until they are stopped, and the number of running workers.
+func startBlockingPool(ctx context.Context) (*workerPool, *int32) {
+	var running int32
+	pool := newWorkerPool(ctx, "test", func(ctx context.Context, wg *sync.WaitGroup) {
+		defer wg.Done()
+		atomic.AddInt32(&running, 1)
+		<-ctx.Done()
+		atomic.AddInt32(&running, -1)
+	})
+	return pool, &running
+}
+
+func TestWorkerPool_Resize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool, running := startBlockingPool(ctx)
+
+	pool.grow(3)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(running) == 3 }, time.Second, time.Millisecond)
+
+	pool.resize(1)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(running) == 1 }, time.Second, time.Millisecond)
+
+	pool.grow(1)
+	pool.resize(2)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(running) == 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	pool.wait()
+	assert.Equal(t, int32(0), atomic.LoadInt32(running))
+
+	// no workers are started once the pool was waited for
+	pool.resize(2)
+	assert.Never(t, func() bool { return atomic.LoadInt32(running) != 0 }, 50*time.Millisecond, time.Millisecond)
+}
+
+func TestConcurrencyControl(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool, running := startBlockingPool(ctx)
+	control := NewConcurrencyControl(2)
+
+	assert.Equal(t, 2, control.attach(2, pool))
+	pool.grow(2)
+	assert.Error(t, control.SetConcurrency(0))
+
+	assert.NoError(t, control.SetConcurrency(4))
+	assert.Equal(t, 4, control.Concurrency())
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(running) == 4 }, time.Second, time.Millisecond)
+
+	// the concurrency set while a warmup ran is used by the next one
+	control.detach()
+	assert.Equal(t, 4, control.attach(2))
+}
+
+func TestRampDown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool, running := startBlockingPool(ctx)
+	pool.grow(3)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(running) == 3 }, time.Second, time.Millisecond)
+
+	start := time.Now()
+	rampDown(ctx, []*workerPool{pool}, nil, 3, start, 1)
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(running) == 1 }, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 600*time.Millisecond)
+}
+
+func TestRampDown_StartsFromTheCurrentConcurrency(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool, running := startBlockingPool(ctx)
+	control := NewConcurrencyControl(3)
+	control.attach(3, pool)
+	pool.grow(3)
+	assert.NoError(t, control.SetConcurrency(2))
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(running) == 2 }, time.Second, time.Millisecond)
+
+	start := time.Now()
+	rampDown(ctx, []*workerPool{pool}, control, 3, start, 1)
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(running) == 1 }, time.Second, time.Millisecond)
+	// with 2 workers left the single step happens after half of the ramp-down
+	assert.Less(t, time.Since(start), 800*time.Millisecond)
+}
+
+func TestWaitForPools_GivesUpAfterDrain(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stuck := make(chan struct{})
+	defer close(stuck)
+	// a worker whose request is still in flight long after the warmup ended
+	pool := newWorkerPool(context.Background(), "test", func(_ context.Context, wg *sync.WaitGroup) {
+		defer wg.Done()
+		<-stuck
+	})
+	pool.grow(1)
+	cancel()
+
+	start := time.Now()
+	(&Warmup{DrainSeconds: 1}).waitForPools(ctx, []*workerPool{pool})
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
@@ -0,0 +1,80 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// LinearRampUp starts the workers at regular intervals.
+	LinearRampUp = "linear"
+	// ExponentialRampUp starts the workers slowly at first and then faster and faster, like traffic that picks up.
+	ExponentialRampUp = "exponential"
+)
+
+// DefaultRampUpShape is the shape of the exponential ramp-up if none is set.
+const DefaultRampUpShape = 3.0
+
+// ValidateRampUp returns an error if the ramp-up curve is not supported or its shape is not positive.
+func ValidateRampUp(curve string, shape float64) error {
+	switch curve {
+	case LinearRampUp:
+		return nil
+	case ExponentialRampUp:
+		if shape <= 0 {
+			return fmt.Errorf("ramp-up shape must be greater than 0, got %v", shape)
+		}
+		return nil
+	default:
+		return fmt.Errorf("ramp-up curve %s not supported, please use %s or %s", curve, LinearRampUp, ExponentialRampUp)
+	}
+}
+
+// rampUpDelays returns, for every worker, the time to wait before starting it once the previous worker started,
+// so that the concurrency grows over rampUpSeconds following the curve. The first worker starts straight away.
+// The linear ramp-up waits the same whole number of seconds before every worker. The exponential one starts worker i
+// of n after rampUpSeconds * ln(1 + (e^shape - 1) * (i-1) / n) / shape: the greater the shape, the slower the start.
+// It falls back to the linear ramp-up if the curve is not set and returns no delays if the concurrency is not greater than 0.
+// A negative rampUpSeconds starts all the workers at once.
+func rampUpDelays(curve string, shape float64, rampUpSeconds int, concurrency int) []time.Duration {
+	if concurrency < 1 {
+		return nil
+	}
+	if rampUpSeconds < 0 {
+		rampUpSeconds = 0
+	}
+	delays := make([]time.Duration, concurrency)
+	if curve != ExponentialRampUp {
+		for i := 1; i < concurrency; i++ {
+			delays[i] = time.Duration(rampUpSeconds/concurrency) * time.Second
+		}
+		return delays
+	}
+
+	if shape <= 0 {
+		shape = DefaultRampUpShape
+	}
+	total := time.Duration(rampUpSeconds) * time.Second
+	start := func(i int) time.Duration {
+		return time.Duration(float64(total) * math.Log(1+math.Expm1(shape)*float64(i)/float64(concurrency)) / shape)
+	}
+	for i := 1; i < concurrency; i++ {
+		delays[i] = start(i) - start(i-1)
+	}
+	return delays
+}
@@ -0,0 +1,67 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRampUp(t *testing.T) {
+	assert.NoError(t, ValidateRampUp(LinearRampUp, 0))
+	assert.NoError(t, ValidateRampUp(ExponentialRampUp, 2))
+	assert.Error(t, ValidateRampUp(ExponentialRampUp, 0))
+	assert.Error(t, ValidateRampUp("step", 1))
+}
+
+func TestRampUpDelays_Linear(t *testing.T) {
+	assert.Equal(t, []time.Duration{0, 2 * time.Second, 2 * time.Second, 2 * time.Second}, rampUpDelays(LinearRampUp, 0, 10, 4))
+	// the interval is a whole number of seconds, as it has always been
+	assert.Equal(t, []time.Duration{0, 0, 0, 0}, rampUpDelays("", 0, 3, 4))
+}
+
+func TestRampUpDelays_NoWorkers(t *testing.T) {
+	for _, concurrency := range []int{0, -1} {
+		assert.Empty(t, rampUpDelays(LinearRampUp, 0, 10, concurrency))
+		assert.Empty(t, rampUpDelays(ExponentialRampUp, 3, 10, concurrency))
+	}
+}
+
+func TestRampUpDelays_NegativeRampUp(t *testing.T) {
+	assert.Equal(t, []time.Duration{0, 0, 0}, rampUpDelays(LinearRampUp, 0, -10, 3))
+	assert.Equal(t, []time.Duration{0, 0, 0}, rampUpDelays(ExponentialRampUp, 3, -10, 3))
+}
+
+func TestRampUpDelays_Exponential(t *testing.T) {
+	delays := rampUpDelays(ExponentialRampUp, 3, 10, 5)
+
+	require.Len(t, delays, 5)
+	assert.Equal(t, time.Duration(0), delays[0])
+	for i := 2; i < len(delays); i++ {
+		assert.Less(t, delays[i], delays[i-1], "the workers must be started faster and faster")
+	}
+	var total time.Duration
+	for _, delay := range delays {
+		total += delay
+	}
+	// the last worker starts later than with the linear ramp-up, after 8s, but before the end of the ramp-up
+	assert.Greater(t, total, 8*time.Second)
+	assert.Less(t, total, 10*time.Second)
+
+	assert.Equal(t, rampUpDelays(ExponentialRampUp, DefaultRampUpShape, 10, 5), rampUpDelays(ExponentialRampUp, 0, 10, 5))
+}
@@ -0,0 +1,152 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"os"
+	"strings"
+)
+
+// httpRequestLine is an HTTP request written as a JSON object in a requests file.
+type httpRequestLine struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body"`
+	// BodyFile is the path of a file whose content is sent as the body, read when the request is first sent.
+	BodyFile string `json:"bodyFile"`
+	// Query holds query parameters appended to the path, URL encoded, when the request is sent.
+	Query map[string]string `json:"query"`
+}
+
+// grpcRequestLine is a gRPC request written as a JSON object in a requests file.
+type grpcRequestLine struct {
+	ServiceMethod string          `json:"serviceMethod"`
+	Message       json.RawMessage `json:"message"`
+}
+
+// LoadHTTPRequestsFromFile reads HTTP requests from a file with a request per line, either in the format of the
+// http-requests flag, e.g. `[burst=3]post:/ping:{"key":"value"}`, or as a JSON object, e.g.
+// `{"method": "post", "path": "/ping", "body": "{\"key\":\"value\"}"}`. JSON objects can set bodyFile instead of body
+// to send the content of a file, read once when the request is first sent, and query to add URL encoded query parameters
+// to the path. Blank lines and lines starting with # are ignored.
+func LoadHTTPRequestsFromFile(path string) ([]http.Request, error) {
+	var requests []http.Request
+	err := readRequestsFile(path, func(text string) error {
+		var bodyFile string
+		var query map[string]string
+		if strings.HasPrefix(text, "{") {
+			var line httpRequestLine
+			if err := decodeRequestLine(text, &line); err != nil {
+				return err
+			}
+			bodyFile = line.BodyFile
+			query = line.Query
+			text = line.Method + ":" + line.Path
+			if line.Body != "" {
+				text += ":" + line.Body
+			}
+		}
+		request, err := http.ToHTTPRequest(text)
+		if err != nil {
+			return err
+		}
+		if request.Body == nil {
+			request.BodyFile = bodyFile
+		}
+		request.Query = query
+		requests = append(requests, request)
+		return nil
+	})
+	return requests, err
+}
+
+// LoadGrpcRequestsFromFile reads gRPC requests from a file with a request per line, either in the format of the
+// grpc-requests flag, e.g. `[burst=3]health/ping:{"key":"value"}`, or as a JSON object, e.g.
+// `{"serviceMethod": "health/ping", "message": {"key": "value"}}`. Blank lines and lines starting with # are ignored.
+func LoadGrpcRequestsFromFile(path string) ([]grpc.Request, error) {
+	var requests []grpc.Request
+	err := readRequestsFile(path, func(text string) error {
+		if strings.HasPrefix(text, "{") {
+			var line grpcRequestLine
+			if err := decodeRequestLine(text, &line); err != nil {
+				return err
+			}
+			text = line.ServiceMethod
+			if message := grpcMessage(line.Message); message != "" {
+				text += ":" + message
+			}
+		}
+		request, err := grpc.ToGrpcRequest(text)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, request)
+		return nil
+	})
+	return requests, err
+}
+
+// readRequestsFile calls parse with every line of a requests file that is neither blank nor a comment.
+// Errors are reported with the number of the line that caused them.
+func readRequestsFile(path string, parse func(text string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open requests file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 10<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if err := parse(text); err != nil {
+			return fmt.Errorf("invalid requests file %s, line %d: %v", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("cannot read requests file %s: %v", path, err)
+	}
+	return nil
+}
+
+// decodeRequestLine decodes a request written as a JSON object, rejecting unknown fields.
+func decodeRequestLine(text string, line interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(line); err != nil {
+		return fmt.Errorf("invalid JSON request: %v", err)
+	}
+	return nil
+}
+
+// grpcMessage returns the message of a gRPC request written as a JSON object. The message is either a JSON object
+// or a string, e.g. a message in the text format. A missing or null message is empty.
+func grpcMessage(raw json.RawMessage) string {
+	var message string
+	if len(raw) == 0 || json.Unmarshal(raw, &message) == nil {
+		return message
+	}
+	return string(raw)
+}
@@ -0,0 +1,93 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRequestsFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "requests")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadHTTPRequestsFromFile(t *testing.T) {
+	path := writeRequestsFile(t, `
+# search
+get:/ping
+[burst=3]post:/search:{"q":"shoes"}
+{"method": "put", "path": "/orders/1", "body": "{\"qty\":2}"}
+{"method": "delete", "path": "/orders/1"}
+{"method": "post", "path": "/orders", "bodyFile": "/bodies/order.json"}
+{"method": "get", "path": "/search", "query": {"q": "red shoes"}}
+`)
+
+	requests, err := LoadHTTPRequestsFromFile(path)
+	require.NoError(t, err)
+
+	require.Len(t, requests, 6)
+	assert.Equal(t, "GET", requests[0].Method)
+	assert.Equal(t, "/ping", requests[0].Path)
+	assert.Equal(t, 3, requests[1].Burst)
+	assert.Equal(t, `{"q":"shoes"}`, *requests[1].Body)
+	assert.Equal(t, "PUT", requests[2].Method)
+	assert.Equal(t, `{"qty":2}`, *requests[2].Body)
+	assert.Equal(t, "DELETE", requests[3].Method)
+	assert.Nil(t, requests[3].Body)
+	assert.Equal(t, "", requests[3].BodyFile)
+	assert.Nil(t, requests[4].Body)
+	assert.Equal(t, "/bodies/order.json", requests[4].BodyFile)
+	assert.Nil(t, requests[4].Query)
+	assert.Equal(t, map[string]string{"q": "red shoes"}, requests[5].Query)
+}
+
+func TestLoadGrpcRequestsFromFile(t *testing.T) {
+	path := writeRequestsFile(t, `
+health/ping
+[burst=2]health/ping:{"key":"value"}
+{"serviceMethod": "orders.Orders/Get", "message": {"id": 1}}
+{"serviceMethod": "orders.Orders/List", "message": "limit: 10"}
+{"serviceMethod": "orders.Orders/Count"}
+`)
+
+	requests, err := LoadGrpcRequestsFromFile(path)
+	require.NoError(t, err)
+
+	require.Len(t, requests, 5)
+	assert.Equal(t, "health/ping", requests[0].ServiceMethod)
+	assert.Equal(t, 2, requests[1].Burst)
+	assert.Equal(t, `{"key":"value"}`, requests[1].Message)
+	assert.Equal(t, "orders.Orders/Get", requests[2].ServiceMethod)
+	assert.Equal(t, `{"id": 1}`, requests[2].Message)
+	assert.Equal(t, "limit: 10", requests[3].Message)
+	assert.Equal(t, "", requests[4].Message)
+}
+
+func TestLoadRequestsFromFile_Invalid(t *testing.T) {
+	_, err := LoadHTTPRequestsFromFile(writeRequestsFile(t, "get:/ping\n\nfe tch:/ping\n"))
+	assert.ErrorContains(t, err, "line 3")
+	_, err = LoadHTTPRequestsFromFile(writeRequestsFile(t, "get:/ping\n{\"method\": \"get\", \"url\": \"/ping\"}\n"))
+	assert.ErrorContains(t, err, "line 2")
+	_, err = LoadGrpcRequestsFromFile(writeRequestsFile(t, "# comment\n{\"serviceMethod\": \"health/ping\"\n"))
+	assert.ErrorContains(t, err, "line 2")
+	_, err = LoadGrpcRequestsFromFile(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorContains(t, err, "cannot open requests file")
+}
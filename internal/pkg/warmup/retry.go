@@ -0,0 +1,59 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"log"
+	"mittens/internal/pkg/marker"
+	"mittens/internal/pkg/response"
+	"time"
+)
+
+// withRetries sends a request with send and sends it again, up to Retries times, as long as retryable returns true for its response.
+// The delay before every retry doubles, starting from RetryBackoff. Retries stop as soon as the warmup is over.
+// It returns the last response.
+func (w Warmup) withRetries(protocol string, endpoint string, send func() response.Response, retryable func(response.Response) bool) response.Response {
+	resp := send()
+	resp.Attempts = 1
+	for attempt := 0; attempt < w.Retries && retryable(resp); attempt++ {
+		delay := w.RetryBackoff << attempt
+		log.Printf("%s Request for %s failed, retrying in %v (%d of %d)", marker.Warning(), endpoint, delay, attempt+1, w.Retries)
+		select {
+		case <-w.done:
+			return resp
+		case <-time.After(delay):
+		}
+		select {
+		case <-w.done:
+			// the warmup ended during the backoff
+			return resp
+		default:
+		}
+		w.summary.RecordRetry(protocol, endpoint)
+		resp = send()
+		resp.Attempts = attempt + 2
+	}
+	return resp
+}
+
+// retryableHTTP returns true for the HTTP responses worth retrying: transport errors and 5xx status codes.
+func retryableHTTP(resp response.Response) bool {
+	return resp.Err != nil || resp.StatusCode/100 == 5
+}
+
+// retryableGrpc returns true for the gRPC responses worth retrying: transport errors.
+func retryableGrpc(resp response.Response) bool {
+	return resp.Err != nil
+}
@@ -0,0 +1,111 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"bytes"
+	"encoding/json"
+	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/response"
+	"mittens/internal/pkg/resultlog"
+	nethttp "net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendHTTPRequest_RetriesUntilSuccess(t *testing.T) {
+	var calls int32
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			rw.WriteHeader(nethttp.StatusServiceUnavailable)
+		}
+	})
+	w.Retries = 3
+	w.RetryBackoff = time.Millisecond
+
+	resp := w.sendHTTPRequest(http.Request{Method: "GET", Path: "/booting"}, []string{}, nil, requestsSent)
+
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, int64(1), *requestsSent)
+	endpoints := w.summary.Endpoints()
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, 1, endpoints[0].Sent)
+	assert.Equal(t, 1, endpoints[0].Successes)
+	assert.Equal(t, 2, endpoints[0].Retried)
+}
+
+func TestSendHTTPRequest_LogsTheResultAsJSON(t *testing.T) {
+	var calls int32
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		if atomic.AddInt32(&calls, 1) < 2 {
+			rw.WriteHeader(nethttp.StatusServiceUnavailable)
+		}
+	})
+	var out bytes.Buffer
+	w.Retries = 1
+	w.RetryBackoff = time.Millisecond
+	w.ResultLogger = resultlog.NewJSONLogger(&out)
+
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/booting"}, []string{}, nil, requestsSent)
+
+	var result resultlog.Result
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, "http", result.Protocol)
+	assert.Equal(t, "GET", result.Method)
+	assert.Equal(t, "/booting", result.Path)
+	assert.Equal(t, "200", result.Status)
+	assert.Equal(t, 2, result.Attempt)
+	assert.Equal(t, resultlog.Success, result.Outcome)
+	assert.Empty(t, result.Error)
+}
+
+func TestWithRetries_GivesUpAfterRetries(t *testing.T) {
+	w := Warmup{summary: NewSummary(), Retries: 2, RetryBackoff: time.Millisecond}
+	sent := 0
+	resp := w.withRetries("http", "GET /", func() response.Response {
+		sent++
+		return response.Response{StatusCode: 500}
+	}, retryableHTTP)
+
+	assert.Equal(t, 500, resp.StatusCode)
+	assert.Equal(t, 3, sent)
+}
+
+func TestWithRetries_StopsOnceTheWarmupIsOver(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+	w := Warmup{summary: NewSummary(), Retries: 5, RetryBackoff: time.Hour, done: done}
+	sent := 0
+	w.withRetries("grpc", "Service/Method", func() response.Response {
+		sent++
+		return response.Response{Err: assert.AnError}
+	}, retryableGrpc)
+
+	assert.Equal(t, 1, sent)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, retryableHTTP(response.Response{Err: assert.AnError}))
+	assert.True(t, retryableHTTP(response.Response{StatusCode: 503}))
+	assert.False(t, retryableHTTP(response.Response{StatusCode: 404}))
+	assert.False(t, retryableHTTP(response.Response{StatusCode: 200}))
+	assert.True(t, retryableGrpc(response.Response{Err: assert.AnError}))
+	assert.False(t, retryableGrpc(response.Response{}))
+}
@@ -0,0 +1,61 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"log"
+	"mittens/internal/pkg/marker"
+	"mittens/internal/pkg/response"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryAfter returns how long the target asked to wait before the next request, through the Retry-After header of
+// a 429 or 503 response. The header is either a number of seconds or an HTTP date.
+// It returns false if the response does not ask to wait.
+func retryAfter(resp response.Response, now time.Time) (time.Duration, bool) {
+	if resp.Err != nil || (resp.StatusCode != nethttp.StatusTooManyRequests && resp.StatusCode != nethttp.StatusServiceUnavailable) {
+		return 0, false
+	}
+	value := strings.TrimSpace(resp.Header("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := nethttp.ParseTime(value); err == nil {
+		delay = date.Sub(now)
+	} else {
+		return 0, false
+	}
+	if delay <= 0 {
+		return 0, false
+	}
+	return delay, true
+}
+
+// waitForRetryAfter pauses the worker for as long as the response asked with its Retry-After header.
+// It returns false if the warmup ended during the pause.
+func (w Warmup) waitForRetryAfter(resp response.Response, method string, path string) bool {
+	delay, ok := retryAfter(resp, time.Now())
+	if !ok {
+		return true
+	}
+	log.Printf("%s %s %s returned %d, pausing the worker for %v as requested by Retry-After", marker.Warning(), method, path, resp.StatusCode, delay)
+	return w.sleep(delay)
+}
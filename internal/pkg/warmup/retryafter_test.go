@@ -0,0 +1,77 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"context"
+	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/response"
+	nethttp "net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	withHeader := func(statusCode int, value string) response.Response {
+		return response.Response{StatusCode: statusCode, Headers: map[string][]string{"Retry-After": {value}}}
+	}
+
+	delay, ok := retryAfter(withHeader(429, "3"), now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, delay)
+
+	delay, ok = retryAfter(withHeader(503, "Tue, 01 Mar 2022 10:00:05 GMT"), now)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, delay)
+
+	// dates in the past, invalid values and other status codes do not pause the worker
+	_, ok = retryAfter(withHeader(503, "Tue, 01 Mar 2022 09:59:00 GMT"), now)
+	assert.False(t, ok)
+	_, ok = retryAfter(withHeader(429, "soon"), now)
+	assert.False(t, ok)
+	_, ok = retryAfter(withHeader(500, "3"), now)
+	assert.False(t, ok)
+	_, ok = retryAfter(response.Response{StatusCode: 429}, now)
+	assert.False(t, ok)
+}
+
+func TestRun_PausesWorkerOnRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	var received []time.Time
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, time.Now())
+		if len(received) == 1 {
+			rw.Header().Set("Retry-After", "1")
+			rw.WriteHeader(nethttp.StatusTooManyRequests)
+		}
+	})
+	w.Concurrency = 1
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/"}}
+	w.MaxRequests = 2
+
+	w.Run(context.Background(), true, false, 60, requestsSent)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2)
+	assert.GreaterOrEqual(t, received[1].Sub(received[0]), time.Second)
+}
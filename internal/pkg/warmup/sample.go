@@ -0,0 +1,36 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// SampleIndexes returns the indexes, in increasing order, of a random sample of n requests of the given rate, between 0 and 1.
+// At least one request is sampled if n is greater than 0.
+func SampleIndexes(n int, rate float64, rnd *rand.Rand) []int {
+	k := int(math.Ceil(rate * float64(n)))
+	if k > n {
+		k = n
+	}
+	if k < 1 && n > 0 {
+		k = 1
+	}
+	indexes := rnd.Perm(n)[:k]
+	sort.Ints(indexes)
+	return indexes
+}
@@ -0,0 +1,41 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleIndexes(t *testing.T) {
+	indexes := SampleIndexes(1000, 0.1, rand.New(rand.NewSource(42)))
+	assert.Len(t, indexes, 100)
+	assert.IsIncreasing(t, indexes)
+	for _, index := range indexes {
+		assert.True(t, index >= 0 && index < 1000)
+	}
+
+	// the same seed selects the same sample
+	assert.Equal(t, indexes, SampleIndexes(1000, 0.1, rand.New(rand.NewSource(42))))
+}
+
+func TestSampleIndexes_Bounds(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	assert.Len(t, SampleIndexes(3, 0.01, rnd), 1)
+	assert.Len(t, SampleIndexes(3, 1, rnd), 3)
+	assert.Empty(t, SampleIndexes(0, 0.5, rnd))
+}
@@ -0,0 +1,63 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+// Schedule splits the time left for a warmup into its phases, in seconds.
+type Schedule struct {
+	RampUpSeconds   int
+	SteadySeconds   int
+	RampDownSeconds int
+	// DrainSeconds is the time reserved at the end for the requests still in flight to complete.
+	DrainSeconds int
+}
+
+// WarmupSeconds returns the time during which requests are sent: ramp-up, steady phase and ramp-down.
+func (s Schedule) WarmupSeconds() int {
+	return s.RampUpSeconds + s.SteadySeconds + s.RampDownSeconds
+}
+
+// NewSchedule fits the ramp-up and ramp-down in totalSeconds and gives the rest to the steady phase, after reserving
+// a tenth of the total, between 1 and 10 seconds, for the drain. If the ramps alone do not fit they are shortened
+// proportionally, the steady phase is skipped and false is returned. Negative ramps are treated as 0.
+func NewSchedule(totalSeconds int, rampUpSeconds int, rampDownSeconds int) (Schedule, bool) {
+	if rampUpSeconds < 0 {
+		rampUpSeconds = 0
+	}
+	if rampDownSeconds < 0 {
+		rampDownSeconds = 0
+	}
+	if totalSeconds <= 0 {
+		return Schedule{}, rampUpSeconds+rampDownSeconds == 0
+	}
+	drain := totalSeconds / 10
+	if drain < 1 {
+		drain = 1
+	}
+	if drain > 10 {
+		drain = 10
+	}
+	warmupSeconds := totalSeconds - drain
+
+	if ramps := rampUpSeconds + rampDownSeconds; ramps > warmupSeconds {
+		rampUp := rampUpSeconds * warmupSeconds / ramps
+		return Schedule{RampUpSeconds: rampUp, RampDownSeconds: warmupSeconds - rampUp, DrainSeconds: drain}, false
+	}
+	return Schedule{
+		RampUpSeconds:   rampUpSeconds,
+		SteadySeconds:   warmupSeconds - rampUpSeconds - rampDownSeconds,
+		RampDownSeconds: rampDownSeconds,
+		DrainSeconds:    drain,
+	}, true
+}
@@ -0,0 +1,62 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSchedule(t *testing.T) {
+	schedule, ok := NewSchedule(120, 20, 10)
+	assert.True(t, ok)
+	assert.Equal(t, Schedule{RampUpSeconds: 20, SteadySeconds: 80, RampDownSeconds: 10, DrainSeconds: 10}, schedule)
+	assert.Equal(t, 110, schedule.WarmupSeconds())
+
+	schedule, ok = NewSchedule(30, 0, 0)
+	assert.True(t, ok)
+	assert.Equal(t, Schedule{SteadySeconds: 27, DrainSeconds: 3}, schedule)
+}
+
+func TestNewSchedule_RampsDoNotFit(t *testing.T) {
+	schedule, ok := NewSchedule(20, 30, 10)
+	assert.False(t, ok)
+	assert.Equal(t, Schedule{RampUpSeconds: 13, RampDownSeconds: 5, DrainSeconds: 2}, schedule)
+}
+
+func TestNewSchedule_NegativeRamps(t *testing.T) {
+	schedule, ok := NewSchedule(20, -5, 20)
+	assert.False(t, ok)
+	assert.Equal(t, Schedule{RampDownSeconds: 18, DrainSeconds: 2}, schedule)
+
+	schedule, ok = NewSchedule(20, -5, -5)
+	assert.True(t, ok)
+	assert.Equal(t, Schedule{SteadySeconds: 18, DrainSeconds: 2}, schedule)
+}
+
+func TestNewSchedule_Short(t *testing.T) {
+	schedule, ok := NewSchedule(1, 0, 0)
+	assert.True(t, ok)
+	assert.Equal(t, Schedule{DrainSeconds: 1}, schedule)
+
+	schedule, ok = NewSchedule(0, 0, 0)
+	assert.True(t, ok)
+	assert.Equal(t, Schedule{}, schedule)
+
+	schedule, ok = NewSchedule(-2, 5, 0)
+	assert.False(t, ok)
+	assert.Equal(t, Schedule{}, schedule)
+}
@@ -0,0 +1,464 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"fmt"
+	"mittens/internal/pkg/stats"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EndpointSummary holds the outcome of the requests sent to a single endpoint.
+type EndpointSummary struct {
+	Protocol  string
+	Endpoint  string
+	Sent      int
+	Successes int
+	Failures  int
+	// LastFailure describes the last failed request, if known.
+	LastFailure string
+	// Hedged is the number of requests for which a second copy was sent because the first one was slow.
+	Hedged int
+	// Retried is the number of times a failed request was sent again.
+	Retried int
+	// LatencyChecked is the number of successful responses that were checked against the max latency of their request.
+	LatencyChecked int
+	// LatencyViolations is the number of checked responses that were slower than the max latency of their request.
+	LatencyViolations int
+	// HeaderValues counts the values of the captured response headers, by header name and value.
+	HeaderValues map[string]map[string]int
+	// Latencies counts the durations of the requests that returned a response, successful or not.
+	Latencies *stats.Histogram
+	// FirstLatencies and LastLatencies count the durations of the first and of the last latencyTrendSamples responses,
+	// which tell whether the latency of the endpoint improved during the warmup, see LatencyStalled.
+	FirstLatencies *stats.Histogram
+	LastLatencies  *stats.Histogram
+	// durations of the last latencyTrendSamples responses, the oldest one at lastLatencyIndex once full
+	lastLatencies    []time.Duration
+	lastLatencyIndex int
+}
+
+const (
+	// latencyTrendSamples is the number of first and last responses of an endpoint whose latencies are compared.
+	latencyTrendSamples = 50
+	// minLatencyImprovement is the fraction by which the median latency of the last responses of an endpoint is expected
+	// to be lower than the one of its first responses.
+	minLatencyImprovement = 0.1
+)
+
+// LatencyStalled returns true if the median latency of the last responses of the endpoint is not at least
+// minLatencyImprovement lower than the one of its first responses, e.g. because the warmup requests miss the caches
+// that the real traffic hits. It returns false until the endpoint received twice latencyTrendSamples responses.
+func (e EndpointSummary) LatencyStalled() bool {
+	if e.Latencies == nil || e.Latencies.Count() < 2*latencyTrendSamples || e.FirstLatencies == nil || e.LastLatencies == nil {
+		return false
+	}
+	return float64(e.LastLatencies.Percentile(50)) > (1-minLatencyImprovement)*float64(e.FirstLatencies.Percentile(50))
+}
+
+// recordTrend records the duration of a response among the first or the last ones of the endpoint.
+func (e *EndpointSummary) recordTrend(duration time.Duration) {
+	if e.FirstLatencies == nil {
+		e.FirstLatencies = stats.NewHistogram()
+	}
+	if e.FirstLatencies.Count() < latencyTrendSamples {
+		e.FirstLatencies.Record(duration)
+	}
+	if len(e.lastLatencies) < latencyTrendSamples {
+		e.lastLatencies = append(e.lastLatencies, duration)
+		return
+	}
+	e.lastLatencies[e.lastLatencyIndex] = duration
+	e.lastLatencyIndex = (e.lastLatencyIndex + 1) % latencyTrendSamples
+}
+
+// maxSlowSamples is the number of slowest requests kept by the summary.
+const maxSlowSamples = 100
+
+// SlowSample is a request that took longer than the slow request threshold.
+type SlowSample struct {
+	Protocol string
+	Endpoint string
+	Duration time.Duration
+}
+
+// Summary aggregates the outcome of the warmup requests per endpoint. It is safe for concurrent use.
+type Summary struct {
+	mu        sync.Mutex
+	endpoints map[string]*EndpointSummary
+	// keys of the endpoints in the order they were registered
+	keys []string
+	// problems found before sending any request that should fail the warmup
+	preflightErrors []string
+	// outcome of the requests of all the endpoints per second
+	timeseries *stats.Timeseries
+	// latencies of all the endpoints during the last seconds, only kept if enabled
+	recentLatencies *stats.Window
+	// why the warmup was aborted, if it was
+	abortReason string
+	// whether the abort should fail the warmup
+	abortFailed bool
+	// slowest requests above the slow request threshold, at most maxSlowSamples
+	slowSamples []SlowSample
+}
+
+// NewSummary returns an empty summary.
+func NewSummary() *Summary {
+	return &Summary{endpoints: make(map[string]*EndpointSummary), timeseries: stats.NewTimeseries(time.Now())}
+}
+
+// register adds an endpoint to the summary so that it is reported even if no requests are sent to it.
+func (s *Summary) register(protocol string, endpoint string) *EndpointSummary {
+	key := protocol + " " + endpoint
+	if e, ok := s.endpoints[key]; ok {
+		return e
+	}
+	e := &EndpointSummary{Protocol: protocol, Endpoint: endpoint}
+	s.endpoints[key] = e
+	s.keys = append(s.keys, key)
+	return e
+}
+
+// Register adds an endpoint to the summary so that it is reported even if no requests are sent to it.
+func (s *Summary) Register(protocol string, endpoint string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.register(protocol, endpoint)
+}
+
+// Record records the outcome of a request sent to an endpoint.
+func (s *Summary) Record(protocol string, endpoint string, success bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.register(protocol, endpoint)
+	e.Sent++
+	if success {
+		e.Successes++
+	} else {
+		e.Failures++
+	}
+	s.timeseries.Record(time.Now(), success)
+}
+
+// RecordFailure records a failed request sent to an endpoint along with the reason of the failure.
+func (s *Summary) RecordFailure(protocol string, endpoint string, reason string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.register(protocol, endpoint)
+	e.Sent++
+	e.Failures++
+	e.LastFailure = reason
+	s.timeseries.Record(time.Now(), false)
+}
+
+// RecordHedged records that a second copy of a request sent to an endpoint was sent because the first one was slow.
+// The copy is not counted as a separate request.
+func (s *Summary) RecordHedged(protocol string, endpoint string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.register(protocol, endpoint).Hedged++
+}
+
+// RecordRetry records that a failed request to an endpoint was sent again. Only the outcome of the last attempt is recorded as a request.
+func (s *Summary) RecordRetry(protocol string, endpoint string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.register(protocol, endpoint).Retried++
+}
+
+// RecordLatencyCheck records whether a successful response returned by an endpoint was slower than the max latency of its request.
+func (s *Summary) RecordLatencyCheck(protocol string, endpoint string, violated bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.register(protocol, endpoint)
+	e.LatencyChecked++
+	if violated {
+		e.LatencyViolations++
+	}
+}
+
+// RecordSlow records a request sent to an endpoint that took longer than the slow request threshold.
+// Only the maxSlowSamples slowest requests are kept.
+func (s *Summary) RecordSlow(protocol string, endpoint string, duration time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sample := SlowSample{Protocol: protocol, Endpoint: endpoint, Duration: duration}
+	if len(s.slowSamples) < maxSlowSamples {
+		s.slowSamples = append(s.slowSamples, sample)
+		return
+	}
+	fastest := 0
+	for i, kept := range s.slowSamples {
+		if kept.Duration < s.slowSamples[fastest].Duration {
+			fastest = i
+		}
+	}
+	if duration > s.slowSamples[fastest].Duration {
+		s.slowSamples[fastest] = sample
+	}
+}
+
+// SlowSamples returns the slowest requests above the slow request threshold, the slowest first.
+func (s *Summary) SlowSamples() []SlowSample {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := append([]SlowSample{}, s.slowSamples...)
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Duration > samples[j].Duration
+	})
+	return samples
+}
+
+// LatencyViolationPercent returns the percentage of the checked responses of all the endpoints that were slower than
+// the max latency of their request, or 0 if no response was checked.
+func (s *Summary) LatencyViolationPercent() float64 {
+	var checked, violations int
+	for _, e := range s.Endpoints() {
+		checked += e.LatencyChecked
+		violations += e.LatencyViolations
+	}
+	if checked == 0 {
+		return 0
+	}
+	return 100 * float64(violations) / float64(checked)
+}
+
+// RecordHeader records the value of a captured header of a response returned by an endpoint.
+func (s *Summary) RecordHeader(protocol string, endpoint string, name string, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.register(protocol, endpoint)
+	if e.HeaderValues == nil {
+		e.HeaderValues = make(map[string]map[string]int)
+	}
+	if e.HeaderValues[name] == nil {
+		e.HeaderValues[name] = make(map[string]int)
+	}
+	e.HeaderValues[name][value]++
+}
+
+// RecordLatency records the duration of a request sent to an endpoint that returned a response.
+func (s *Summary) RecordLatency(protocol string, endpoint string, duration time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.register(protocol, endpoint)
+	if e.Latencies == nil {
+		e.Latencies = stats.NewHistogram()
+	}
+	e.Latencies.Record(duration)
+	e.recordTrend(duration)
+	s.timeseries.RecordLatency(time.Now(), duration)
+	if s.recentLatencies != nil {
+		s.recentLatencies.Record(time.Now(), duration)
+	}
+}
+
+// keepRecentLatencies makes the summary keep the latencies of the last seconds, see RecentLatencies.
+func (s *Summary) keepRecentLatencies(seconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recentLatencies = stats.NewWindow(seconds, time.Now())
+}
+
+// RecentLatencies returns the latencies of all the endpoints during the last seconds, or nil if they are not kept.
+func (s *Summary) RecentLatencies() *stats.Histogram {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.recentLatencies == nil {
+		return nil
+	}
+	return s.recentLatencies.Latencies(time.Now())
+}
+
+// RecentOutcomes returns the number of requests of all the endpoints sent and failed during the last seconds.
+func (s *Summary) RecentOutcomes(seconds int) (int, int) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.timeseries.Recent(time.Now(), seconds)
+}
+
+// Timeseries returns the outcome of the requests of all the endpoints for every second since the summary was created.
+func (s *Summary) Timeseries() []stats.Second {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.timeseries.Seconds()
+}
+
+// Latencies returns the durations of the requests of all the endpoints that returned a response.
+func (s *Summary) Latencies() *stats.Histogram {
+	latencies := stats.NewHistogram()
+	for _, e := range s.Endpoints() {
+		latencies.Merge(e.Latencies)
+	}
+	return latencies
+}
+
+// Endpoints returns the summary of every endpoint in the order they were registered.
+func (s *Summary) Endpoints() []EndpointSummary {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoints := make([]EndpointSummary, 0, len(s.keys))
+	for _, key := range s.keys {
+		e := *s.endpoints[key]
+		// copy the header values so that they are not modified by requests still in flight
+		if e.HeaderValues != nil {
+			headerValues := make(map[string]map[string]int, len(e.HeaderValues))
+			for name, values := range e.HeaderValues {
+				headerValues[name] = make(map[string]int, len(values))
+				for value, count := range values {
+					headerValues[name][value] = count
+				}
+			}
+			e.HeaderValues = headerValues
+		}
+		if e.Latencies != nil {
+			e.Latencies = e.Latencies.Copy()
+		}
+		if e.FirstLatencies != nil {
+			e.FirstLatencies = e.FirstLatencies.Copy()
+			e.LastLatencies = stats.NewHistogram()
+			for _, d := range e.lastLatencies {
+				e.LastLatencies.Record(d)
+			}
+		}
+		e.lastLatencies = nil
+		endpoints = append(endpoints, e)
+	}
+	return endpoints
+}
+
+// AddPreflightError records a problem found before sending any request that should fail the warmup.
+func (s *Summary) AddPreflightError(err string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preflightErrors = append(s.preflightErrors, err)
+}
+
+// abort records why the warmup was aborted and whether this should fail the warmup.
+func (s *Summary) abort(reason string, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.abortReason = reason
+	s.abortFailed = failed
+}
+
+// AbortFailed returns true if the warmup was aborted for a reason that should fail it, e.g. too many failed requests.
+func (s *Summary) AbortFailed() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.abortFailed
+}
+
+// AbortReason returns why the warmup was aborted, or an empty string if it was not.
+func (s *Summary) AbortReason() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.abortReason
+}
+
+// PreflightErrors returns the problems found before sending any request.
+func (s *Summary) PreflightErrors() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.preflightErrors...)
+}
+
+// SuccessShortfall returns how far the successful requests are from minSuccess, either per endpoint or in total,
+// e.g. `GET /ping: 3 of 10`. It returns nothing once the minimum is reached.
+func (s *Summary) SuccessShortfall(minSuccess int, perEndpoint bool) []string {
+	var shortfall []string
+	total := 0
+	endpoints := s.Endpoints()
+	if perEndpoint && len(endpoints) == 0 && minSuccess > 0 {
+		return []string{fmt.Sprintf("no endpoint: 0 of %d", minSuccess)}
+	}
+	for _, e := range endpoints {
+		total += e.Successes
+		if perEndpoint && e.Successes < minSuccess {
+			shortfall = append(shortfall, fmt.Sprintf("%s %s: %d of %d", e.Protocol, e.Endpoint, e.Successes, minSuccess))
+		}
+	}
+	if !perEndpoint && total < minSuccess {
+		shortfall = append(shortfall, fmt.Sprintf("total: %d of %d", total, minSuccess))
+	}
+	return shortfall
+}
+
+// EndpointsWithoutSuccess returns the endpoints that never returned a successful response.
+func (s *Summary) EndpointsWithoutSuccess() []EndpointSummary {
+	var endpoints []EndpointSummary
+	for _, e := range s.Endpoints() {
+		if e.Successes == 0 {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,196 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummary_Record(t *testing.T) {
+	summary := NewSummary()
+	summary.Register("http", "GET /ping")
+	summary.Record("http", "GET /ping", true)
+	summary.Record("http", "GET /ping", false)
+	summary.Record("grpc", "health/ping", true)
+
+	endpoints := summary.Endpoints()
+	require.Equal(t, 2, len(endpoints))
+	assert.Equal(t, EndpointSummary{Protocol: "http", Endpoint: "GET /ping", Sent: 2, Successes: 1, Failures: 1}, endpoints[0])
+	assert.Equal(t, EndpointSummary{Protocol: "grpc", Endpoint: "health/ping", Sent: 1, Successes: 1, Failures: 0}, endpoints[1])
+}
+
+func TestSummary_EndpointsWithoutSuccess(t *testing.T) {
+	summary := NewSummary()
+	summary.Register("http", "GET /ping")
+	summary.Register("http", "GET /never-sent")
+	summary.Record("http", "GET /ping", true)
+	summary.Record("http", "GET /failing", false)
+
+	endpoints := summary.EndpointsWithoutSuccess()
+	require.Equal(t, 2, len(endpoints))
+	assert.Equal(t, "GET /never-sent", endpoints[0].Endpoint)
+	assert.Equal(t, "GET /failing", endpoints[1].Endpoint)
+}
+
+func TestSummary_Nil(t *testing.T) {
+	var summary *Summary
+	summary.Record("http", "GET /ping", true)
+	summary.AddPreflightError("unknown gRPC methods")
+
+	assert.Empty(t, summary.Endpoints())
+	assert.Empty(t, summary.PreflightErrors())
+}
+
+func TestSummary_PreflightErrors(t *testing.T) {
+	summary := NewSummary()
+	summary.AddPreflightError("unknown gRPC methods [foo/bar]")
+
+	assert.Equal(t, []string{"unknown gRPC methods [foo/bar]"}, summary.PreflightErrors())
+}
+
+func TestSummary_RecordHeader(t *testing.T) {
+	summary := NewSummary()
+	summary.RecordHeader("http", "GET /ping", "X-Cache", "MISS")
+	summary.RecordHeader("http", "GET /ping", "X-Cache", "HIT")
+	summary.RecordHeader("http", "GET /ping", "X-Cache", "HIT")
+
+	endpoints := summary.Endpoints()
+	require.Equal(t, 1, len(endpoints))
+	assert.Equal(t, map[string]int{"HIT": 2, "MISS": 1}, endpoints[0].HeaderValues["X-Cache"])
+
+	// the returned values are a copy
+	summary.RecordHeader("http", "GET /ping", "X-Cache", "HIT")
+	assert.Equal(t, 2, endpoints[0].HeaderValues["X-Cache"]["HIT"])
+}
+
+func TestSummary_LatencyViolationPercent(t *testing.T) {
+	summary := NewSummary()
+	assert.Zero(t, summary.LatencyViolationPercent())
+
+	summary.RecordLatencyCheck("http", "GET /ping", false)
+	summary.RecordLatencyCheck("http", "GET /ping", true)
+	summary.RecordLatencyCheck("grpc", "health/ping", false)
+	summary.RecordLatencyCheck("grpc", "health/ping", false)
+
+	endpoints := summary.Endpoints()
+	require.Equal(t, 2, len(endpoints))
+	assert.Equal(t, 2, endpoints[0].LatencyChecked)
+	assert.Equal(t, 1, endpoints[0].LatencyViolations)
+	assert.Equal(t, 25.0, summary.LatencyViolationPercent())
+}
+
+func TestSummary_RecordLatency(t *testing.T) {
+	summary := NewSummary()
+	summary.RecordLatency("http", "GET /ping", 10*time.Millisecond)
+	summary.RecordLatency("http", "GET /ping", 30*time.Millisecond)
+	summary.RecordLatency("grpc", "health/ping", 20*time.Millisecond)
+
+	endpoints := summary.Endpoints()
+	require.Equal(t, 2, len(endpoints))
+	assert.Equal(t, uint64(2), endpoints[0].Latencies.Count())
+	assert.Equal(t, 30*time.Millisecond, endpoints[0].Latencies.Max())
+
+	latencies := summary.Latencies()
+	assert.Equal(t, uint64(3), latencies.Count())
+	assert.Equal(t, 20*time.Millisecond, latencies.Mean())
+}
+
+func TestSummary_LatencyStalled(t *testing.T) {
+	summary := NewSummary()
+	for i := 0; i < 3*latencyTrendSamples; i++ {
+		// the latency of /cached drops once it is warm while the one of /uncached never does
+		latency := 100 * time.Millisecond
+		if i >= latencyTrendSamples {
+			latency = 10 * time.Millisecond
+		}
+		summary.RecordLatency("http", "GET /cached", latency)
+		summary.RecordLatency("http", "GET /uncached", 100*time.Millisecond)
+		if i < 2*latencyTrendSamples-1 {
+			summary.RecordLatency("http", "GET /rare", 100*time.Millisecond)
+		}
+	}
+
+	endpoints := summary.Endpoints()
+	require.Len(t, endpoints, 3)
+	assert.False(t, endpoints[0].LatencyStalled())
+	assert.Equal(t, uint64(latencyTrendSamples), endpoints[0].FirstLatencies.Count())
+	assert.Equal(t, 100*time.Millisecond, endpoints[0].FirstLatencies.Max())
+	assert.Equal(t, uint64(latencyTrendSamples), endpoints[0].LastLatencies.Count())
+	assert.Equal(t, 10*time.Millisecond, endpoints[0].LastLatencies.Max())
+	assert.True(t, endpoints[1].LatencyStalled())
+	assert.False(t, endpoints[2].LatencyStalled(), "not enough responses to tell")
+}
+
+func TestSummary_SlowSamples(t *testing.T) {
+	summary := NewSummary()
+	for i := 1; i <= maxSlowSamples+2; i++ {
+		summary.RecordSlow("http", "GET /slow", time.Duration(i)*time.Millisecond)
+	}
+	summary.RecordSlow("grpc", "health/ping", time.Millisecond)
+
+	samples := summary.SlowSamples()
+	require.Len(t, samples, maxSlowSamples)
+	assert.Equal(t, SlowSample{Protocol: "http", Endpoint: "GET /slow", Duration: time.Duration(maxSlowSamples+2) * time.Millisecond}, samples[0])
+	assert.Equal(t, 3*time.Millisecond, samples[maxSlowSamples-1].Duration)
+}
+
+func TestSummary_SuccessShortfall(t *testing.T) {
+	summary := NewSummary()
+	summary.Register("http", "GET /never-sent")
+	summary.Record("http", "GET /ping", true)
+	summary.Record("http", "GET /ping", true)
+	summary.Record("grpc", "health/ping", true)
+	summary.Record("grpc", "health/ping", false)
+
+	assert.Empty(t, summary.SuccessShortfall(3, false))
+	assert.Equal(t, []string{"total: 3 of 4"}, summary.SuccessShortfall(4, false))
+	assert.Equal(t, []string{"http GET /never-sent: 0 of 2", "grpc health/ping: 1 of 2"}, summary.SuccessShortfall(2, true))
+	assert.Empty(t, NewSummary().SuccessShortfall(0, true))
+	assert.Equal(t, []string{"no endpoint: 0 of 1"}, NewSummary().SuccessShortfall(1, true))
+}
+
+func TestSummary_Timeseries(t *testing.T) {
+	s := NewSummary()
+	s.Record("http", "GET /a", true)
+	s.RecordLatency("http", "GET /a", 10*time.Millisecond)
+	s.RecordFailure("http", "GET /b", "500")
+
+	seconds := s.Timeseries()
+	require.Len(t, seconds, 1)
+	assert.Equal(t, 2, seconds[0].Sent)
+	assert.Equal(t, 1, seconds[0].Successes)
+	assert.Equal(t, 1, seconds[0].Failures)
+	assert.Equal(t, 10*time.Millisecond, seconds[0].P95)
+
+	var nilSummary *Summary
+	assert.Empty(t, nilSummary.Timeseries())
+}
+
+func TestSummary_RecentLatencies(t *testing.T) {
+	s := NewSummary()
+	s.RecordLatency("http", "GET /a", 10*time.Millisecond)
+	assert.Nil(t, s.RecentLatencies())
+
+	s.keepRecentLatencies(5)
+	s.RecordLatency("http", "GET /a", 20*time.Millisecond)
+	s.RecordLatency("grpc", "Service/Method", 30*time.Millisecond)
+	recent := s.RecentLatencies()
+	assert.Equal(t, uint64(2), recent.Count())
+	assert.Equal(t, 20*time.Millisecond, recent.Min())
+}
@@ -15,12 +15,15 @@
 package warmup
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
 	"log"
 	"mittens/internal/pkg/grpc"
 	whttp "mittens/internal/pkg/http"
 	"net/http"
 	"time"
+
+	"google.golang.org/grpc/codes"
 )
 
 // TargetOptions represents target configurations set by the user.
@@ -37,7 +40,9 @@ type Target struct {
 	readinessGrpcClient grpc.Client
 	httpClient          whttp.Client
 	grpcClient          grpc.Client
-	options             TargetOptions
+	// clients of the other gRPC hosts across which the gRPC warmup requests are spread
+	additionalGrpcClients []grpc.Client
+	options               TargetOptions
 }
 
 // NewTarget returns an instance of the target versus which mittens will run.
@@ -52,44 +57,100 @@ func NewTarget(readinessHTTPClient whttp.Client, readinessGrpcClient grpc.Client
 	return t
 }
 
-// WaitForReadinessProbe sends health-check requests to the target and waits until it becomes ready.
-// It returns an error if the timeout is exceeded.
-// It supports both HTTP and gRPC health-checks.
-func (t Target) WaitForReadinessProbe(maxReadinessWaitDurationInSeconds int, headers []string) error {
-	log.Printf("Waiting for %s target to be ready for a max of %ds", t.options.ReadinessProtocol, maxReadinessWaitDurationInSeconds)
+// WithAdditionalGrpcClients returns a copy of the target whose gRPC warmup requests are spread across its gRPC warmup client
+// and these clients, e.g. connected to other hosts of the same service.
+func (t Target) WithAdditionalGrpcClients(clients ...grpc.Client) Target {
+	t.additionalGrpcClients = append(append([]grpc.Client{}, t.additionalGrpcClients...), clients...)
+	return t
+}
 
-	timeout := time.After(time.Duration(maxReadinessWaitDurationInSeconds) * time.Second)
+// grpcClients returns the gRPC warmup client followed by the additional ones.
+func (t *Target) grpcClients() []*grpc.Client {
+	clients := []*grpc.Client{&t.grpcClient}
+	for i := range t.additionalGrpcClients {
+		clients = append(clients, &t.additionalGrpcClients[i])
+	}
+	return clients
+}
 
+// grpcHealthCheckMethod is the method of the standard gRPC health-check service, whose responses carry a serving status.
+const grpcHealthCheckMethod = "grpc.health.v1.Health/Check"
+
+// waitForReadiness sends health-check requests to the target, every interval, until it becomes ready or ctx is done.
+// It supports both HTTP and gRPC health-checks.
+func (t Target) waitForReadiness(ctx context.Context, headers []string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
-		select {
-		case <-timeout:
-			return fmt.Errorf("giving up; target not ready after %d seconds 🙁", maxReadinessWaitDurationInSeconds)
-		default:
-			// Wait one second between attempts. This is not configurable
-			time.Sleep(time.Second * 1)
-
-			if t.options.ReadinessProtocol == "http" {
-				// error if error in the response or status code not in the 200 range
-				if resp := t.readinessHTTPClient.SendRequest(http.MethodGet, t.options.ReadinessHTTPPath, headers, nil); resp.Err != nil || resp.StatusCode/100 != 2 {
-					log.Printf("HTTP target not ready yet...")
-					continue
-				}
-			} else {
-				request, err := grpc.ToGrpcRequest(t.options.ReadinessGrpcMethod)
-				if err == nil {
-					log.Print("gRPC readiness client connecting...")
-					connErr := t.readinessGrpcClient.Connect(nil)
-					if connErr != nil {
-						log.Printf("gRPC readiness client connect error: %v", connErr)
-					}
-					err1 := t.readinessGrpcClient.SendRequest(request.ServiceMethod, "", headers, false)
-					if err1.Err != nil {
-						log.Printf("gRPC target not ready yet...")
-						continue
-					}
-				}
-			}
+		if t.ready(headers) {
 			return nil
 		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ready sends a single health-check request to the target. HTTP targets are ready once they return a 2xx status code
+// and gRPC targets once they return the OK status and, for the standard health-check method, the SERVING status.
+func (t *Target) ready(headers []string) bool {
+	if t.options.ReadinessProtocol == "http" {
+		// error if error in the response or status code not in the 200 range
+		if resp := t.readinessHTTPClient.SendRequest(http.MethodGet, t.options.ReadinessHTTPPath, headers, nil); resp.Err != nil || resp.StatusCode/100 != 2 {
+			log.Printf("HTTP target not ready yet...")
+			return false
+		}
+		return true
 	}
+
+	request, err := grpc.ToGrpcRequest(t.options.ReadinessGrpcMethod)
+	if err != nil {
+		log.Printf("Invalid gRPC readiness method %s: %v", t.options.ReadinessGrpcMethod, err)
+		return false
+	}
+	if !t.readinessGrpcClient.Connected() {
+		log.Print("gRPC readiness client connecting...")
+		if err := t.readinessGrpcClient.Connect(nil); err != nil {
+			log.Printf("gRPC readiness client connect error: %v", err)
+			return false
+		}
+	}
+	resp := t.readinessGrpcClient.SendRequestCapturingBody(request.ServiceMethod, "", headers, maxHealthCheckBodyBytes)
+	if resp.Err != nil || resp.GrpcStatus != codes.OK {
+		log.Printf("gRPC target not ready yet...")
+		return false
+	}
+	if request.ServiceMethod == grpcHealthCheckMethod {
+		if status := healthCheckStatus(resp.Body); status != "SERVING" {
+			log.Printf("gRPC target not ready yet: health status %s", status)
+			return false
+		}
+	}
+	return true
+}
+
+// maxHealthCheckBodyBytes bounds the captured health-check response, which is tiny.
+const maxHealthCheckBodyBytes = 1024
+
+// healthCheckStatus returns the status of a JSON health-check response. The status is omitted from the JSON when it is UNKNOWN.
+func healthCheckStatus(body []byte) string {
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &health); err != nil || health.Status == "" {
+		return "UNKNOWN"
+	}
+	return health.Status
+}
+
+// GrpcPoolStats returns the health of the connection pool of the gRPC warmup client. It is false if there is no pool.
+func (t Target) GrpcPoolStats() (grpc.PoolStats, bool) {
+	return t.grpcClient.PoolStats()
+}
+
+// HTTPConnections returns the connections opened so far by the HTTP warmup client, by host.
+func (t Target) HTTPConnections() map[string]whttp.HostConnections {
+	return t.httpClient.Connections()
 }
@@ -0,0 +1,50 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"fmt"
+)
+
+// Validate checks every request without sending any of them, as a dry run of the warmup.
+// HTTP requests must have a supported method and a valid path. gRPC methods must be resolved via the descriptor source
+// and their messages must be valid inputs of the methods, which requires connecting to the target.
+// It returns all the problems found, so that they can be fixed at once.
+func (w *Warmup) Validate() []error {
+	var problems []error
+	for _, request := range w.HttpRequests {
+		if err := w.Target.httpClient.ValidateRequest(request.Method, request.WithQuery().Path); err != nil {
+			problems = append(problems, fmt.Errorf("HTTP request %s: %v", httpEndpoint(request), err))
+		}
+		if _, err := request.WithBodyFromFile(); err != nil {
+			problems = append(problems, fmt.Errorf("HTTP request %s: %v", httpEndpoint(request), err))
+		}
+	}
+	if len(w.GrpcRequests) == 0 {
+		return problems
+	}
+
+	if !w.Target.grpcClient.Connected() {
+		if err := w.Target.grpcClient.Connect(w.withGrpcMetadata(w.HttpHeaders)); err != nil {
+			return append(problems, fmt.Errorf("gRPC requests cannot be validated: %v", err))
+		}
+	}
+	for _, request := range w.GrpcRequests {
+		if err := w.Target.grpcClient.ValidateRequest(request.ServiceMethod, request.Message); err != nil {
+			problems = append(problems, fmt.Errorf("gRPC request %s: %v", request.ServiceMethod, err))
+		}
+	}
+	return problems
+}
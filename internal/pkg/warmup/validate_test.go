@@ -0,0 +1,74 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"mittens/fixture"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	var received int32
+	httpServer := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer httpServer.Close()
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+
+	httpClient := newHTTPClient(t, httpServer.URL, false, http.ClientOptions{})
+	grpcClient := newGrpcClient(t, address, true, grpc.ClientOptions{})
+	w := &Warmup{
+		Target: NewTarget(httpClient, grpcClient, httpClient, grpcClient, TargetOptions{}),
+		HttpRequests: []http.Request{
+			{Method: "GET", Path: "/ping"},
+			{Method: "FE TCH", Path: "/ping"},
+		},
+		GrpcRequests: []grpc.Request{
+			{ServiceMethod: "grpc.testing.TestService/EmptyCall"},
+			{ServiceMethod: "grpc.testing.TestService/EmptyCal"},
+			{ServiceMethod: "grpc.testing.TestService/UnaryCall", Message: `{"unknown": 1}`},
+		},
+	}
+	defer w.Target.grpcClient.Close()
+
+	problems := w.Validate()
+
+	require.Len(t, problems, 3)
+	assert.Contains(t, problems[0].Error(), "HTTP request FE TCH /ping")
+	assert.Contains(t, problems[1].Error(), "gRPC request grpc.testing.TestService/EmptyCal")
+	assert.Contains(t, problems[2].Error(), "gRPC request grpc.testing.TestService/UnaryCall")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&received))
+}
+
+func TestValidate_GrpcConnectionError(t *testing.T) {
+	grpcClient := newGrpcClient(t, "127.0.0.1:1", true, grpc.ClientOptions{})
+	w := &Warmup{
+		Target:       NewTarget(http.Client{}, grpcClient, http.Client{}, grpcClient, TargetOptions{}),
+		GrpcRequests: []grpc.Request{{ServiceMethod: "grpc.testing.TestService/EmptyCall"}},
+	}
+
+	problems := w.Validate()
+
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Error(), "gRPC requests cannot be validated")
+}
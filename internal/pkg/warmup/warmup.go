@@ -15,160 +15,1093 @@
 package warmup
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"math/rand"
+	"mittens/internal/pkg/golden"
 	"mittens/internal/pkg/grpc"
 	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/marker"
+	"mittens/internal/pkg/metrics"
+	"mittens/internal/pkg/placeholders"
+	"mittens/internal/pkg/ratelimit"
+	"mittens/internal/pkg/recording"
+	"mittens/internal/pkg/response"
+	"mittens/internal/pkg/resultlog"
 	"mittens/internal/pkg/safe"
+	"strconv"
+	"strings"
 
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"google.golang.org/grpc/codes"
 )
 
+// ErrNoRequests is returned by Run if there are no warmup requests to send.
+var ErrNoRequests = errors.New("no warmup requests to send")
+
 // Warmup holds any information needed for the workers to send requests.
 type Warmup struct {
-	Target                   Target
-	Concurrency              int
-	HttpRequests             []http.Request
-	HttpHeaders              []string
+	Target Target
+	// Concurrency is the number of workers of each protocol. If it is not greater than 0 the number of CPUs is used.
+	Concurrency  int
+	HttpRequests []http.Request
+	HttpHeaders  []string
+	// GrpcMetadata is sent with the gRPC requests in addition to the HTTP headers.
+	GrpcMetadata             []string
 	GrpcRequests             []grpc.Request
+	GrpcWarmAll              bool
+	GrpcServiceFilters       []string
 	RequestDelayMilliseconds int
+	// DelayJitterMilliseconds, if greater than 0, randomizes every delay between requests by up to this number
+	// of milliseconds either way so that the workers do not send their requests in sync.
+	DelayJitterMilliseconds  int
 	ConcurrencyTargetSeconds int
+	// RampUpCurve is how the workers are started over ConcurrencyTargetSeconds, LinearRampUp or ExponentialRampUp.
+	// It defaults to LinearRampUp.
+	RampUpCurve string
+	// RampUpShape, if greater than 0, shapes the ExponentialRampUp: the greater, the slower the start. It defaults to DefaultRampUpShape.
+	RampUpShape float64
+	// RampDownSeconds, if greater than 0, is the time before the end of the warmup during which the workers exit one by one.
+	RampDownSeconds int
+	// DrainSeconds, if greater than 0, bounds the time spent waiting for the requests still in flight once the warmup ends.
+	DrainSeconds        int
+	RequestOrder        string
+	GoldenNormalizeJSON bool
+	GoldenPrintDiff     bool
+	// TargetRequestsPerSecond, if greater than 0, replaces Concurrency with the concurrency needed to reach this rate.
+	TargetRequestsPerSecond int
+	MinConcurrency          int
+	MaxConcurrency          int
+	// FailOnUnknownGrpcMethods aborts the warmup if a configured gRPC method cannot be resolved; otherwise such methods are skipped.
+	FailOnUnknownGrpcMethods bool
+	// RequestsPerSecond caps the rate of the requests sent by all the workers together. 0 means no limit.
+	RequestsPerSecond float64
+	// PerWorkerRequestsPerSecond caps the rate of the requests sent by each worker. 0 means no limit.
+	PerWorkerRequestsPerSecond float64
+	// CorrelationHeader, if set, is the name of a header in which a new UUID is sent with every request and logged with its result.
+	CorrelationHeader string
+	// CaptureHeaders are the names of the HTTP response headers whose values are counted in the summary.
+	CaptureHeaders []string
+	// ProtocolMix, if set, makes the same workers send both HTTP and gRPC requests in the given proportion
+	// instead of running separate HTTP and gRPC workers.
+	ProtocolMix *ProtocolMix
+	// Recorder, if set, records every request sent along with a summary of its response.
+	Recorder *recording.Recorder
+	// ResultLogger, if set, writes the result of every request as a JSON object instead of logging it as a text line.
+	ResultLogger *resultlog.Logger
+	// Metrics, if set, counts every request sent along with its duration and the workers running.
+	Metrics *metrics.Metrics
+	// MinSuccess, if greater than 0, stops the warmup early once this number of requests succeeded, in total
+	// or per endpoint if MinSuccessPerEndpoint is set.
+	MinSuccess            int
+	MinSuccessPerEndpoint bool
+	// AbortP99Latency, if greater than 0, aborts the warmup once the p99 latency of the last AbortP99WindowSeconds
+	// stays above it for AbortP99SustainSeconds in a row, as the warmup is then likely harming the target.
+	AbortP99Latency        time.Duration
+	AbortP99WindowSeconds  int
+	AbortP99SustainSeconds int
+	// AbortErrorRate, if greater than 0, aborts and fails the warmup once more than this percentage of the requests
+	// of the last AbortErrorWindowSeconds failed, provided that at least AbortErrorMinRequests were sent.
+	AbortErrorRate          float64
+	AbortErrorWindowSeconds int
+	AbortErrorMinRequests   int
+	// WorkerSeed is the seed of the worker placeholders of the first worker, the next workers get the next seeds.
+	WorkerSeed int64
+	// SlowRequestThresholdMilliseconds, if greater than 0, is the duration above which a request is logged as slow
+	// and kept in the summary among the slowest requests.
+	SlowRequestThresholdMilliseconds int
+	// MaxBodyBytes is the maximum number of bytes of a response body searched for the expected substring of its request.
+	MaxBodyBytes int
+	// Retries is the number of times a request that failed with a transport error or a 5xx status code is sent again.
+	// The delay before every retry doubles, starting from RetryBackoff.
+	Retries      int
+	RetryBackoff time.Duration
+	// ReadinessTimeout is the maximum time WaitForReadiness waits for the target. 0 means no limit other than its context.
+	// ReadinessPollInterval is the interval between health-checks, 1 second if not set.
+	ReadinessTimeout      time.Duration
+	ReadinessPollInterval time.Duration
+	// MaxRequests, if greater than 0, is the number of requests, HTTP and gRPC together, after which the workers are fed
+	// no more requests and the warmup ends, even if its duration is not over.
+	MaxRequests int
+	// NewRand, if set, returns the random source of a request feeder or a worker, e.g. a seeded one in tests.
+	// Every feeder gets its own source so that they neither contend on nor depend on each other.
+	NewRand func() *rand.Rand
+	// ConcurrencyControl, if set, allows changing the concurrency while the warmup runs.
+	ConcurrencyControl *ConcurrencyControl
+	summary            *Summary
+	rateLimiter        *ratelimit.Limiter
+	budget             *requestBudget
+	grpcTargets        *grpcTargets
+	// closed once the warmup is over
+	done <-chan struct{}
+}
+
+// newRand returns a new random source for a request feeder or a worker, seeded from the global one unless NewRand is set.
+func (w Warmup) newRand() *rand.Rand {
+	if w.NewRand != nil {
+		return w.NewRand()
+	}
+	return rand.New(rand.NewSource(rand.Int63()))
+}
+
+// GetWarmupHTTPRequests returns a channel fed with the HTTP requests, see feedRequests.
+func (w Warmup) GetWarmupHTTPRequests(ctx context.Context, maxDurationSeconds int) chan http.Request {
+	return feedRequests(ctx, w, w.HttpRequests, httpWeights(w.HttpRequests), func(request http.Request) int { return request.Burst }, maxDurationSeconds)
+}
+
+// GetWarmupGrpcRequests returns a channel fed with the gRPC requests, see feedRequests.
+func (w Warmup) GetWarmupGrpcRequests(ctx context.Context, maxDurationSeconds int) chan grpc.Request {
+	return feedRequests(ctx, w, w.GrpcRequests, grpcWeights(w.GrpcRequests), func(request grpc.Request) int { return request.Burst }, maxDurationSeconds)
 }
 
-func (w Warmup) GetWarmupHTTPRequests(maxDurationSeconds int) chan http.Request {
-	requestsChan := make(chan http.Request)
+// feedRequests returns a channel that a goroutine continuously feeds with requests, picked in the request order of the
+// warmup according to their weights, for a maximum of maxDurationSeconds, until ctx is done or the request budget runs out.
+// The channel is closed then, or right away if there are no requests.
+func feedRequests[T any](ctx context.Context, w Warmup, requests []T, weights []int, bursts func(T) int, maxDurationSeconds int) chan T {
+	requestsChan := make(chan T)
 
-	// create a goroutine that continuously adds requests to a channel for a maximum of maxDurationSeconds
 	go safe.Do(func() {
-		if len(w.HttpRequests) == 0 {
-			close(requestsChan)
+		defer close(requestsChan)
+		if len(requests) == 0 {
 			return
 		}
 		timeout := time.After(time.Duration(maxDurationSeconds) * time.Second)
+		selector := newWeightedRequestSelector(w.RequestOrder, weights, w.newRand())
 
 		for {
+			request := requests[selector.next()]
+			if !w.budget.take(burst(bursts(request))) {
+				return
+			}
 			select {
 			case <-timeout:
-				close(requestsChan)
 				return
-			default:
-				number := rand.Intn(len(w.HttpRequests))
-				requestsChan <- w.HttpRequests[number]
+			case <-ctx.Done():
+				return
+			case requestsChan <- request:
 			}
 		}
 	})
 	return requestsChan
 }
 
-// FIXME: make this generic and remove duplication
-func (w Warmup) GetWarmupGrpcRequests(maxDurationSeconds int) chan grpc.Request {
-	requestsChan := make(chan grpc.Request)
+// Run sends requests to the target using goroutines until maxDurationSeconds elapse or ctx is done.
+// maxDurationSeconds counts from the call to Run: connecting to the gRPC target, measuring the latency for
+// TargetRequestsPerSecond and the ramp up are all part of it, so that only the requests still in flight can outlast it.
+// Once ctx is done the ramp up stops, the workers send no new requests and Run returns as soon as the requests in flight complete.
+// It returns a summary of the outcome of the requests sent to each endpoint, and an error if no request could be sent
+// at all, e.g. because there are no requests or the gRPC client could not connect and there are no HTTP requests.
+func (w *Warmup) Run(ctx context.Context, hasHttpRequests bool, hasGrpcRequests bool, maxDurationSeconds int, requestsSentCounter *int64) (*Summary, error) {
+	rand.Seed(time.Now().UnixNano()) // initialize seed only once to prevent deterministic/repeated calls every time we run
 
-	// create a goroutine that continuously adds requests to a channel for a maximum of maxDurationSeconds
-	go safe.Do(func() {
-		if len(w.GrpcRequests) == 0 {
-			close(requestsChan)
-			return
+	w.summary = NewSummary()
+	w.rateLimiter = ratelimit.New(w.RequestsPerSecond)
+	// workers started later via the concurrency control must not outlive the warmup
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(maxDurationSeconds)*time.Second)
+	defer cancel()
+	w.done = ctx.Done()
+	w.budget = nil
+	if w.MaxRequests > 0 {
+		w.budget = newRequestBudget(w.MaxRequests)
+	}
+
+	var grpcConnErr error
+	if hasGrpcRequests {
+		// connect to the gRPC hosts once and only if there are gRPC requests
+		w.grpcTargets, grpcConnErr = w.connectGrpcTargets()
+		if grpcConnErr != nil {
+			log.Printf("gRPC client connect error: %v", grpcConnErr)
+		} else if !w.withKnownGrpcMethods() {
+			return w.summary, fmt.Errorf("unknown gRPC methods, no warmup requests were sent")
+		} else if w.GrpcWarmAll {
+			w.GrpcRequests = w.withDiscoveredGrpcRequests()
 		}
-		timeout := time.After(time.Duration(maxDurationSeconds) * time.Second)
+		for _, request := range w.GrpcRequests {
+			w.summary.Register("grpc", request.ServiceMethod)
+		}
+	}
+	if hasHttpRequests {
+		for _, request := range w.HttpRequests {
+			w.summary.Register("http", httpEndpoint(request))
+			if request.Conditional {
+				w.summary.Register("http", conditionalHTTPEndpoint(request))
+			}
+		}
+	}
 
-		for {
-			select {
-			case <-timeout:
-				close(requestsChan)
+	sendsHTTP := hasHttpRequests && len(w.HttpRequests) > 0
+	sendsGrpc := hasGrpcRequests && grpcConnErr == nil && len(w.GrpcRequests) > 0
+	if !sendsHTTP && !sendsGrpc {
+		if grpcConnErr != nil {
+			return w.summary, fmt.Errorf("gRPC client connect error: %v", grpcConnErr)
+		}
+		return w.summary, ErrNoRequests
+	}
+
+	if w.TargetRequestsPerSecond > 0 {
+		w.Concurrency = w.autoConcurrency(hasHttpRequests, hasGrpcRequests && grpcConnErr == nil, requestsSentCounter)
+	}
+	if w.MinSuccess > 0 {
+		go safe.Do(func() {
+			w.stopOnMinSuccess(ctx, cancel)
+		})
+	}
+	if w.AbortP99Latency > 0 {
+		w.summary.keepRecentLatencies(w.AbortP99WindowSeconds)
+		go safe.Do(func() {
+			w.abortOnLatencyBreach(ctx, cancel)
+		})
+	}
+	if w.AbortErrorRate > 0 {
+		go safe.Do(func() {
+			w.abortOnErrorRate(ctx, cancel)
+		})
+	}
+	// every worker of the run, whatever its pool, gets the next seed
+	var workers int64
+	nextWorker := func() *placeholders.Worker {
+		return placeholders.NewWorker(w.WorkerSeed + atomic.AddInt64(&workers, 1))
+	}
+	httpPool := newWorkerPool(ctx, "HTTP", func(ctx context.Context, wg *sync.WaitGroup) {
+		w.HTTPWarmupWorker(wg, w.GetWarmupHTTPRequests(ctx, maxDurationSeconds), w.HttpHeaders, nextWorker(), w.RequestDelayMilliseconds, requestsSentCounter)
+	})
+	grpcPool := newWorkerPool(ctx, "gRPC", func(ctx context.Context, wg *sync.WaitGroup) {
+		w.GrpcWarmupWorker(wg, w.GetWarmupGrpcRequests(ctx, maxDurationSeconds), w.HttpHeaders, nextWorker(), w.RequestDelayMilliseconds, requestsSentCounter)
+	})
+	var pools []*workerPool
+	mixed := w.ProtocolMix != nil && hasHttpRequests && hasGrpcRequests
+	if mixed {
+		mix := *w.ProtocolMix
+		if grpcConnErr != nil {
+			// the mixed workers still send the HTTP requests
+			mix.GrpcWeight = 0
+		}
+		pools = append(pools, newWorkerPool(ctx, "mixed HTTP and gRPC", func(ctx context.Context, wg *sync.WaitGroup) {
+			w.MixedWarmupWorker(wg, w.GetWarmupMixedRequests(ctx, mix, maxDurationSeconds), w.HttpHeaders, nextWorker(), w.RequestDelayMilliseconds, requestsSentCounter)
+		}))
+	} else {
+		if hasHttpRequests {
+			pools = append(pools, httpPool)
+		}
+		if hasGrpcRequests && grpcConnErr == nil {
+			pools = append(pools, grpcPool)
+		}
+	}
+	// the concurrency may have been changed while an earlier cycle was running
+	w.Concurrency = w.ConcurrencyControl.attach(cpuConcurrency(w.Concurrency), pools...)
+	defer w.ConcurrencyControl.detach()
+
+	if w.ConcurrencyTargetSeconds < 0 {
+		log.Printf("%s Ramp-up of %d second(s) is negative, starting all the workers at once", marker.Warning(), w.ConcurrencyTargetSeconds)
+		w.ConcurrencyTargetSeconds = 0
+	}
+	rampUpDelays := rampUpDelays(w.RampUpCurve, w.RampUpShape, w.ConcurrencyTargetSeconds, w.Concurrency)
+
+	for _, pool := range pools {
+		// the ramp up stops as soon as the warmup is over
+		for i := 1; i <= w.Concurrency && w.waitForRampUp(rampUpDelays[i-1]); i++ {
+			w.ConcurrencyControl.grow(pool, i)
+		}
+	}
+
+	if w.RampDownSeconds > 0 {
+		deadline, _ := ctx.Deadline()
+		go safe.Do(func() {
+			rampDown(ctx, pools, w.ConcurrencyControl, w.Concurrency, deadline.Add(-time.Duration(w.RampDownSeconds)*time.Second), w.RampDownSeconds)
+		})
+	}
+
+	w.waitForPools(ctx, pools)
+	if mixed {
+		logProtocolMix(w.summary, *w.ProtocolMix)
+	}
+	return w.summary, nil
+}
+
+// stopOnMinSuccess cancels the warmup once the minimum number of successful requests is reached.
+func (w *Warmup) stopOnMinSuccess(ctx context.Context, cancel context.CancelFunc) {
+	start := time.Now()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if len(w.summary.SuccessShortfall(w.MinSuccess, w.MinSuccessPerEndpoint)) == 0 {
+				log.Printf("%s Minimum of %d successful request(s) reached after %d second(s), stopping the warmup", marker.Success(), w.MinSuccess, int(time.Since(start).Seconds()))
+				cancel()
 				return
-			default:
-				number := rand.Intn(len(w.GrpcRequests))
-				requestsChan <- w.GrpcRequests[number]
 			}
 		}
-	})
-	return requestsChan
+	}
 }
 
-// Run sends requests to the target using goroutines.
-func (w Warmup) Run(hasHttpRequests bool, hasGrpcRequests bool, maxDurationSeconds int, requestsSentCounter *int) {
-	rand.Seed(time.Now().UnixNano()) // initialize seed only once to prevent deterministic/repeated calls every time we run
+// abortOnLatencyBreach cancels the warmup once the p99 latency of the recent requests stays above AbortP99Latency
+// for AbortP99SustainSeconds in a row. Windows with fewer than minBreachSamples latencies are not checked.
+func (w *Warmup) abortOnLatencyBreach(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	breached := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		latencies := w.summary.RecentLatencies()
+		p99 := latencies.Percentile(99)
+		if latencies.Count() < minBreachSamples || p99 <= w.AbortP99Latency {
+			breached = 0
+			continue
+		}
+		breached++
+		log.Printf("%s p99 latency of the last %d second(s) is %d ms, above the ceiling of %d ms", marker.Warning(), w.AbortP99WindowSeconds, p99/time.Millisecond, w.AbortP99Latency/time.Millisecond)
+		if breached >= w.AbortP99SustainSeconds {
+			reason := fmt.Sprintf("p99 latency above %d ms for %d second(s), last %d ms", w.AbortP99Latency/time.Millisecond, breached, p99/time.Millisecond)
+			log.Printf("%s Aborting the warmup: %s", marker.Failure(), reason)
+			w.summary.abort(reason, false)
+			cancel()
+			return
+		}
+	}
+}
 
-	var wg sync.WaitGroup
-	var rampUpInterval = w.ConcurrencyTargetSeconds / w.Concurrency
+// abortOnErrorRate cancels the warmup once more than AbortErrorRate percent of the requests of the last
+// AbortErrorWindowSeconds failed. Windows with fewer than AbortErrorMinRequests requests are not checked.
+func (w *Warmup) abortOnErrorRate(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sent, failures := w.summary.RecentOutcomes(w.AbortErrorWindowSeconds)
+		if sent == 0 || sent < w.AbortErrorMinRequests {
+			continue
+		}
+		rate := 100 * float64(failures) / float64(sent)
+		if rate <= w.AbortErrorRate {
+			continue
+		}
+		reason := fmt.Sprintf("%.1f%% of the %d request(s) of the last %d second(s) failed, above the limit of %.1f%%", rate, sent, w.AbortErrorWindowSeconds, w.AbortErrorRate)
+		log.Printf("%s Aborting the warmup: %s", marker.Failure(), reason)
+		w.summary.abort(reason, true)
+		cancel()
+		return
+	}
+}
 
-	if hasHttpRequests {
-		for i := 1; i <= w.Concurrency; i++ {
-			waitForRampUp(rampUpInterval, i)
-			log.Printf("Spawning new go routine for HTTP requests")
-			wg.Add(1)
-			go safe.Do(func() {
-				w.HTTPWarmupWorker(&wg, w.GetWarmupHTTPRequests(maxDurationSeconds), w.HttpHeaders, w.RequestDelayMilliseconds, requestsSentCounter)
-			})
+// waitForPools waits for the workers of the pools to finish. If DrainSeconds is greater than 0 it stops waiting for the requests
+// still in flight DrainSeconds after ctx is done, so that the warmup ends on time; their outcome may then be missing from the summary.
+func (w *Warmup) waitForPools(ctx context.Context, pools []*workerPool) {
+	done := make(chan struct{})
+	go safe.Do(func() {
+		for _, pool := range pools {
+			pool.wait()
 		}
+		close(done)
+	})
+	if w.DrainSeconds <= 0 {
+		<-done
+		return
 	}
 
-	if hasGrpcRequests {
-		// connect to gRPC server once and only if there are gRPC requests
-		log.Print("gRPC client connecting...")
-		connErr := w.Target.grpcClient.Connect(w.HttpHeaders)
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Duration(w.DrainSeconds) * time.Second):
+		log.Printf("%s Gave up on the requests still in flight %d second(s) after the end of the warmup", marker.Warning(), w.DrainSeconds)
+	}
+}
 
-		if connErr != nil {
-			log.Printf("gRPC client connect error: %v", connErr)
-		} else {
-			for i := 1; i <= w.Concurrency; i++ {
-				waitForRampUp(rampUpInterval, i)
-				log.Printf("Spawning new go routine for gRPC requests")
-				wg.Add(1)
-				go safe.Do(func() {
-					w.GrpcWarmupWorker(&wg, w.GetWarmupGrpcRequests(maxDurationSeconds), w.HttpHeaders, w.RequestDelayMilliseconds, requestsSentCounter)
-				})
+// WaitForReadiness polls the readiness endpoint of the target, an HTTP path or a gRPC method, until the target is ready
+// and is meant to be called before Run so that no warmup request is sent to a target still starting up.
+// It returns an error if the target is not ready within ReadinessTimeout or before ctx is done.
+func (w *Warmup) WaitForReadiness(ctx context.Context) error {
+	if w.ReadinessTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.ReadinessTimeout)
+		defer cancel()
+	}
+	interval := w.ReadinessPollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if w.ReadinessTimeout > 0 {
+		log.Printf("Waiting for %s target to be ready for a max of %v", w.Target.options.ReadinessProtocol, w.ReadinessTimeout)
+	} else {
+		log.Printf("Waiting for %s target to be ready with no time limit", w.Target.options.ReadinessProtocol)
+	}
+	err := w.Target.waitForReadiness(ctx, w.HttpHeaders, interval)
+	if errors.Is(err, context.DeadlineExceeded) && w.ReadinessTimeout > 0 {
+		return fmt.Errorf("giving up; target not ready after %v", w.ReadinessTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("giving up; target not ready: %v", err)
+	}
+	return nil
+}
+
+// CloseConnections closes the idle HTTP connections and the gRPC connections to the target.
+// They are established again the next time the warmup runs.
+func (w *Warmup) CloseConnections() {
+	w.Target.httpClient.CloseIdleConnections()
+	for _, client := range w.Target.grpcClients() {
+		if client.Connected() {
+			if err := client.Close(); err != nil {
+				log.Printf("gRPC client close error: %v", err)
 			}
 		}
 	}
+}
+
+// KeepAlive sends the ping request to the gRPC target every interval until ctx is done, to keep the connection and the state
+// of the target warm between warmup cycles. The pings are not counted in the summary. It returns the number of pings sent
+// and how many of them failed.
+func (w *Warmup) KeepAlive(ctx context.Context, ping grpc.Request, interval time.Duration) (int, int) {
+	var sent, failed int
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return sent, failed
+		case <-ticker.C:
+		}
 
-	wg.Wait()
+		headers := w.withGrpcMetadata(w.HttpHeaders)
+		if !w.Target.grpcClient.Connected() {
+			if err := w.Target.grpcClient.Connect(headers); err != nil {
+				log.Printf("%s gRPC keep-alive connect error: %v", marker.Failure(), err)
+				sent++
+				failed++
+				continue
+			}
+		}
+		resp := w.Target.grpcClient.SendRequest(ping.ServiceMethod, ping.Message, headers, false)
+		sent++
+		if resp.Err != nil {
+			log.Printf("%s gRPC keep-alive ping %s failed: %v", marker.Failure(), ping.ServiceMethod, resp.Err)
+			failed++
+		}
+	}
+}
+
+// stopped returns true once the warmup is over, so that the workers do not start new requests.
+func (w Warmup) stopped() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d and returns true, or returns false as soon as the warmup is over.
+func (w Warmup) sleep(d time.Duration) bool {
+	if d <= 0 {
+		return !w.stopped()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+		return false
+	case <-timer.C:
+		return true
+	}
 }
 
 // HTTPWarmupWorker sends HTTP requests to the target using goroutines.
-func (w Warmup) HTTPWarmupWorker(wg *sync.WaitGroup, requests <-chan http.Request, headers []string, requestDelayMilliseconds int, requestsSentCounter *int) {
+// It stops as soon as the warmup is over, once its request in flight completes.
+// If the target answers with a 429 or 503 and a Retry-After header the worker pauses for the time requested.
+func (w Warmup) HTTPWarmupWorker(wg *sync.WaitGroup, requests <-chan http.Request, headers []string, worker *placeholders.Worker, requestDelayMilliseconds int, requestsSentCounter *int64) {
+	w.Metrics.AddWorkers(1)
+	workerRateLimiter := ratelimit.New(w.PerWorkerRequestsPerSecond)
+	random := w.newRand()
 	for request := range requests {
-		time.Sleep(time.Duration(requestDelayMilliseconds) * time.Millisecond)
+		for i := 0; i < burst(request.Burst) && !w.stopped(); i++ {
+			w.waitForRateLimits(workerRateLimiter)
+			resp := w.sendHTTPRequest(request, headers, worker, requestsSentCounter)
+			if !w.waitForRetryAfter(resp, request.Method, request.Path) {
+				break
+			}
+		}
+		if !w.sleep(w.requestDelay(requestDelayMilliseconds, random)) {
+			break
+		}
+	}
+	w.Metrics.AddWorkers(-1)
+	wg.Done()
+}
+
+// SendHTTP sends a single HTTP request synchronously, the way the workers do, and returns its response.
+// The request is neither rate limited nor counted in the summary of the warmup, and is retried if Retries is set.
+func (w Warmup) SendHTTP(request http.Request) response.Response {
+	w.summary, w.done = nil, nil
+	var requestsSent int64
+	return w.sendHTTPRequest(request, w.HttpHeaders, nil, &requestsSent)
+}
 
-		resp := w.Target.httpClient.SendRequest(request.Method, request.Path, headers, request.Body)
+// SendGrpc sends a single gRPC request synchronously, the way the workers do, and returns its response.
+// The gRPC warmup client of the target is connected first if needed. The request is neither rate limited nor counted
+// in the summary of the warmup, and is retried if Retries is set.
+func (w *Warmup) SendGrpc(request grpc.Request) response.Response {
+	if !w.Target.grpcClient.Connected() {
+		if err := w.Target.grpcClient.Connect(w.withGrpcMetadata(w.HttpHeaders)); err != nil {
+			return response.Response{Err: fmt.Errorf("gRPC client connect error: %v", err), Type: "grpc"}
+		}
+	}
+	single := *w
+	single.summary, single.done, single.grpcTargets = nil, nil, nil
+	var requestsSent int64
+	return single.sendGrpcRequest(request, w.HttpHeaders, nil, &requestsSent)
+}
 
-		if resp.Err != nil {
-			log.Printf("🔴 Error in request for %s: %v", request.Path, resp.Err)
+func (w Warmup) sendHTTPRequest(request http.Request, workerHeaders []string, worker *placeholders.Worker, requestsSentCounter *int64) response.Response {
+	// the endpoint is the request as configured, before the worker placeholders are resolved
+	endpoint := httpEndpoint(request)
+	request, err := request.WithBodyFromFile()
+	if err != nil {
+		w.ResultLogger.Log(resultlog.Result{Protocol: "http", Method: request.Method, Path: request.Path, Error: err.Error(), Outcome: resultlog.Failure},
+			"%s %s %s not sent: %v", marker.Failure(), request.Method, request.Path, err)
+		w.summary.RecordFailure("http", endpoint, err.Error())
+		return response.Response{Err: err, Type: "http"}
+	}
+	request, workerHeaders = withWorkerPlaceholders(request, workerHeaders, worker)
+	request = request.WithQuery()
+	if request.Conditional {
+		// the client would interpolate the placeholders again for the conditional copy, which must be sent to the same resource
+		request = withPlaceholders(request)
+	}
+	headers, correlationID := w.withCorrelationID(request.WithContentType(workerHeaders))
+	client := w.httpClient(request)
+	resp := w.withRetries("http", endpoint, func() response.Response {
+		if request.Golden != nil {
+			return client.SendRequestCapturingBody(request.Method, request.Path, headers, request.Body, golden.MaxBodyBytes)
+		}
+		if request.ExpectedBodySubstring != "" {
+			return client.SendRequestCapturingBody(request.Method, request.Path, headers, request.Body, w.MaxBodyBytes)
+		}
+		return client.SendRequest(request.Method, request.Path, headers, request.Body)
+	}, func(resp response.Response) bool {
+		return retryableHTTP(resp) && !request.AcceptedStatusCodes.Accepts(resp.StatusCode)
+	})
+	w.record(recording.Entry{Protocol: "http", Method: request.Method, Path: request.Path, Body: request.Body, ContentType: request.ContentType}, resp)
+
+	var ok bool
+	if resp.TimedOut {
+		w.ResultLogger.Log(httpResult(request, resp, resultlog.Failure, ""),
+			"%s Request for %s timed out after %d ms: %v%s", marker.Failure(), request.Path, resp.Duration/time.Millisecond, resp.Err, correlationID)
+		w.summary.RecordFailure("http", endpoint, "timed out")
+	} else if resp.Err != nil {
+		w.ResultLogger.Log(httpResult(request, resp, resultlog.Failure, ""),
+			"%s Error in request for %s: %v%s", marker.Failure(), request.Path, resp.Err, correlationID)
+		w.summary.RecordFailure("http", endpoint, resp.Err.Error())
+	} else {
+		atomic.AddInt64(requestsSentCounter, 1)
+		w.summary.RecordLatency("http", endpoint, resp.Duration)
+		if resp.Hedged {
+			w.summary.RecordHedged("http", endpoint)
+		}
+		for _, name := range w.CaptureHeaders {
+			w.summary.RecordHeader("http", endpoint, name, resp.Header(name))
+		}
+		var failure string
+		if !request.AcceptedStatusCodes.Accepts(resp.StatusCode) {
+			failure = fmt.Sprintf("status code %d", resp.StatusCode)
+		} else if failure = w.goldenMismatch(request.Golden, resp, endpoint); failure == "" {
+			failure = unexpectedBody(request.ExpectedBodySubstring, resp, endpoint)
+		}
+		ok = failure == ""
+		if ok {
+			w.summary.Record("http", endpoint, true)
 		} else {
-			*requestsSentCounter++
+			w.summary.RecordFailure("http", endpoint, failure)
+		}
 
-			if resp.StatusCode/100 == 2 {
-				log.Printf("🟢 %s response\t%d ms\t%v\t%s\t%s", resp.Type, resp.Duration/time.Millisecond, resp.StatusCode, request.Method, request.Path)
-			} else {
-				log.Printf("🔴 %s response\t%d ms\t%v\t%s\t%s", resp.Type, resp.Duration/time.Millisecond, resp.StatusCode, request.Method, request.Path)
-			}
+		slow := w.isSlow(resp, "http", endpoint)
+		if ok && w.exceedsMaxLatency(request.MaxLatency, resp, "http", endpoint) {
+			w.ResultLogger.Log(httpResult(request, resp, resultlog.Warning, ""),
+				"%s %s response\t%d ms\t%v\t%s\t%s%s\texceeded max latency of %v", marker.Warning(), resp.Type, resp.Duration/time.Millisecond, resp.StatusCode, request.Method, request.Path, correlationID, request.MaxLatency)
+		} else if ok && slow {
+			w.ResultLogger.Log(httpResult(request, resp, resultlog.Slow, ""),
+				"%s %s response\t%d ms\t%v\t%s\t%s%s\tslower than %d ms", marker.Slow(), resp.Type, resp.Duration/time.Millisecond, resp.StatusCode, request.Method, request.Path, correlationID, w.SlowRequestThresholdMilliseconds)
+		} else if ok {
+			w.ResultLogger.Log(httpResult(request, resp, resultlog.Success, ""),
+				"%s %s response\t%d ms\t%v\t%s\t%s%s", marker.Success(), resp.Type, resp.Duration/time.Millisecond, resp.StatusCode, request.Method, request.Path, correlationID)
+		} else {
+			w.ResultLogger.Log(httpResult(request, resp, resultlog.Failure, failure),
+				"%s %s response\t%d ms\t%v\t%s\t%s%s", marker.Failure(), resp.Type, resp.Duration/time.Millisecond, resp.StatusCode, request.Method, request.Path, correlationID)
+		}
+
+		if ok && request.Conditional {
+			w.sendConditionalHTTPRequest(request, endpoint+conditionalSuffix, workerHeaders, resp.Header("ETag"), requestsSentCounter)
 		}
 	}
-	wg.Done()
+	w.Metrics.RecordRequest("http", resp.Duration, ok)
+	return resp
+}
+
+// httpClient returns the client a request is sent with, which honors the timeout of the request if it has one.
+func (w Warmup) httpClient(request http.Request) http.Client {
+	client := w.Target.httpClient
+	if request.Timeout > 0 {
+		client = client.WithTimeout(request.Timeout)
+	}
+	if request.Gzip {
+		client = client.WithGzip()
+	}
+	return client
+}
+
+// sendConditionalHTTPRequest sends the request again with If-None-Match set to the ETag of its previous response,
+// which succeeds only if the target returns 304 Not Modified. It is recorded in the summary as a separate endpoint.
+func (w Warmup) sendConditionalHTTPRequest(request http.Request, endpoint string, workerHeaders []string, etag string, requestsSentCounter *int64) {
+	if etag == "" {
+		w.ResultLogger.Log(resultlog.Result{Protocol: "http", Method: request.Method, Path: request.Path, Error: "no ETag in the response", Outcome: resultlog.Failure},
+			"%s No ETag in the response of %s %s, cannot send a conditional request", marker.Failure(), request.Method, request.Path)
+		w.summary.RecordFailure("http", endpoint, "no ETag in the response")
+		return
+	}
+	// copy the headers as they are shared by all the requests of the worker
+	headers := append(append([]string{}, request.WithContentType(workerHeaders)...), "If-None-Match: "+etag)
+	headers, correlationID := w.withCorrelationID(headers)
+	resp := w.httpClient(request).SendRequest(request.Method, request.Path, headers, request.Body)
+	w.record(recording.Entry{Protocol: "http", Method: request.Method, Path: request.Path, Body: request.Body, ContentType: request.ContentType}, resp)
+
+	w.Metrics.RecordRequest("http", resp.Duration, resp.Err == nil && resp.StatusCode == 304)
+
+	if resp.Err != nil {
+		w.ResultLogger.Log(httpResult(request, resp, resultlog.Failure, ""),
+			"%s Error in conditional request for %s: %v%s", marker.Failure(), request.Path, resp.Err, correlationID)
+		w.summary.RecordFailure("http", endpoint, resp.Err.Error())
+		return
+	}
+	atomic.AddInt64(requestsSentCounter, 1)
+	w.summary.RecordLatency("http", endpoint, resp.Duration)
+	if resp.StatusCode == 304 {
+		w.summary.Record("http", endpoint, true)
+		w.ResultLogger.Log(httpResult(request, resp, resultlog.Success, ""),
+			"%s %s response\t%d ms\t%v\t%s\t%s\tIf-None-Match=%s%s", marker.Success(), resp.Type, resp.Duration/time.Millisecond, resp.StatusCode, request.Method, request.Path, etag, correlationID)
+	} else {
+		failure := fmt.Sprintf("status code %d, expected 304", resp.StatusCode)
+		w.summary.RecordFailure("http", endpoint, failure)
+		w.ResultLogger.Log(httpResult(request, resp, resultlog.Failure, failure),
+			"%s %s response\t%d ms\t%v\t%s\t%s\tIf-None-Match=%s%s", marker.Failure(), resp.Type, resp.Duration/time.Millisecond, resp.StatusCode, request.Method, request.Path, etag, correlationID)
+	}
 }
 
 // GrpcWarmupWorker sends gRPC requests to the target using goroutines.
-func (w Warmup) GrpcWarmupWorker(wg *sync.WaitGroup, requests <-chan grpc.Request, headers []string, requestDelayMilliseconds int, requestsSentCounter *int) {
+// It stops as soon as the warmup is over, once its request in flight completes.
+func (w Warmup) GrpcWarmupWorker(wg *sync.WaitGroup, requests <-chan grpc.Request, headers []string, worker *placeholders.Worker, requestDelayMilliseconds int, requestsSentCounter *int64) {
+	w.Metrics.AddWorkers(1)
+	workerRateLimiter := ratelimit.New(w.PerWorkerRequestsPerSecond)
+	random := w.newRand()
 	for request := range requests {
-		time.Sleep(time.Duration(requestDelayMilliseconds) * time.Millisecond)
+		for i := 0; i < burst(request.Burst) && !w.stopped(); i++ {
+			w.waitForRateLimits(workerRateLimiter)
+			w.sendGrpcRequest(request, headers, worker, requestsSentCounter)
+		}
+		if !w.sleep(w.requestDelay(requestDelayMilliseconds, random)) {
+			break
+		}
+	}
+	w.Metrics.AddWorkers(-1)
+	wg.Done()
+}
 
-		resp := w.Target.grpcClient.SendRequest(request.ServiceMethod, request.Message, headers, false)
+func (w Warmup) sendGrpcRequest(request grpc.Request, headers []string, worker *placeholders.Worker, requestsSentCounter *int64) response.Response {
+	request.Message = worker.Interpolate(request.Message)
+	headers, correlationID := w.withCorrelationID(grpc.MergeMetadata(w.withGrpcMetadata(worker.InterpolateAll(headers)), worker.InterpolateAll(request.Headers)))
+	client := w.nextGrpcClient()
+	resp := w.withRetries("grpc", request.ServiceMethod, func() response.Response {
+		if request.Golden != nil {
+			return client.SendRequestCapturingBody(request.ServiceMethod, request.Message, headers, golden.MaxBodyBytes)
+		}
+		return client.SendRequest(request.ServiceMethod, request.Message, headers, false)
+	}, retryableGrpc)
+	w.record(recording.Entry{Protocol: "grpc", ServiceMethod: request.ServiceMethod, Message: request.Message}, resp)
 
-		if resp.Err != nil {
-			log.Printf("🔴 Error in request for %s: %v", request.ServiceMethod, resp.Err)
+	var ok bool
+	if resp.Err != nil {
+		w.ResultLogger.Log(grpcResult(request, resp, resultlog.Failure, ""),
+			"%s Error in request for %s: %v%s", marker.Failure(), request.ServiceMethod, resp.Err, correlationID)
+		w.summary.RecordFailure("grpc", request.ServiceMethod, resp.Err.Error())
+	} else {
+		atomic.AddInt64(requestsSentCounter, 1)
+		w.summary.RecordLatency("grpc", request.ServiceMethod, resp.Duration)
+		var failure string
+		if resp.GrpcStatus != codes.OK {
+			failure = fmt.Sprintf("status %s", resp.GrpcStatus)
 		} else {
-			*requestsSentCounter++
-			log.Printf("🟢 %s response\t%d ms %s", resp.Type, resp.Duration/time.Millisecond, request.ServiceMethod)
+			failure = w.goldenMismatch(request.Golden, resp, request.ServiceMethod)
+		}
+		ok = failure == ""
+		if ok {
+			w.summary.Record("grpc", request.ServiceMethod, true)
+		} else {
+			w.summary.RecordFailure("grpc", request.ServiceMethod, failure)
 		}
 
+		slow := w.isSlow(resp, "grpc", request.ServiceMethod)
+		if ok && w.exceedsMaxLatency(request.MaxLatency, resp, "grpc", request.ServiceMethod) {
+			w.ResultLogger.Log(grpcResult(request, resp, resultlog.Warning, ""),
+				"%s %s response\t%d ms\t%v\t%s%s\texceeded max latency of %v", marker.Warning(), resp.Type, resp.Duration/time.Millisecond, resp.GrpcStatus, request.ServiceMethod, correlationID, request.MaxLatency)
+		} else if ok && slow {
+			w.ResultLogger.Log(grpcResult(request, resp, resultlog.Slow, ""),
+				"%s %s response\t%d ms\t%v\t%s%s\tslower than %d ms", marker.Slow(), resp.Type, resp.Duration/time.Millisecond, resp.GrpcStatus, request.ServiceMethod, correlationID, w.SlowRequestThresholdMilliseconds)
+		} else if ok {
+			w.ResultLogger.Log(grpcResult(request, resp, resultlog.Success, ""),
+				"%s %s response\t%d ms\t%v\t%s%s", marker.Success(), resp.Type, resp.Duration/time.Millisecond, resp.GrpcStatus, request.ServiceMethod, correlationID)
+		} else {
+			w.ResultLogger.Log(grpcResult(request, resp, resultlog.Failure, failure),
+				"%s %s response\t%d ms\t%v\t%s%s", marker.Failure(), resp.Type, resp.Duration/time.Millisecond, resp.GrpcStatus, request.ServiceMethod, correlationID)
+		}
 	}
-	wg.Done()
+	w.Metrics.RecordRequest("grpc", resp.Duration, ok)
+	return resp
+}
+
+// httpResult returns the result of an HTTP request for the result logger. failure, if set, is the reason why a response failed.
+func httpResult(request http.Request, resp response.Response, outcome string, failure string) resultlog.Result {
+	result := resultlog.Result{Protocol: "http", Method: request.Method, Path: request.Path, Outcome: outcome}
+	withResponse(&result, resp, failure)
+	if resp.Err == nil {
+		result.Status = strconv.Itoa(resp.StatusCode)
+	}
+	return result
+}
+
+// grpcResult returns the result of a gRPC request for the result logger. failure, if set, is the reason why a response failed.
+func grpcResult(request grpc.Request, resp response.Response, outcome string, failure string) resultlog.Result {
+	result := resultlog.Result{Protocol: "grpc", ServiceMethod: request.ServiceMethod, Outcome: outcome}
+	withResponse(&result, resp, failure)
+	if resp.Err == nil {
+		result.Status = resp.GrpcStatus.String()
+	}
+	return result
+}
+
+func withResponse(result *resultlog.Result, resp response.Response, failure string) {
+	result.DurationMs = int64(resp.Duration / time.Millisecond)
+	result.Attempt = resp.Attempts
+	if result.Attempt == 0 {
+		result.Attempt = 1
+	}
+	result.Error = failure
+	if resp.Err != nil {
+		result.Error = resp.Err.Error()
+	}
+}
+
+// record records a request along with a summary of its response, if recording is enabled.
+func (w Warmup) record(entry recording.Entry, resp response.Response) {
+	if w.Recorder == nil {
+		return
+	}
+	entry.Time = time.Now()
+	entry.StatusCode = resp.StatusCode
+	entry.DurationMs = int64(resp.Duration / time.Millisecond)
+	if resp.Err != nil {
+		entry.Error = resp.Err.Error()
+	}
+	if err := w.Recorder.Record(entry); err != nil {
+		log.Printf("Cannot record request: %v", err)
+	}
+}
+
+// withPlaceholders returns the request with the placeholders of its path and body interpolated.
+func withPlaceholders(request http.Request) http.Request {
+	request.Path = placeholders.InterpolatePlaceholders(request.Path)
+	if request.Body != nil {
+		body := placeholders.InterpolatePlaceholders(*request.Body)
+		request.Body = &body
+	}
+	return request
+}
+
+// withWorkerPlaceholders returns the request and the headers with the worker placeholders resolved
+// and, if the request has a body schema, with a new body generated from the random stream of the worker.
+func withWorkerPlaceholders(request http.Request, headers []string, worker *placeholders.Worker) (http.Request, []string) {
+	request.Path = worker.Interpolate(request.Path)
+	if len(request.Query) > 0 {
+		query := make(map[string]string, len(request.Query))
+		for name, value := range request.Query {
+			query[name] = worker.Interpolate(value)
+		}
+		request.Query = query
+	}
+	if request.BodySchema != nil {
+		body := request.BodySchema.Generate(worker.Rand())
+		request.Body = &body
+	} else if request.Body != nil {
+		body := worker.Interpolate(*request.Body)
+		request.Body = &body
+	}
+	return request, worker.InterpolateAll(headers)
+}
+
+// withGrpcMetadata returns the headers plus the gRPC metadata.
+func (w Warmup) withGrpcMetadata(headers []string) []string {
+	if len(w.GrpcMetadata) == 0 {
+		return headers
+	}
+	// copy the headers as they are shared by all the requests of the worker
+	return append(append([]string{}, headers...), w.GrpcMetadata...)
+}
+
+// withCorrelationID returns the headers plus a new correlation id, if a correlation header is configured,
+// and the suffix that identifies the request in the log lines of its result.
+func (w Warmup) withCorrelationID(headers []string) ([]string, string) {
+	if w.CorrelationHeader == "" {
+		return headers, ""
+	}
+	id := placeholders.UUID()
+	// copy the headers as they are shared by all the requests of the worker
+	withID := append(append([]string{}, headers...), fmt.Sprintf("%s: %s", w.CorrelationHeader, id))
+	return withID, fmt.Sprintf("\t%s=%s", w.CorrelationHeader, id)
+}
+
+// exceedsMaxLatency checks a successful response against the max latency of its request, if any, and records the outcome in the summary.
+// It returns true if the response was slower than the max latency.
+func (w Warmup) exceedsMaxLatency(maxLatency time.Duration, resp response.Response, protocol string, endpoint string) bool {
+	if maxLatency <= 0 {
+		return false
+	}
+	violated := resp.Duration > maxLatency
+	w.summary.RecordLatencyCheck(protocol, endpoint, violated)
+	return violated
+}
+
+// isSlow returns true if a request that got a response took longer than the slow request threshold, if any,
+// and records it in the summary.
+func (w Warmup) isSlow(resp response.Response, protocol string, endpoint string) bool {
+	if w.SlowRequestThresholdMilliseconds <= 0 || resp.Duration <= time.Duration(w.SlowRequestThresholdMilliseconds)*time.Millisecond {
+		return false
+	}
+	w.summary.RecordSlow(protocol, endpoint, resp.Duration)
+	return true
+}
+
+// goldenMismatch returns an empty string if the request has no golden file or if the captured response body matches it,
+// and the reason of the mismatch otherwise.
+func (w Warmup) goldenMismatch(goldenFile *golden.File, resp response.Response, endpoint string) string {
+	if goldenFile == nil {
+		return ""
+	}
+	diff := goldenFile.Compare(resp.Body, resp.BodyTruncated, w.GoldenNormalizeJSON)
+	if diff == "" {
+		return ""
+	}
+	if w.GoldenPrintDiff {
+		log.Printf("Response of %s does not match golden file %s, %s", endpoint, goldenFile.Path, diff)
+	} else {
+		log.Printf("Response of %s does not match golden file %s", endpoint, goldenFile.Path)
+	}
+	return fmt.Sprintf("response does not match golden file %s", goldenFile.Path)
+}
+
+// waitForRateLimits blocks until both the worker's own rate limit and the global one allow a new request.
+// The worker limit is waited for first so that a worker never holds a global slot while it is throttled.
+func (w Warmup) waitForRateLimits(workerRateLimiter *ratelimit.Limiter) {
+	workerRateLimiter.Wait()
+	w.rateLimiter.Wait()
+}
+
+// httpWeights returns the weights of the HTTP requests, in the same order.
+func httpWeights(requests []http.Request) []int {
+	weights := make([]int, len(requests))
+	for i, request := range requests {
+		weights[i] = request.Weight
+	}
+	return weights
 }
 
-func waitForRampUp(rampUpInterval int, currentConcurrency int) {
-	if currentConcurrency > 1 && rampUpInterval > 0 {
-		time.Sleep(time.Duration(rampUpInterval) * time.Second)
+// grpcWeights returns the weights of the gRPC requests, in the same order.
+func grpcWeights(requests []grpc.Request) []int {
+	weights := make([]int, len(requests))
+	for i, request := range requests {
+		weights[i] = request.Weight
+	}
+	return weights
+}
+
+// burst returns the number of times a request is sent every time it is selected. Requests that were not parsed from a flag, e.g. discovered gRPC methods, are sent once.
+func burst(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// unexpectedBody returns why the body of a response does not contain the expected substring, or "" if it does or nothing is expected.
+func unexpectedBody(expected string, resp response.Response, endpoint string) string {
+	if expected == "" || strings.Contains(string(resp.Body), expected) {
+		return ""
+	}
+	failure := fmt.Sprintf("body of %d bytes does not contain %q", resp.BodySize, expected)
+	if resp.BodyTruncated {
+		failure = fmt.Sprintf("the first %d bytes of the body of %d bytes do not contain %q", len(resp.Body), resp.BodySize, expected)
+	}
+	log.Printf("Response of %s: %s", endpoint, failure)
+	return failure
+}
+
+// withKnownGrpcMethods checks that the configured gRPC methods can be resolved via the descriptor source.
+// Unknown methods are dropped with a warning, unless FailOnUnknownGrpcMethods is set, in which case the failure is
+// added to the summary and false is returned so that no request is sent at all.
+func (w *Warmup) withKnownGrpcMethods() bool {
+	var methods []string
+	for _, request := range w.GrpcRequests {
+		methods = append(methods, request.ServiceMethod)
+	}
+	unknown := w.descriptorGrpcClient().UnknownMethods(methods)
+	if len(unknown) == 0 {
+		return true
+	}
+
+	if w.FailOnUnknownGrpcMethods {
+		log.Printf("%s Unknown gRPC methods %v, no warmup requests will be sent", marker.Failure(), unknown)
+		w.summary.AddPreflightError(fmt.Sprintf("unknown gRPC methods %v", unknown))
+		return false
+	}
+
+	log.Printf("%s Unknown gRPC methods %v will not be warmed up", marker.Warning(), unknown)
+	isUnknown := make(map[string]bool)
+	for _, method := range unknown {
+		isUnknown[method] = true
+	}
+	var known []grpc.Request
+	for _, request := range w.GrpcRequests {
+		if !isUnknown[request.ServiceMethod] {
+			known = append(known, request)
+		}
+	}
+	w.GrpcRequests = known
+	return true
+}
+
+// withDiscoveredGrpcRequests returns the configured gRPC requests plus a request for every method discovered via the descriptor source.
+// Discovered methods are sent a default message generated from their descriptor.
+// Configured requests take precedence over discovered ones for the same method, which allows overriding the default message.
+func (w Warmup) withDiscoveredGrpcRequests() []grpc.Request {
+	client := w.descriptorGrpcClient()
+	methods, err := client.DiscoverMethods(w.GrpcServiceFilters)
+	if err != nil {
+		log.Printf("gRPC methods discovery error: %v", err)
+		return w.GrpcRequests
+	}
+
+	configured := make(map[string]bool)
+	for _, request := range w.GrpcRequests {
+		configured[request.ServiceMethod] = true
+	}
+
+	requests := w.GrpcRequests
+	for _, method := range methods {
+		if configured[method] {
+			continue
+		}
+		message, err := client.DefaultMessage(method)
+		if err != nil {
+			log.Printf("Cannot generate default message for %s, sending an empty one: %v", method, err)
+		}
+		requests = append(requests, grpc.Request{ServiceMethod: method, Message: message})
+	}
+	log.Printf("Discovered %d gRPC method(s) to warm up", len(requests)-len(w.GrpcRequests))
+	return requests
+}
+
+// httpEndpoint returns the name under which the results of an HTTP request are summarised.
+func httpEndpoint(request http.Request) string {
+	return request.Method + " " + request.Path
+}
+
+// minBreachSamples is the number of latencies needed in the window before its p99 latency is checked against the ceiling,
+// so that a few slow requests do not abort the warmup on their own.
+const minBreachSamples = 20
+
+// conditionalSuffix is appended to the endpoint of a request to name the endpoint of its conditional copy.
+const conditionalSuffix = " If-None-Match"
+
+// conditionalHTTPEndpoint returns the endpoint of the conditional copy of a request in the summary.
+func conditionalHTTPEndpoint(request http.Request) string {
+	return httpEndpoint(request) + conditionalSuffix
+}
+
+// rampDown mirrors the ramp-up: from start on it stops a worker of every pool at regular intervals
+// over rampDownSeconds so that a single worker per pool is left at the end. It starts from the concurrency
+// of the control at that time, if any, and returns early once ctx is done.
+func rampDown(ctx context.Context, pools []*workerPool, control *ConcurrencyControl, concurrency int, start time.Time, rampDownSeconds int) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(time.Until(start)):
+	}
+	concurrency = control.current(concurrency)
+	if concurrency <= 1 {
+		return
+	}
+	interval := time.Duration(rampDownSeconds) * time.Second / time.Duration(concurrency)
+	next := start
+	for n := concurrency - 1; n >= 1; n-- {
+		next = next.Add(interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		log.Printf("Ramping down to %d worker(s)", n)
+		for _, pool := range pools {
+			control.shrink(pool, n)
+		}
+	}
+}
+
+// requestDelay returns the delay of a worker before its next request, randomized by up to DelayJitterMilliseconds either way.
+func (w Warmup) requestDelay(requestDelayMilliseconds int, random *rand.Rand) time.Duration {
+	delay := requestDelayMilliseconds
+	if w.DelayJitterMilliseconds > 0 {
+		delay += random.Intn(2*w.DelayJitterMilliseconds+1) - w.DelayJitterMilliseconds
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay) * time.Millisecond
+}
+
+// waitForRampUp waits before starting another worker and returns true, or returns false once the warmup is over
+// or the request budget is spent.
+func (w Warmup) waitForRampUp(delay time.Duration) bool {
+	if delay <= 0 {
+		select {
+		case <-w.budget.done():
+			return false
+		default:
+			return !w.stopped()
+		}
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+		return false
+	case <-w.budget.done():
+		return false
+	case <-timer.C:
+		return true
 	}
 }
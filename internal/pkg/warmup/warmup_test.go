@@ -0,0 +1,755 @@
+//Copyright 2022 Expedia, Inc.
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing, software
+//distributed under the License is distributed on an "AS IS" BASIS,
+//WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//See the License for the specific language governing permissions and
+//limitations under the License.
+
+package warmup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"mittens/fixture"
+	"mittens/internal/pkg/grpc"
+	"mittens/internal/pkg/http"
+	"mittens/internal/pkg/jsonschema"
+	"mittens/internal/pkg/metrics"
+	"mittens/internal/pkg/placeholders"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHTTPClient returns an HTTP client of the host, which must be valid.
+func newHTTPClient(t *testing.T, host string, insecure bool, options http.ClientOptions) http.Client {
+	t.Helper()
+	client, err := http.NewClient(host, insecure, options)
+	require.NoError(t, err)
+	return client
+}
+
+// newHTTPWarmup returns a warmup whose target is a test server serving handler, closed once the test is over,
+// and the counter of the requests it sends.
+func newHTTPWarmup(t *testing.T, handler func(nethttp.ResponseWriter, *nethttp.Request)) (*Warmup, *int64) {
+	t.Helper()
+	server := httptest.NewServer(nethttp.HandlerFunc(handler))
+	t.Cleanup(server.Close)
+	client := newHTTPClient(t, server.URL, false, http.ClientOptions{})
+	return &Warmup{Target: NewTarget(client, grpc.Client{}, client, grpc.Client{}, TargetOptions{}), summary: NewSummary()}, new(int64)
+}
+
+// newGrpcClient returns a gRPC client of the host, which must be valid.
+func newGrpcClient(t *testing.T, host string, insecure bool, options grpc.ClientOptions) grpc.Client {
+	t.Helper()
+	client, err := grpc.NewClient(host, insecure, options)
+	require.NoError(t, err)
+	return client
+}
+
+func TestWithCorrelationID_AddsHeaderAndLogSuffix(t *testing.T) {
+	w := Warmup{CorrelationHeader: "X-Request-Id"}
+	headers := []string{"Accept: */*"}
+
+	withID, suffix := w.withCorrelationID(headers)
+
+	require.Len(t, withID, 2)
+	assert.Equal(t, []string{"Accept: */*"}, headers, "the shared headers must not be modified")
+	id := strings.TrimPrefix(withID[1], "X-Request-Id: ")
+	assert.Len(t, id, 36)
+	assert.Equal(t, "\tX-Request-Id="+id, suffix)
+
+	_, otherSuffix := w.withCorrelationID(headers)
+	assert.NotEqual(t, suffix, otherSuffix)
+}
+
+func TestWithCorrelationID_Disabled(t *testing.T) {
+	headers := []string{"Accept: */*"}
+
+	withID, suffix := Warmup{}.withCorrelationID(headers)
+
+	assert.Equal(t, headers, withID)
+	assert.Empty(t, suffix)
+}
+
+func TestSendHTTPRequest_Conditional(t *testing.T) {
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		switch {
+		case r.URL.Path == "/no-etag":
+			rw.WriteHeader(nethttp.StatusOK)
+		case r.Header.Get("If-None-Match") == `"v1"`:
+			rw.WriteHeader(nethttp.StatusNotModified)
+		default:
+			rw.Header().Set("ETag", `"v1"`)
+			rw.WriteHeader(nethttp.StatusOK)
+		}
+	})
+
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/logo.png", Conditional: true}, []string{}, nil, requestsSent)
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/no-etag", Conditional: true}, []string{}, nil, requestsSent)
+
+	assert.Equal(t, int64(3), *requestsSent)
+	endpoints := w.summary.Endpoints()
+	require.Len(t, endpoints, 4)
+	assert.Equal(t, "GET /logo.png If-None-Match", endpoints[1].Endpoint)
+	assert.Equal(t, 1, endpoints[1].Successes)
+	assert.Equal(t, "GET /no-etag If-None-Match", endpoints[3].Endpoint)
+	assert.Equal(t, "no ETag in the response", endpoints[3].LastFailure)
+}
+
+func TestSendHTTPRequest_ExpectedBodySubstring(t *testing.T) {
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		rw.Write([]byte(`{"status":"UP","checks":[]}`))
+	})
+	w.MaxBodyBytes = 1024
+
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/up", ExpectedBodySubstring: `"status":"UP"`}, []string{}, nil, requestsSent)
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/down", ExpectedBodySubstring: `"status":"DOWN"`}, []string{}, nil, requestsSent)
+	w.MaxBodyBytes = 5
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/truncated", ExpectedBodySubstring: `"status":"UP"`}, []string{}, nil, requestsSent)
+
+	endpoints := w.summary.Endpoints()
+	require.Len(t, endpoints, 3)
+	assert.Equal(t, 1, endpoints[0].Successes)
+	assert.Equal(t, 1, endpoints[1].Failures)
+	assert.Equal(t, `body of 27 bytes does not contain "\"status\":\"DOWN\""`, endpoints[1].LastFailure)
+	assert.Equal(t, `the first 5 bytes of the body of 27 bytes do not contain "\"status\":\"UP\""`, endpoints[2].LastFailure)
+}
+
+func TestSendHTTPRequest_AcceptedStatusCodes(t *testing.T) {
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		rw.WriteHeader(nethttp.StatusNotFound)
+	})
+	accept404, err := http.ParseStatusCodes("404")
+	require.NoError(t, err)
+
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/accepted", AcceptedStatusCodes: accept404}, []string{}, nil, requestsSent)
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/default"}, []string{}, nil, requestsSent)
+
+	endpoints := w.summary.Endpoints()
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, 1, endpoints[0].Successes)
+	assert.Equal(t, 1, endpoints[1].Failures)
+	assert.Equal(t, "status code 404", endpoints[1].LastFailure)
+}
+
+func TestSendHTTPRequest_Gzip(t *testing.T) {
+	var encodings []string
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+	})
+
+	body := `{"id": 1}`
+	w.sendHTTPRequest(http.Request{Method: "POST", Path: "/compressed", Body: &body, Gzip: true}, []string{}, nil, requestsSent)
+	w.sendHTTPRequest(http.Request{Method: "POST", Path: "/plain", Body: &body}, []string{}, nil, requestsSent)
+
+	assert.Equal(t, []string{"gzip", ""}, encodings)
+}
+
+func TestSendHTTPRequest_Query(t *testing.T) {
+	var queries []string
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		queries = append(queries, r.URL.RawQuery)
+	})
+
+	query := map[string]string{"q": "{$range|min=5,max=5} & more", "worker": "{$workerSeed}"}
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/search", Query: query}, []string{}, placeholders.NewWorker(7), requestsSent)
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/search?page=2", Query: map[string]string{"q": "a/b"}}, []string{}, nil, requestsSent)
+
+	assert.Equal(t, []string{"q=5+%26+more&worker=7", "page=2&q=a%2Fb"}, queries)
+	endpoints := w.summary.Endpoints()
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, "GET /search", endpoints[0].Endpoint)
+}
+
+func TestSendHTTPRequest_Slow(t *testing.T) {
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(80 * time.Millisecond)
+		}
+	})
+	w.SlowRequestThresholdMilliseconds = 50
+
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/fast"}, []string{}, nil, requestsSent)
+	w.sendHTTPRequest(http.Request{Method: "GET", Path: "/slow"}, []string{}, nil, requestsSent)
+
+	samples := w.summary.SlowSamples()
+	require.Len(t, samples, 1)
+	assert.Equal(t, "http", samples[0].Protocol)
+	assert.Equal(t, "GET /slow", samples[0].Endpoint)
+	assert.GreaterOrEqual(t, samples[0].Duration, 80*time.Millisecond)
+}
+
+func TestSendHTTP(t *testing.T) {
+	var header string
+	w, _ := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		header = r.Header.Get("X-Warmup")
+		rw.WriteHeader(nethttp.StatusAccepted)
+	})
+	w.HttpHeaders = []string{"X-Warmup: true"}
+
+	resp := w.SendHTTP(http.Request{Method: "GET", Path: "/ping"})
+	require.NoError(t, resp.Err)
+	assert.Equal(t, nethttp.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "true", header)
+}
+
+func TestSendGrpc(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+	client := newGrpcClient(t, address, true, grpc.ClientOptions{})
+	w := Warmup{Target: NewTarget(http.Client{}, client, http.Client{}, client, TargetOptions{})}
+	defer w.Target.grpcClient.Close()
+
+	resp := w.SendGrpc(grpc.Request{ServiceMethod: "grpc.testing.TestService/EmptyCall"})
+	require.NoError(t, resp.Err)
+	assert.Equal(t, "OK", resp.GrpcStatus.String())
+	assert.True(t, w.Target.grpcClient.Connected())
+}
+
+func TestSendGrpcRequest_Status(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+	client := newGrpcClient(t, address, true, grpc.ClientOptions{})
+	require.NoError(t, client.Connect(nil))
+	defer client.Close()
+	w := Warmup{Target: NewTarget(http.Client{}, client, http.Client{}, client, TargetOptions{}), summary: NewSummary()}
+
+	var requestsSent int64
+	w.sendGrpcRequest(grpc.Request{ServiceMethod: "grpc.testing.TestService/EmptyCall"}, nil, nil, &requestsSent)
+	w.sendGrpcRequest(grpc.Request{ServiceMethod: "grpc.testing.TestService/UnaryCall"}, nil, nil, &requestsSent)
+
+	assert.Equal(t, int64(2), requestsSent)
+	endpoints := w.summary.Endpoints()
+	require.Len(t, endpoints, 2)
+	assert.Equal(t, 1, endpoints[0].Successes)
+	assert.Equal(t, 1, endpoints[1].Failures)
+	assert.Equal(t, "status Unimplemented", endpoints[1].LastFailure)
+}
+
+func TestGrpcWarmupWorker_SendsBeforeTheDelay(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+	client := newGrpcClient(t, address, true, grpc.ClientOptions{})
+	require.NoError(t, client.Connect(nil))
+	defer client.Close()
+	done := make(chan struct{})
+	w := Warmup{Target: NewTarget(http.Client{}, client, http.Client{}, client, TargetOptions{}), summary: NewSummary(), done: done}
+
+	requests := make(chan grpc.Request, 1)
+	requests <- grpc.Request{ServiceMethod: "grpc.testing.TestService/EmptyCall"}
+	close(requests)
+	time.AfterFunc(200*time.Millisecond, func() { close(done) })
+
+	var requestsSent int64
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	w.GrpcWarmupWorker(wg, requests, nil, nil, 10000, &requestsSent)
+
+	// the warmup ended during the delay that follows the request, so the request was sent
+	assert.Equal(t, int64(1), requestsSent)
+}
+
+func TestWaitForReadiness_HTTP(t *testing.T) {
+	var checks int32
+	w, _ := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		if atomic.AddInt32(&checks, 1) < 3 {
+			rw.WriteHeader(nethttp.StatusServiceUnavailable)
+		}
+	})
+	w.Target.options = TargetOptions{ReadinessProtocol: "http", ReadinessHTTPPath: "/ready"}
+	w.ReadinessTimeout = time.Second
+	w.ReadinessPollInterval = 10 * time.Millisecond
+
+	require.NoError(t, w.WaitForReadiness(context.Background()))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&checks))
+}
+
+func TestWaitForReadiness_Timeout(t *testing.T) {
+	w, _ := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		rw.WriteHeader(nethttp.StatusServiceUnavailable)
+	})
+	w.Target.options = TargetOptions{ReadinessProtocol: "http", ReadinessHTTPPath: "/ready"}
+	w.ReadinessTimeout = 100 * time.Millisecond
+	w.ReadinessPollInterval = 10 * time.Millisecond
+
+	start := time.Now()
+	err := w.WaitForReadiness(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "target not ready after 100ms")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWaitForReadiness_NoTimeout(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	w, _ := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		rw.WriteHeader(nethttp.StatusServiceUnavailable)
+	})
+	w.Target.options = TargetOptions{ReadinessProtocol: "http", ReadinessHTTPPath: "/ready"}
+	w.ReadinessPollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := w.WaitForReadiness(ctx)
+	require.Error(t, err)
+	assert.Contains(t, logs.String(), "Waiting for http target to be ready with no time limit")
+	assert.Contains(t, err.Error(), "target not ready: context deadline exceeded")
+}
+
+func TestWaitForReadiness_GrpcHealthCheck(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+	client := newGrpcClient(t, address, true, grpc.ClientOptions{})
+	w := Warmup{
+		Target:                NewTarget(http.Client{}, client, http.Client{}, client, TargetOptions{ReadinessProtocol: "grpc", ReadinessGrpcMethod: "grpc.health.v1.Health/Check"}),
+		ReadinessTimeout:      time.Second,
+		ReadinessPollInterval: 10 * time.Millisecond,
+	}
+
+	require.NoError(t, w.WaitForReadiness(context.Background()))
+}
+
+func TestHealthCheckStatus(t *testing.T) {
+	assert.Equal(t, "SERVING", healthCheckStatus([]byte(`{"status": "SERVING"}`)))
+	assert.Equal(t, "NOT_SERVING", healthCheckStatus([]byte(`{"status": "NOT_SERVING"}`)))
+	assert.Equal(t, "UNKNOWN", healthCheckStatus([]byte(`{}`)))
+}
+
+func TestGetWarmupHTTPRequests_InjectedRandomSource(t *testing.T) {
+	paths := func() []string {
+		w := Warmup{
+			HttpRequests: []http.Request{{Method: "GET", Path: "/a"}, {Method: "GET", Path: "/b"}, {Method: "GET", Path: "/c"}},
+			NewRand:      func() *rand.Rand { return rand.New(rand.NewSource(42)) },
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		requests := w.GetWarmupHTTPRequests(ctx, 10)
+		var paths []string
+		for i := 0; i < 20; i++ {
+			paths = append(paths, (<-requests).Path)
+		}
+		return paths
+	}
+
+	assert.Equal(t, paths(), paths())
+}
+
+func TestGetWarmupGrpcRequests_Sequential(t *testing.T) {
+	w := Warmup{
+		GrpcRequests: []grpc.Request{{ServiceMethod: "a/A"}, {ServiceMethod: "b/B", Weight: 2}},
+		RequestOrder: SequentialOrder,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	requests := w.GetWarmupGrpcRequests(ctx, 10)
+
+	var methods []string
+	for i := 0; i < 6; i++ {
+		methods = append(methods, (<-requests).ServiceMethod)
+	}
+	assert.Equal(t, []string{"a/A", "b/B", "b/B", "a/A", "b/B", "b/B"}, methods)
+}
+
+func TestFeedRequests_NoRequests(t *testing.T) {
+	_, ok := <-feedRequests(context.Background(), Warmup{}, []string{}, nil, func(string) int { return 1 }, 10)
+	assert.False(t, ok)
+}
+
+func TestFeedRequests_StopsWhenTheBudgetIsSpent(t *testing.T) {
+	w := Warmup{budget: newRequestBudget(5)}
+	requests := feedRequests(context.Background(), w, []string{"a"}, []int{1}, func(string) int { return 2 }, 10)
+
+	var fed int
+	for range requests {
+		fed++
+	}
+	assert.Equal(t, 3, fed)
+}
+
+func TestFeedRequests_StopsWhenTheContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	requests := feedRequests(ctx, Warmup{}, []string{"a"}, []int{1}, func(string) int { return 1 }, 10)
+	<-requests
+	cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		for range requests {
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("the requests channel was not closed once the context was done")
+	}
+}
+
+func TestRequestDelay(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	w := Warmup{}
+	assert.Equal(t, 500*time.Millisecond, w.requestDelay(500, random))
+
+	w.DelayJitterMilliseconds = 100
+	delays := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		delay := w.requestDelay(500, random)
+		assert.GreaterOrEqual(t, delay, 400*time.Millisecond)
+		assert.LessOrEqual(t, delay, 600*time.Millisecond)
+		delays[delay] = true
+	}
+	assert.Greater(t, len(delays), 1)
+
+	// the delay is never negative
+	for i := 0; i < 100; i++ {
+		assert.GreaterOrEqual(t, w.requestDelay(50, random), time.Duration(0))
+	}
+}
+
+func TestRun_WorkerPlaceholders(t *testing.T) {
+	var mu sync.Mutex
+	paths := make(map[string]string)
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths[r.URL.Path] = r.Header.Get("X-Worker")
+	})
+	w.Concurrency = 3
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/keys/{$workerSeed}"}}
+	w.HttpHeaders = []string{"X-Worker: {$workerSeed}"}
+	w.RequestDelayMilliseconds = 10
+	w.WorkerSeed = 100
+
+	summary, err := w.Run(context.Background(), true, false, 1, requestsSent)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]string{"/keys/101": "101", "/keys/102": "102", "/keys/103": "103"}, paths)
+	// the requests of all the workers are recorded under the configured endpoint
+	endpoints := summary.Endpoints()
+	require.Len(t, endpoints, 1)
+	assert.Equal(t, "GET /keys/{$workerSeed}", endpoints[0].Endpoint)
+}
+
+func TestSendHTTPRequest_BodyFile(t *testing.T) {
+	var bodies []string
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	})
+	bodyFile := filepath.Join(t.TempDir(), "order.json")
+	require.NoError(t, os.WriteFile(bodyFile, []byte(`{"id": "{$range|min=1,max=1}"}`), 0644))
+
+	resp := w.sendHTTPRequest(http.Request{Method: "POST", Path: "/orders", BodyFile: bodyFile}, []string{}, nil, requestsSent)
+	require.NoError(t, resp.Err)
+	require.Equal(t, []string{`{"id": "1"}`}, bodies)
+
+	resp = w.sendHTTPRequest(http.Request{Method: "POST", Path: "/orders", BodyFile: filepath.Join(t.TempDir(), "missing.json")}, []string{}, nil, requestsSent)
+	assert.ErrorContains(t, resp.Err, "cannot read body file")
+	assert.Len(t, bodies, 1)
+	assert.Equal(t, int64(1), *requestsSent)
+}
+
+func TestSendHTTPRequest_BodySchema(t *testing.T) {
+	var bodies []string
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("Content-Type")+" "+string(body))
+	})
+	schema, err := jsonschema.Parse([]byte(`{"type": "object", "required": ["id"], "properties": {"id": {"type": "integer", "minimum": 1, "maximum": 1000000}}}`))
+	require.NoError(t, err)
+	request := http.Request{Method: "POST", Path: "/orders", BodySchema: schema, ContentType: http.JSONContentType}
+
+	w.sendHTTPRequest(request, []string{}, placeholders.NewWorker(1), requestsSent)
+	w.sendHTTPRequest(request, []string{}, placeholders.NewWorker(1), requestsSent)
+	w.sendHTTPRequest(request, []string{}, placeholders.NewWorker(2), requestsSent)
+
+	require.Len(t, bodies, 3)
+	assert.Regexp(t, `^application/json \{"id":\d+\}$`, bodies[0])
+	// the same seed generates the same body and different seeds different ones
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.NotEqual(t, bodies[0], bodies[2])
+}
+
+func TestRun_AbortsOnLatencyBreach(t *testing.T) {
+	server, port := fixture.StartLatencyTestServer([]time.Duration{30 * time.Millisecond})
+	defer server.Close()
+	client := newHTTPClient(t, fmt.Sprintf("http://127.0.0.1:%d", port), false, http.ClientOptions{})
+	w := Warmup{
+		Target:                 NewTarget(client, grpc.Client{}, client, grpc.Client{}, TargetOptions{}),
+		Concurrency:            4,
+		HttpRequests:           []http.Request{{Method: "GET", Path: "/"}},
+		AbortP99Latency:        10 * time.Millisecond,
+		AbortP99WindowSeconds:  2,
+		AbortP99SustainSeconds: 2,
+	}
+
+	var requestsSent int64
+	start := time.Now()
+	summary, err := w.Run(context.Background(), true, false, 20, &requestsSent)
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Contains(t, summary.AbortReason(), "p99 latency above 10 ms for 2 second(s)")
+}
+
+func TestRun_AbortsOnErrorRate(t *testing.T) {
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		rw.WriteHeader(nethttp.StatusInternalServerError)
+	})
+	w.Concurrency = 2
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/"}}
+	w.RequestDelayMilliseconds = 10
+	w.AbortErrorRate = 90
+	w.AbortErrorWindowSeconds = 5
+	w.AbortErrorMinRequests = 20
+
+	start := time.Now()
+	summary, err := w.Run(context.Background(), true, false, 20, requestsSent)
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.True(t, summary.AbortFailed())
+	assert.Contains(t, summary.AbortReason(), "100.0% of the")
+}
+
+func TestRun_DoesNotAbortBelowErrorRate(t *testing.T) {
+	var received int32
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		// every other request fails
+		if atomic.AddInt32(&received, 1)%2 == 0 {
+			rw.WriteHeader(nethttp.StatusInternalServerError)
+		}
+	})
+	w.Concurrency = 1
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/"}}
+	w.RequestDelayMilliseconds = 10
+	w.AbortErrorRate = 90
+	w.AbortErrorWindowSeconds = 5
+	w.AbortErrorMinRequests = 20
+
+	summary, err := w.Run(context.Background(), true, false, 2, requestsSent)
+	require.NoError(t, err)
+
+	assert.False(t, summary.AbortFailed())
+	assert.Equal(t, "", summary.AbortReason())
+}
+
+func TestRun_ReturnsAnErrorIfNothingCanBeSent(t *testing.T) {
+	client := newHTTPClient(t, "http://127.0.0.1:1", false, http.ClientOptions{})
+	w := Warmup{Target: NewTarget(client, grpc.Client{}, client, grpc.Client{}, TargetOptions{}), Concurrency: 1}
+
+	var requestsSent int64
+	_, err := w.Run(context.Background(), false, false, 1, &requestsSent)
+	assert.EqualError(t, err, "no warmup requests to send")
+
+	// the gRPC client cannot connect and there are no HTTP requests to fall back on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener.Close()
+	grpcClient := newGrpcClient(t, listener.Addr().String(), true, grpc.ClientOptions{DialTimeout: 100 * time.Millisecond})
+	w = Warmup{
+		Target:       NewTarget(client, grpcClient, client, grpcClient, TargetOptions{}),
+		Concurrency:  1,
+		GrpcRequests: []grpc.Request{{ServiceMethod: "grpc.testing.TestService/EmptyCall"}},
+	}
+	_, err = w.Run(context.Background(), false, true, 1, &requestsSent)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gRPC client connect error")
+	assert.Equal(t, int64(0), requestsSent)
+}
+
+func TestKeepAlive(t *testing.T) {
+	address := fixture.StartGrpcTargetTestServerOnFreePort(t)
+
+	client := newGrpcClient(t, address, true, grpc.ClientOptions{})
+	w := Warmup{Target: NewTarget(http.Client{}, client, http.Client{}, client, TargetOptions{})}
+	ctx, cancel := context.WithTimeout(context.Background(), 350*time.Millisecond)
+	defer cancel()
+
+	sent, failed := w.KeepAlive(ctx, grpc.Request{ServiceMethod: "grpc.testing.TestService/EmptyCall"}, 100*time.Millisecond)
+	assert.Equal(t, 3, sent)
+	assert.Equal(t, 0, failed)
+	assert.True(t, w.Target.grpcClient.Connected())
+}
+
+func TestRun_MaxRequests(t *testing.T) {
+	var received int32
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		atomic.AddInt32(&received, 1)
+	})
+	w.Concurrency = 4
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/a"}, {Method: "GET", Path: "/b"}}
+	w.MaxRequests = 25
+	// the ramp up would outlast the test if it did not stop once the budget is spent
+	w.ConcurrencyTargetSeconds = 60
+
+	start := time.Now()
+	w.Run(context.Background(), true, false, 60, requestsSent)
+
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, int32(25), atomic.LoadInt32(&received))
+}
+
+func TestRun_CountsRequestsSentByManyWorkers(t *testing.T) {
+	var received int32
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		atomic.AddInt32(&received, 1)
+	})
+	w.Concurrency = 20
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/", Burst: 10}}
+	w.MaxRequests = 2000
+
+	w.Run(context.Background(), true, false, 60, requestsSent)
+
+	assert.Equal(t, int64(2000), *requestsSent)
+	assert.Equal(t, int64(atomic.LoadInt32(&received)), *requestsSent)
+}
+
+func TestRun_Metrics(t *testing.T) {
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path == "/missing" {
+			rw.WriteHeader(nethttp.StatusNotFound)
+		}
+	})
+	m := metrics.New()
+	w.Concurrency = 2
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/missing", Burst: 10}}
+	w.MaxRequests = 20
+	w.Metrics = m
+
+	w.Run(context.Background(), true, false, 60, requestsSent)
+
+	out := &strings.Builder{}
+	require.NoError(t, m.Write(out))
+	assert.Contains(t, out.String(), `mittens_requests_total{protocol="http"} 20`)
+	assert.Contains(t, out.String(), `mittens_request_failures_total{protocol="http"} 20`)
+	assert.Contains(t, out.String(), `mittens_request_duration_seconds_count{protocol="http"} 20`)
+	// the workers are no longer running once the warmup is over
+	assert.Contains(t, out.String(), "mittens_concurrency 0")
+}
+
+func TestRun_StopsPromptlyWhenCancelled(t *testing.T) {
+	server, port := fixture.StartLatencyTestServer([]time.Duration{time.Millisecond})
+	defer server.Close()
+	client := newHTTPClient(t, fmt.Sprintf("http://127.0.0.1:%d", port), false, http.ClientOptions{})
+	w := Warmup{
+		Target:       NewTarget(client, grpc.Client{}, client, grpc.Client{}, TargetOptions{}),
+		Concurrency:  2,
+		HttpRequests: []http.Request{{Method: "GET", Path: "/", Burst: 1000}},
+		// both the ramp up and the delay between requests would outlast the test
+		ConcurrencyTargetSeconds: 60,
+		RequestDelayMilliseconds: 200,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	var requestsSent int64
+	start := time.Now()
+	w.Run(ctx, true, false, 60, &requestsSent)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestRun_MaxDurationIncludesTheLatencyMeasurement(t *testing.T) {
+	server, port := fixture.StartLatencyTestServer([]time.Duration{400 * time.Millisecond})
+	defer server.Close()
+	client := newHTTPClient(t, fmt.Sprintf("http://127.0.0.1:%d", port), false, http.ClientOptions{})
+	w := Warmup{
+		Target:       NewTarget(client, grpc.Client{}, client, grpc.Client{}, TargetOptions{}),
+		Concurrency:  1,
+		HttpRequests: []http.Request{{Method: "GET", Path: "/"}},
+		// measuring the latency alone would take 2 seconds
+		TargetRequestsPerSecond:  10,
+		ConcurrencyTargetSeconds: 60,
+	}
+
+	var requestsSent int64
+	start := time.Now()
+	_, err := w.Run(context.Background(), true, false, 1, &requestsSent)
+
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 1900*time.Millisecond)
+	assert.Less(t, requestsSent, int64(latencySamples))
+}
+
+func TestRun_ZeroConcurrencyUsesTheCPUs(t *testing.T) {
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {})
+	control := NewConcurrencyControl(0)
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/"}}
+	w.MaxRequests = 10
+	w.ConcurrencyControl = control
+
+	_, err := w.Run(context.Background(), true, false, 1, requestsSent)
+
+	require.NoError(t, err)
+	assert.Equal(t, runtime.NumCPU(), control.Concurrency())
+	assert.Equal(t, int64(10), *requestsSent)
+}
+
+func TestRun_RampUpKeepsAConcurrencyLoweredMidRamp(t *testing.T) {
+	var inFlight, maxInFlight int64
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {
+		n := atomic.AddInt64(&inFlight, 1)
+		defer atomic.AddInt64(&inFlight, -1)
+		for {
+			max := atomic.LoadInt64(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	})
+	control := NewConcurrencyControl(2)
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/"}}
+	w.Concurrency = 2
+	w.ConcurrencyTargetSeconds = 2
+	w.ConcurrencyControl = control
+
+	go func() {
+		// the second worker is due after a second
+		for atomic.LoadInt64(requestsSent) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		control.SetConcurrency(1)
+	}()
+	_, err := w.Run(context.Background(), true, false, 2, requestsSent)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, control.Concurrency())
+	assert.Equal(t, int64(1), atomic.LoadInt64(&maxInFlight))
+}
+
+func TestRun_NegativeRampUpStartsAllTheWorkers(t *testing.T) {
+	w, requestsSent := newHTTPWarmup(t, func(rw nethttp.ResponseWriter, r *nethttp.Request) {})
+	w.HttpRequests = []http.Request{{Method: "GET", Path: "/"}}
+	w.Concurrency = 4
+	w.ConcurrencyTargetSeconds = -10
+	w.MaxRequests = 10
+
+	_, err := w.Run(context.Background(), true, false, 1, requestsSent)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), *requestsSent)
+}
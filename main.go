@@ -15,10 +15,12 @@
 package main
 
 import (
+	"os"
+
 	"mittens/cmd"
 )
 
 func main() {
 	cmd.CreateConfig()
-	cmd.RunCmdRoot()
+	os.Exit(cmd.RunCmdRoot())
 }
@@ -15,15 +15,21 @@
 package test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"log"
 	"mittens/cmd"
 	"mittens/fixture"
 	"mittens/internal/pkg/probe"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unicode"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -33,7 +39,7 @@ import (
 var mockHttpServerPort int
 var mockHttpServer *http.Server
 var mockGrpcServer *grpc.Server
-var httpInvocations = 0
+var httpInvocations int64
 
 func TestMain(m *testing.M) {
 	setup()
@@ -59,7 +65,7 @@ func TestShouldBeReadyRegardlessIfWarmupRan(t *testing.T) {
 	cmd.CreateConfig()
 	cmd.RunCmdRoot()
 
-	assert.Equal(t, httpInvocations, 0, "Assert that no calls were made to the http service")
+	assert.Equal(t, httpInvocationCount(), 0, "Assert that no calls were made to the http service")
 
 	readyFileExists, err := probe.FileExists("ready")
 	require.NoError(t, err)
@@ -86,13 +92,14 @@ func TestWarmupFailReadinessIfTargetIsNeverReady(t *testing.T) {
 	}
 
 	cmd.CreateConfig()
-	cmd.RunCmdRoot()
+	exitCode := cmd.RunCmdRoot()
 
-	assert.Equal(t, httpInvocations, 0, "Assert that no calls were made to the http service")
+	assert.Equal(t, httpInvocationCount(), 0, "Assert that no calls were made to the http service")
 
 	readyFileExists, err := probe.FileExists("ready")
 	require.NoError(t, err)
 	assert.False(t, readyFileExists)
+	assert.Equal(t, 2, exitCode)
 }
 
 func TestWarmupFailReadinessIfNoRequestsAreSentToTarget(t *testing.T) {
@@ -113,10 +120,130 @@ func TestWarmupFailReadinessIfNoRequestsAreSentToTarget(t *testing.T) {
 		"-fail-readiness=true",
 	}
 
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	assert.Equal(t, httpInvocationCount(), 0, "Assert that no calls were made to the http service")
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.False(t, readyFileExists)
+	assert.Equal(t, 2, exitCode)
+}
+
+func TestWarmupFailReadinessIfTheGrpcTargetCannotBeReached(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	// only gRPC requests to a port nobody listens on (9999), so no warmup request can be sent at all
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		"-grpc-requests=grpc.testing.TestService/EmptyCall",
+		"-target-grpc-port=9999",
+		"-target-insecure=true",
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+		"-exit-after-warmup=true",
+	}
+
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.False(t, readyFileExists)
+	assert.Equal(t, 2, exitCode)
+}
+
+func TestWarmupFailReadinessIfThereAreNoRequests(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+		"-exit-after-warmup=true",
+	}
+
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.False(t, readyFileExists)
+	assert.Equal(t, 5, exitCode)
+}
+
+func TestWarmupFailReadinessIfAnEndpointNeverSucceeded(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-http-requests=get:/hello-world",
+		"-http-requests=get:/non-existent",
+		"-concurrency=2",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+		"-require-all-endpoints-ok=true",
+	}
+
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	assert.Greater(t, httpInvocationCount(), 0, "Assert that we made some calls to the http service")
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.False(t, readyFileExists)
+	assert.Equal(t, 3, exitCode)
+}
+
+func TestWarmupFailReadinessIfResponseDoesNotMatchGoldenFile(t *testing.T) {
+	goldenFile := filepath.Join(t.TempDir(), "hello-world.golden")
+	require.NoError(t, os.WriteFile(goldenFile, []byte("not the response"), 0644))
+	junitFile := filepath.Join(t.TempDir(), "results.xml")
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-http-requests=[golden=%s]get:/hello-world", goldenFile),
+		"-concurrency=2",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+		"-require-all-endpoints-ok=true",
+		"-golden-print-diff=true",
+		fmt.Sprintf("-junit-out=%s", junitFile),
+	}
+
 	cmd.CreateConfig()
 	cmd.RunCmdRoot()
 
-	assert.Equal(t, httpInvocations, 0, "Assert that no calls were made to the http service")
+	junit, err := os.ReadFile(junitFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(junit), `<testcase classname="mittens.http" name="GET /hello-world">`)
+	assert.Contains(t, string(junit), "does not match golden file")
+
+	assert.Greater(t, httpInvocationCount(), 0, "Assert that we made some calls to the http service")
 
 	readyFileExists, err := probe.FileExists("ready")
 	require.NoError(t, err)
@@ -144,11 +271,159 @@ func TestHttp(t *testing.T) {
 	}
 
 	cmd.CreateConfig()
-	cmd.RunCmdRoot()
+	exitCode := cmd.RunCmdRoot()
 
-	assert.Greater(t, httpInvocations, 1, "Assert that we made some calls to the http service")
+	assert.Greater(t, httpInvocationCount(), 1, "Assert that we made some calls to the http service")
 	// TODO: validate grpc invocations
 
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.True(t, readyFileExists)
+	assert.Equal(t, 0, exitCode)
+}
+
+func TestHttpStopsOnStopCondition(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	stopFile := filepath.Join(t.TempDir(), "stop")
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-http-requests=get:/hello-world",
+		"-target-insecure=true",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=30",
+		"-max-warmup-seconds=30",
+		"-stop-condition=file:" + stopFile,
+	}
+
+	// stop the warmup once it has started, i.e. the target was ready and got a warmup request
+	go func() {
+		for httpInvocationCount() == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		os.WriteFile(stopFile, nil, 0644)
+	}()
+
+	start := time.Now()
+	cmd.CreateConfig()
+	cmd.RunCmdRoot()
+
+	assert.Less(t, time.Since(start), 10*time.Second, "Assert that the warmup stopped before its maximum duration")
+	assert.Greater(t, httpInvocationCount(), 0, "Assert that we made some calls to the http service")
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.True(t, readyFileExists)
+}
+
+func TestHttpWithTotalDuration(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-http-requests=get:/hello-world",
+		"-target-insecure=true",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-warmup-seconds=30",
+		"-concurrency-target-seconds=10",
+		"-total-duration=4s",
+	}
+
+	start := time.Now()
+	cmd.CreateConfig()
+	cmd.RunCmdRoot()
+
+	assert.Less(t, time.Since(start), 6*time.Second, "Assert that the run fit in the total duration")
+	assert.Greater(t, httpInvocationCount(), 0, "Assert that we made some calls to the http service")
+}
+
+func TestSelfTest(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		"-self-test=true",
+	}
+
+	// the self-test returns 1 if it fails
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	assert.Equal(t, 0, httpInvocationCount(), "Assert that the target was not called")
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.False(t, readyFileExists)
+	assert.Equal(t, 0, exitCode)
+}
+
+func TestHttpWithTargetRequestsPerSecond(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-http-requests=get:/hello-world",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+		"-request-delay-milliseconds=100",
+		"-target-requests-per-second=20",
+		"-max-concurrency=3",
+	}
+
+	cmd.CreateConfig()
+	cmd.RunCmdRoot()
+
+	// latency samples plus the requests sent by the workers
+	assert.Greater(t, httpInvocationCount(), 5, "Assert that we made some calls to the http service")
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.True(t, readyFileExists)
+}
+
+func TestHttpWithDNSCache(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-http-requests=get:/hello-world",
+		"-dns-prime=true",
+		"-dns-cache=true",
+		"-concurrency=2",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+	}
+
+	cmd.CreateConfig()
+	cmd.RunCmdRoot()
+
+	assert.Greater(t, httpInvocationCount(), 1, "Assert that we made some calls to the http service")
+
 	readyFileExists, err := probe.FileExists("ready")
 	require.NoError(t, err)
 	assert.True(t, readyFileExists)
@@ -180,7 +455,7 @@ func TestGrpcAndHttp(t *testing.T) {
 	cmd.CreateConfig()
 	cmd.RunCmdRoot()
 
-	assert.Greater(t, httpInvocations, 1, "Assert that we made some calls to the http service")
+	assert.Greater(t, httpInvocationCount(), 1, "Assert that we made some calls to the http service")
 	// TODO: validate grpc invocations
 
 	readyFileExists, err := probe.FileExists("ready")
@@ -188,6 +463,143 @@ func TestGrpcAndHttp(t *testing.T) {
 	assert.True(t, readyFileExists)
 }
 
+func TestGrpcWarmAll(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		"-target-grpc-port=50051",
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-grpc-warm-all=true",
+		"-grpc-warm-all-services=grpc.testing.*",
+		"-target-insecure=true",
+		"-concurrency=2",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+	}
+
+	cmd.CreateConfig()
+	cmd.RunCmdRoot()
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.True(t, readyFileExists)
+}
+
+func TestGrpcFailOnUnknownMethods(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		"-target-grpc-port=50051",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-http-requests=get:/hello-world",
+		"-grpc-requests=grpc.testing.TestService/EmptyCall",
+		"-grpc-requests=grpc.testing.TestService/Typo",
+		"-grpc-fail-on-unknown-methods=true",
+		"-target-insecure=true",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+	}
+
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	assert.Equal(t, 0, httpInvocationCount(), "Assert that no calls were made to the http service")
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.False(t, readyFileExists)
+	assert.Equal(t, 5, exitCode)
+}
+
+func TestDryRun(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-target-grpc-port=50051",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-http-requests=get:/hello-world",
+		"-grpc-requests=grpc.testing.TestService/EmptyCall",
+		"-target-insecure=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+		"-dry-run=true",
+	}
+
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	assert.Equal(t, 0, httpInvocationCount(), "Assert that no calls were made to the http service")
+	assert.Equal(t, 0, exitCode)
+}
+
+func TestDryRunReportsInvalidRequests(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-target-grpc-port=50051",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-http-requests=get:/hello-world%zz",
+		"-grpc-requests=grpc.testing.TestService/EmptyCall",
+		"-grpc-requests=grpc.testing.TestService/Typo",
+		"-target-insecure=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+		"-dry-run=true",
+	}
+
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	assert.Equal(t, 0, httpInvocationCount(), "Assert that no calls were made to the http service")
+	assert.Equal(t, 5, exitCode)
+}
+
+func TestGrpcSkipsUnknownMethods(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		"-target-grpc-port=50051",
+		fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+		"-grpc-requests=grpc.testing.TestService/EmptyCall",
+		"-grpc-requests=grpc.testing.TestService/Typo",
+		"-target-insecure=true",
+		"-exit-after-warmup=true",
+		"-target-readiness-http-path=/health",
+		"-max-duration-seconds=2",
+		"-require-all-endpoints-ok=true",
+	}
+
+	cmd.CreateConfig()
+	cmd.RunCmdRoot()
+
+	readyFileExists, err := probe.FileExists("ready")
+	require.NoError(t, err)
+	assert.True(t, readyFileExists)
+}
+
 func setup() {
 	fmt.Println("Starting up http server")
 	mockHttpServer, mockHttpServerPort = fixture.StartHttpTargetTestServer([]fixture.PathResponseHandler{
@@ -197,7 +609,7 @@ func setup() {
 				// Tiny sleep to mimic a regular http call
 				time.Sleep(time.Millisecond * 10)
 				// Record number of invocations made to this endpoint
-				httpInvocations++
+				atomic.AddInt64(&httpInvocations, 1)
 				w.WriteHeader(http.StatusOK)
 			},
 		},
@@ -217,8 +629,13 @@ func teardown() {
 	fmt.Println("All servers server stopped")
 }
 
+// httpInvocationCount returns the number of calls made to the http mock server so far.
+func httpInvocationCount() int {
+	return int(atomic.LoadInt64(&httpInvocations))
+}
+
 func cleanup() {
-	httpInvocations = 0
+	atomic.StoreInt64(&httpInvocations, 0)
 
 	if fileExists, err := probe.FileExists("alive"); err == nil && fileExists {
 		probe.DeleteFile("alive")
@@ -227,3 +644,61 @@ func cleanup() {
 		probe.DeleteFile("ready")
 	}
 }
+
+func TestPlainMarkersLogOnlyASCII(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		cleanup()
+	})
+
+	for _, path := range []string{"/hello-world", "/non-existent"} {
+		os.Args = []string{
+			"mittens",
+			"-file-probe-enabled=true",
+			"-markers=plain",
+			fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+			fmt.Sprintf("-target-readiness-port=%d", mockHttpServerPort),
+			"-http-requests=get:" + path,
+			"-exit-after-warmup=true",
+			"-target-readiness-http-path=/health",
+			"-max-duration-seconds=2",
+			"-require-all-endpoints-ok=true",
+		}
+
+		cmd.CreateConfig()
+		cmd.RunCmdRoot()
+	}
+
+	require.Contains(t, logs.String(), "Warm up finished")
+	require.Contains(t, logs.String(), "Mittens readiness probe will fail")
+	for _, line := range strings.Split(logs.String(), "\n") {
+		for _, r := range line {
+			if r > unicode.MaxASCII {
+				t.Fatalf("non-ASCII character %q in the log line %q", r, line)
+			}
+		}
+	}
+}
+
+func TestInvalidOptionsExitWithConfigError(t *testing.T) {
+	t.Cleanup(func() {
+		cleanup()
+	})
+
+	os.Args = []string{
+		"mittens",
+		"-file-probe-enabled=true",
+		"-http-requests=get:/hello-world",
+		fmt.Sprintf("-target-http-port=%d", mockHttpServerPort),
+		"-min-success=-1",
+		"-exit-after-warmup=true",
+	}
+
+	cmd.CreateConfig()
+	exitCode := cmd.RunCmdRoot()
+
+	assert.Equal(t, 0, httpInvocationCount(), "Assert that no calls were made to the http service")
+	assert.Equal(t, 5, exitCode)
+}